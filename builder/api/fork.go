@@ -0,0 +1,19 @@
+package api
+
+import (
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// DataVersionAt returns the consensus fork a payload with the given timestamp is submitted under,
+// following the execution fork schedule of the chain config
+func DataVersionAt(config *params.ChainConfig, timestamp uint64) consensusspec.DataVersion {
+	switch {
+	case config.IsCancun(timestamp):
+		return consensusspec.DataVersionDeneb
+	case config.IsShanghai(timestamp):
+		return consensusspec.DataVersionCapella
+	default:
+		return consensusspec.DataVersionBellatrix
+	}
+}
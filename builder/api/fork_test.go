@@ -0,0 +1,22 @@
+package api
+
+import (
+	"testing"
+
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataVersionAt(t *testing.T) {
+	shanghaiTime := uint64(100)
+	cancunTime := uint64(200)
+	config := &params.ChainConfig{ShanghaiTime: &shanghaiTime, CancunTime: &cancunTime}
+
+	require.Equal(t, consensusspec.DataVersionBellatrix, DataVersionAt(config, 99))
+	require.Equal(t, consensusspec.DataVersionCapella, DataVersionAt(config, 100))
+	require.Equal(t, consensusspec.DataVersionCapella, DataVersionAt(config, 199))
+	require.Equal(t, consensusspec.DataVersionDeneb, DataVersionAt(config, 200))
+
+	require.Equal(t, consensusspec.DataVersionBellatrix, DataVersionAt(&params.ChainConfig{}, 1000))
+}
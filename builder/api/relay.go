@@ -0,0 +1,29 @@
+package api
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// RelaySubmissionResult is the outcome of submitting a block to a single relay
+type RelaySubmissionResult struct {
+	Relay   string
+	Latency time.Duration
+	Err     error
+}
+
+// ObservedBid is a bid received by a relay for the slot as reported by the relay data API
+type ObservedBid struct {
+	Relay         string      `json:"relay"`
+	Slot          uint64      `json:"slot"`
+	ParentHash    common.Hash `json:"parentHash"`
+	BlockHash     common.Hash `json:"blockHash"`
+	BuilderPubkey string      `json:"builderPubkey"`
+	Value         *big.Int    `json:"value"`
+	// ReceivedAt is the time the relay received the bid
+	ReceivedAt time.Time `json:"receivedAt"`
+	// ObservedAt is the time the builder first saw the bid
+	ObservedAt time.Time `json:"observedAt"`
+}
@@ -0,0 +1,85 @@
+// Package api contains the fork-versioned block submission types shared by the builder and its relays.
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	bellatrixapi "github.com/attestantio/go-builder-client/api/bellatrix"
+	capellaapi "github.com/attestantio/go-builder-client/api/capella"
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
+	"github.com/ethereum/go-ethereum/beacon/engine"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var ErrUnsupportedVersion = errors.New("unsupported version")
+
+// VersionedSubmitBlockRequest is a block submission for any of the supported forks, only the field
+// matching Version is set
+type VersionedSubmitBlockRequest struct {
+	Version   consensusspec.DataVersion
+	Bellatrix *bellatrixapi.SubmitBlockRequest
+	Capella   *capellaapi.SubmitBlockRequest
+}
+
+// BidTrace returns the signed bid trace of the submission
+func (v *VersionedSubmitBlockRequest) BidTrace() (*apiv1.BidTrace, error) {
+	switch v.Version {
+	case consensusspec.DataVersionBellatrix:
+		if v.Bellatrix == nil {
+			return nil, errors.New("no bellatrix submission")
+		}
+		return v.Bellatrix.Message, nil
+	case consensusspec.DataVersionCapella:
+		if v.Capella == nil {
+			return nil, errors.New("no capella submission")
+		}
+		return v.Capella.Message, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedVersion, v.Version)
+	}
+}
+
+// Block decodes the execution payload of the submission into a block
+func (v *VersionedSubmitBlockRequest) Block() (*types.Block, error) {
+	switch v.Version {
+	case consensusspec.DataVersionBellatrix:
+		if v.Bellatrix == nil || v.Bellatrix.ExecutionPayload == nil {
+			return nil, errors.New("nil execution payload")
+		}
+		return engine.ExecutionPayloadToBlock(v.Bellatrix.ExecutionPayload)
+	case consensusspec.DataVersionCapella:
+		if v.Capella == nil || v.Capella.ExecutionPayload == nil {
+			return nil, errors.New("nil execution payload")
+		}
+		return engine.ExecutionPayloadV2ToBlock(v.Capella.ExecutionPayload)
+	default:
+		return nil, fmt.Errorf("%w: no block decoding for %s", ErrUnsupportedVersion, v.Version)
+	}
+}
+
+// MarshalJSON encodes the submission of the set version in the relay API format
+func (v *VersionedSubmitBlockRequest) MarshalJSON() ([]byte, error) {
+	switch v.Version {
+	case consensusspec.DataVersionBellatrix:
+		return json.Marshal(v.Bellatrix)
+	case consensusspec.DataVersionCapella:
+		return json.Marshal(v.Capella)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedVersion, v.Version)
+	}
+}
+
+// MarshalSSZ encodes the submission of the set version as SSZ
+func (v *VersionedSubmitBlockRequest) MarshalSSZ() ([]byte, error) {
+	switch v.Version {
+	case consensusspec.DataVersionBellatrix:
+		return v.Bellatrix.MarshalSSZ()
+	case consensusspec.DataVersionCapella:
+		return v.Capella.MarshalSSZ()
+	default:
+		return nil, fmt.Errorf("%w: no ssz encoding for %s", ErrUnsupportedVersion, v.Version)
+	}
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"testing"
+
+	bellatrixapi "github.com/attestantio/go-builder-client/api/bellatrix"
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionedSubmitBlockRequest(t *testing.T) {
+	msg := &VersionedSubmitBlockRequest{
+		Version:   consensusspec.DataVersionBellatrix,
+		Bellatrix: &bellatrixapi.SubmitBlockRequest{Message: &apiv1.BidTrace{Slot: 1}},
+	}
+	bidTrace, err := msg.BidTrace()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), bidTrace.Slot)
+
+	msg = &VersionedSubmitBlockRequest{Version: consensusspec.DataVersionCapella}
+	_, err = msg.BidTrace()
+	require.Error(t, err)
+
+	// deneb submissions are not supported
+	for _, version := range []consensusspec.DataVersion{consensusspec.DataVersionAltair, consensusspec.DataVersionDeneb} {
+		msg = &VersionedSubmitBlockRequest{Version: version}
+		_, err = msg.BidTrace()
+		require.ErrorIs(t, err, ErrUnsupportedVersion)
+		_, err = msg.MarshalJSON()
+		require.ErrorIs(t, err, ErrUnsupportedVersion)
+		_, err = msg.MarshalSSZ()
+		require.ErrorIs(t, err, ErrUnsupportedVersion)
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,8 +22,13 @@ import (
 )
 
 type IBeaconClient interface {
-	isValidator(pubkey PubkeyHex) bool
+	// activeValidators returns the lowercase pubkeys among the given ones that belong to active validators
+	activeValidators(pubkeys []PubkeyHex) (map[PubkeyHex]struct{}, error)
 	getProposerForNextSlot(requestedSlot uint64) (PubkeyHex, error)
+	// proposerForSlot looks up the proposer of the slot, it reports false if the duties of the slot are not known yet
+	proposerForSlot(slot uint64) (PubkeyHex, bool)
+	// genesisTime returns the genesis time of the beacon chain, it reports false if it is not known yet
+	genesisTime() (uint64, bool)
 	SubscribeToPayloadAttributesEvents(payloadAttrC chan types.BuilderPayloadAttributes)
 	Start() error
 	Stop()
@@ -34,14 +41,26 @@ type testBeaconClient struct {
 
 func (b *testBeaconClient) Stop() {}
 
-func (b *testBeaconClient) isValidator(pubkey PubkeyHex) bool {
-	return true
+func (b *testBeaconClient) activeValidators(pubkeys []PubkeyHex) (map[PubkeyHex]struct{}, error) {
+	active := make(map[PubkeyHex]struct{}, len(pubkeys))
+	for _, pubkey := range pubkeys {
+		active[PubkeyHex(strings.ToLower(string(pubkey)))] = struct{}{}
+	}
+	return active, nil
 }
 
 func (b *testBeaconClient) getProposerForNextSlot(requestedSlot uint64) (PubkeyHex, error) {
 	return PubkeyHex(hexutil.Encode(b.validator.Pk)), nil
 }
 
+func (b *testBeaconClient) proposerForSlot(slot uint64) (PubkeyHex, bool) {
+	return PubkeyHex(hexutil.Encode(b.validator.Pk)), true
+}
+
+func (b *testBeaconClient) genesisTime() (uint64, bool) {
+	return 0, false
+}
+
 func (b *testBeaconClient) SubscribeToPayloadAttributesEvents(payloadAttrC chan types.BuilderPayloadAttributes) {
 }
 
@@ -49,14 +68,22 @@ func (b *testBeaconClient) Start() error { return nil }
 
 type NilBeaconClient struct{}
 
-func (b *NilBeaconClient) isValidator(pubkey PubkeyHex) bool {
-	return false
+func (b *NilBeaconClient) activeValidators(pubkeys []PubkeyHex) (map[PubkeyHex]struct{}, error) {
+	return map[PubkeyHex]struct{}{}, nil
 }
 
 func (b *NilBeaconClient) getProposerForNextSlot(requestedSlot uint64) (PubkeyHex, error) {
 	return PubkeyHex(""), nil
 }
 
+func (b *NilBeaconClient) proposerForSlot(slot uint64) (PubkeyHex, bool) {
+	return PubkeyHex(""), false
+}
+
+func (b *NilBeaconClient) genesisTime() (uint64, bool) {
+	return 0, false
+}
+
 func (b *NilBeaconClient) SubscribeToPayloadAttributesEvents(payloadAttrC chan types.BuilderPayloadAttributes) {
 }
 
@@ -64,36 +91,97 @@ func (b *NilBeaconClient) Start() error { return nil }
 
 func (b *NilBeaconClient) Stop() {}
 
+const (
+	BeaconQuorumDefault = 1
+
+	// beacon nodes more than beaconMaxSlotLag slots behind the most advanced node are considered unhealthy
+	beaconMaxSlotLag = 1
+	// beacon nodes without events for beaconMaxSlotsSilent slots are considered unhealthy
+	beaconMaxSlotsSilent = 2
+)
+
+// BeaconNodeStatus is the health of a beacon node as seen by the MultiBeaconClient
+type BeaconNodeStatus struct {
+	Endpoint    string    `json:"endpoint"`
+	Healthy     bool      `json:"healthy"`
+	HeadSlot    uint64    `json:"headSlot"`
+	SlotLag     uint64    `json:"slotLag"`
+	LastEventAt time.Time `json:"lastEventAt"`
+	Conflicts   uint64    `json:"conflicts"`
+}
+
+type beaconNodeHealth struct {
+	lastEventAt time.Time
+	headSlot    uint64
+	conflicts   uint64
+}
+
+type payloadAttributesKey struct {
+	slot     uint64
+	headHash common.Hash
+}
+
+// payloadAttributesVotes are the nodes which announced the same payload attributes
+type payloadAttributesVotes struct {
+	attrs     types.BuilderPayloadAttributes
+	nodes     map[int]struct{}
+	forwarded bool
+}
+
+// MultiBeaconClient follows several beacon nodes. Payload attributes are forwarded once announced by quorum nodes,
+// duplicates are dropped and nodes disagreeing on the head are reported. Requests go to the healthiest node first.
 type MultiBeaconClient struct {
-	clients []*BeaconClient
-	closeCh chan struct{}
+	clients       []*BeaconClient
+	quorum        int
+	secondsInSlot uint64
+	closeCh       chan struct{}
+
+	mu       sync.Mutex
+	headSlot uint64
+	health   []beaconNodeHealth
+	votes    map[payloadAttributesKey]*payloadAttributesVotes
 }
 
-func NewMultiBeaconClient(endpoints []string, slotsInEpoch, secondsInSlot uint64) *MultiBeaconClient {
+func NewMultiBeaconClient(endpoints []string, slotsInEpoch, secondsInSlot uint64, quorum int, trackValidators bool) *MultiBeaconClient {
 	clients := []*BeaconClient{}
 	for _, endpoint := range endpoints {
-		client := NewBeaconClient(endpoint, slotsInEpoch, secondsInSlot)
+		client := NewBeaconClient(endpoint, slotsInEpoch, secondsInSlot, trackValidators)
 		clients = append(clients, client)
 	}
 
+	if quorum < 1 {
+		quorum = 1
+	} else if quorum > len(clients) {
+		log.Warn("beacon quorum is larger than the number of beacon nodes, requiring all of them", "quorum", quorum, "nodes", len(clients))
+		quorum = len(clients)
+	}
+
 	return &MultiBeaconClient{
-		clients: clients,
-		closeCh: make(chan struct{}),
+		clients:       clients,
+		quorum:        quorum,
+		secondsInSlot: secondsInSlot,
+		closeCh:       make(chan struct{}),
+		health:        make([]beaconNodeHealth, len(clients)),
+		votes:         make(map[payloadAttributesKey]*payloadAttributesVotes),
 	}
 }
 
-func (m *MultiBeaconClient) isValidator(pubkey PubkeyHex) bool {
-	for _, c := range m.clients {
-		// Pick the first one, always true
-		return c.isValidator(pubkey)
+// activeValidators asks the healthiest node which answers
+func (m *MultiBeaconClient) activeValidators(pubkeys []PubkeyHex) (map[PubkeyHex]struct{}, error) {
+	err := errors.New("no beacon nodes")
+	for _, c := range m.clientsByHealth() {
+		var active map[PubkeyHex]struct{}
+		if active, err = c.activeValidators(pubkeys); err == nil {
+			return active, nil
+		}
+		log.Warn("could not look up validators", "endpoint", c.endpoint, "err", err)
 	}
-
-	return false
+	return nil, err
 }
 
 func (m *MultiBeaconClient) getProposerForNextSlot(requestedSlot uint64) (PubkeyHex, error) {
 	var allErrs error
-	for _, c := range m.clients {
+	for _, c := range m.clientsByHealth() {
 		pk, err := c.getProposerForNextSlot(requestedSlot)
 		if err != nil {
 			allErrs = errors.Join(allErrs, err)
@@ -105,13 +193,179 @@ func (m *MultiBeaconClient) getProposerForNextSlot(requestedSlot uint64) (Pubkey
 	return PubkeyHex(""), allErrs
 }
 
+func (m *MultiBeaconClient) proposerForSlot(slot uint64) (PubkeyHex, bool) {
+	for _, c := range m.clientsByHealth() {
+		if pk, found := c.proposerForSlot(slot); found {
+			return pk, true
+		}
+	}
+	return PubkeyHex(""), false
+}
+
+func (m *MultiBeaconClient) genesisTime() (uint64, bool) {
+	for _, c := range m.clientsByHealth() {
+		if genesisTime, found := c.genesisTime(); found {
+			return genesisTime, true
+		}
+	}
+	return 0, false
+}
+
 func (m *MultiBeaconClient) SubscribeToPayloadAttributesEvents(payloadAttrC chan types.BuilderPayloadAttributes) {
-	for _, c := range m.clients {
-		go c.SubscribeToPayloadAttributesEvents(payloadAttrC)
+	for i, c := range m.clients {
+		nodeC := make(chan types.BuilderPayloadAttributes)
+		go c.SubscribeToPayloadAttributesEvents(nodeC)
+		go func(node int) {
+			for {
+				select {
+				case <-m.closeCh:
+					return
+				case attrs := <-nodeC:
+					if !m.onPayloadAttributes(node, &attrs, time.Now()) {
+						continue
+					}
+					select {
+					case payloadAttrC <- attrs:
+					case <-m.closeCh:
+						return
+					}
+				}
+			}
+		}(i)
 	}
 }
 
+// onPayloadAttributes records the payload attributes announced by the node and reports whether they should be
+// forwarded, which they are once when the quorum of nodes announced them
+func (m *MultiBeaconClient) onPayloadAttributes(node int, attrs *types.BuilderPayloadAttributes, now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	endpoint := m.clients[node].endpoint
+	health := &m.health[node]
+	health.lastEventAt = now
+	if attrs.Slot > health.headSlot {
+		health.headSlot = attrs.Slot
+	}
+	markBeaconNodeEvent(endpoint)
+
+	if attrs.Slot < m.headSlot {
+		log.Debug("ignoring payload attributes of a past slot", "endpoint", endpoint, "slot", attrs.Slot, "headSlot", m.headSlot)
+		return false
+	}
+	if attrs.Slot > m.headSlot {
+		m.headSlot = attrs.Slot
+		for key := range m.votes {
+			if key.slot < m.headSlot {
+				delete(m.votes, key)
+			}
+		}
+	}
+
+	key := payloadAttributesKey{attrs.Slot, attrs.HeadHash}
+	votes, found := m.votes[key]
+	if !found {
+		for other := range m.votes {
+			if other.slot == key.slot {
+				log.Warn("beacon nodes disagree on the head", "endpoint", endpoint, "slot", attrs.Slot, "head", attrs.HeadHash, "otherHead", other.headHash)
+				health.conflicts++
+				markBeaconNodeConflict(endpoint)
+				break
+			}
+		}
+		votes = &payloadAttributesVotes{attrs: *attrs, nodes: make(map[int]struct{})}
+		m.votes[key] = votes
+	} else if !votes.attrs.Equal(attrs) {
+		log.Warn("beacon nodes disagree on the payload attributes of the head", "endpoint", endpoint, "slot", attrs.Slot, "head", attrs.HeadHash)
+		health.conflicts++
+		markBeaconNodeConflict(endpoint)
+		return false
+	}
+
+	votes.nodes[node] = struct{}{}
+	if votes.forwarded {
+		markBeaconDuplicateEvent()
+		return false
+	}
+	if len(votes.nodes) < m.quorum {
+		return false
+	}
+	votes.forwarded = true
+	return true
+}
+
+// NodesStatus returns the health of every beacon node in the order of the endpoints
+func (m *MultiBeaconClient) NodesStatus() []BeaconNodeStatus {
+	return m.nodesStatus(time.Now())
+}
+
+func (m *MultiBeaconClient) nodesStatus(now time.Time) []BeaconNodeStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	silentAfter := time.Duration(beaconMaxSlotsSilent*m.secondsInSlot) * time.Second
+	status := make([]BeaconNodeStatus, len(m.clients))
+	for i, c := range m.clients {
+		health := m.health[i]
+		var lag uint64
+		if m.headSlot > health.headSlot {
+			lag = m.headSlot - health.headSlot
+		}
+		status[i] = BeaconNodeStatus{
+			Endpoint:    c.endpoint,
+			Healthy:     !health.lastEventAt.IsZero() && now.Sub(health.lastEventAt) <= silentAfter && lag <= beaconMaxSlotLag,
+			HeadSlot:    health.headSlot,
+			SlotLag:     lag,
+			LastEventAt: health.lastEventAt,
+			Conflicts:   health.conflicts,
+		}
+	}
+	return status
+}
+
+// pollHealth updates the slot lag metrics of the beacon nodes once per slot until the client is stopped
+func (m *MultiBeaconClient) pollHealth() {
+	ticker := time.NewTicker(time.Duration(m.secondsInSlot) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.closeCh:
+			return
+		case now := <-ticker.C:
+			for _, status := range m.nodesStatus(now) {
+				markBeaconNodeLag(status.Endpoint, status.SlotLag)
+			}
+		}
+	}
+}
+
+// clientsByHealth returns the healthy nodes first, least lagging first, keeping the order of the endpoints otherwise
+func (m *MultiBeaconClient) clientsByHealth() []*BeaconClient {
+	status := m.nodesStatus(time.Now())
+	order := make([]int, len(m.clients))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := status[order[i]], status[order[j]]
+		if a.Healthy != b.Healthy {
+			return a.Healthy
+		}
+		return a.SlotLag < b.SlotLag
+	})
+
+	clients := make([]*BeaconClient, len(order))
+	for i, node := range order {
+		clients[i] = m.clients[node]
+	}
+	return clients
+}
+
 func (m *MultiBeaconClient) Start() error {
+	if m.secondsInSlot > 0 {
+		go m.pollHealth()
+	}
+
 	var allErrs error
 	for _, c := range m.clients {
 		err := c.Start()
@@ -136,19 +390,27 @@ type BeaconClient struct {
 	secondsInSlot uint64
 
 	mu              sync.Mutex
-	slotProposerMap map[uint64]PubkeyHex
+	slotProposerMap map[uint64]proposerDuty
+	genesis         uint64
+
+	// the validators are only looked up with trackValidators set, the ones found active are cached for the epoch
+	trackValidators bool
+	validators      map[PubkeyHex]struct{}
+	validatorsEpoch uint64
 
 	ctx      context.Context
 	cancelFn context.CancelFunc
 }
 
-func NewBeaconClient(endpoint string, slotsInEpoch, secondsInSlot uint64) *BeaconClient {
+func NewBeaconClient(endpoint string, slotsInEpoch, secondsInSlot uint64, trackValidators bool) *BeaconClient {
 	ctx, cancelFn := context.WithCancel(context.Background())
 	return &BeaconClient{
 		endpoint:        endpoint,
 		slotsInEpoch:    slotsInEpoch,
 		secondsInSlot:   secondsInSlot,
-		slotProposerMap: make(map[uint64]PubkeyHex),
+		slotProposerMap: make(map[uint64]proposerDuty),
+		trackValidators: trackValidators,
+		validators:      make(map[PubkeyHex]struct{}),
 		ctx:             ctx,
 		cancelFn:        cancelFn,
 	}
@@ -158,8 +420,58 @@ func (b *BeaconClient) Stop() {
 	b.cancelFn()
 }
 
-func (b *BeaconClient) isValidator(pubkey PubkeyHex) bool {
-	return true
+// validatorsLookupBatch is the maximum number of pubkeys looked up in one request to the beacon node
+const validatorsLookupBatch = 64
+
+// activeValidators returns the lowercase pubkeys among the given ones that belong to active validators. The pubkeys
+// not known to be active in the current epoch are looked up at the head state of the beacon node, every pubkey is
+// active if the validators are not tracked.
+func (b *BeaconClient) activeValidators(pubkeys []PubkeyHex) (map[PubkeyHex]struct{}, error) {
+	active := make(map[PubkeyHex]struct{}, len(pubkeys))
+	var unknown []PubkeyHex
+
+	b.mu.Lock()
+	for _, pubkey := range pubkeys {
+		pubkey = PubkeyHex(strings.ToLower(string(pubkey)))
+		if _, found := b.validators[pubkey]; found || !b.trackValidators {
+			active[pubkey] = struct{}{}
+		} else {
+			unknown = append(unknown, pubkey)
+		}
+	}
+	epoch := b.validatorsEpoch
+	b.mu.Unlock()
+
+	for start := 0; start < len(unknown); start += validatorsLookupBatch {
+		end := start + validatorsLookupBatch
+		if end > len(unknown) {
+			end = len(unknown)
+		}
+		found, err := fetchActiveValidators(b.endpoint, unknown[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		b.mu.Lock()
+		for pubkey := range found {
+			active[pubkey] = struct{}{}
+			if b.validatorsEpoch == epoch {
+				b.validators[pubkey] = struct{}{}
+			}
+		}
+		b.mu.Unlock()
+	}
+	return active, nil
+}
+
+// updateValidators forgets the validators found active in the previous epochs, they may have exited since
+func (b *BeaconClient) updateValidators(currentEpoch uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if currentEpoch > b.validatorsEpoch {
+		b.validators = make(map[PubkeyHex]struct{})
+		b.validatorsEpoch = currentEpoch
+	}
 }
 
 func (b *BeaconClient) getProposerForNextSlot(requestedSlot uint64) (PubkeyHex, error) {
@@ -172,7 +484,49 @@ func (b *BeaconClient) getProposerForNextSlot(requestedSlot uint64) (PubkeyHex,
 		log.Error("inconsistent proposer mapping", "requestSlot", requestedSlot, "slotProposerMap", b.slotProposerMap)
 		return PubkeyHex(""), errors.New("inconsistent proposer mapping")
 	}
-	return nextSlotProposer, nil
+	return nextSlotProposer.Pubkey, nil
+}
+
+func (b *BeaconClient) proposerForSlot(slot uint64) (PubkeyHex, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	duty, found := b.slotProposerMap[slot]
+	return duty.Pubkey, found
+}
+
+func (b *BeaconClient) genesisTime() (uint64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.genesis, b.genesis != 0
+}
+
+// updateGenesisTime fetches the genesis time until the beacon node returned it
+func (b *BeaconClient) updateGenesisTime() {
+	if _, found := b.genesisTime(); found {
+		return
+	}
+
+	genesisTime, err := fetchGenesisTime(b.endpoint)
+	if err != nil {
+		log.Error("could not fetch genesis time", "endpoint", b.endpoint, "err", err)
+		return
+	}
+	b.mu.Lock()
+	b.genesis = genesisTime
+	b.mu.Unlock()
+}
+
+// checkProposerIndex verifies the proposer announced for the slot against the proposer duties, if they are known
+func (b *BeaconClient) checkProposerIndex(slot, proposerIndex uint64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	duty, found := b.slotProposerMap[slot]
+	if found && duty.ValidatorIndex != proposerIndex {
+		return fmt.Errorf("%w: announced %d, expected %d", errPayloadAttrsProposer, proposerIndex, duty.ValidatorIndex)
+	}
+	return nil
 }
 
 func (b *BeaconClient) Start() error {
@@ -186,6 +540,8 @@ func (b *BeaconClient) UpdateValidatorMapForever() {
 	log.Info("000 正在更新验证人列表")
 	prevFetchSlot := uint64(0)
 
+	b.updateGenesisTime()
+
 	// fetch current epoch if beacon is online
 	currentSlot, err := fetchCurrentSlot(b.endpoint)
 	if err != nil {
@@ -193,6 +549,7 @@ func (b *BeaconClient) UpdateValidatorMapForever() {
 		log.Error("could not get current slot", "err", err)
 	} else {
 		currentEpoch := currentSlot / b.slotsInEpoch
+		b.updateValidators(currentEpoch)
 		slotProposerMap, err := fetchEpochProposersMap(b.endpoint, currentEpoch)
 		//没走到这
 		log.Info("222 UpdateValidatorMapForever() ", "epoch", currentEpoch, "slotProposerMap", slotProposerMap)
@@ -221,6 +578,7 @@ func (b *BeaconClient) UpdateValidatorMapForever() {
 		}
 
 		log.Info("111 正在更新验证人列表")
+		b.updateGenesisTime()
 		currentSlot, err := fetchCurrentSlot(b.endpoint)
 		if err != nil {
 			log.Error("could not get current slot", "err", err)
@@ -236,6 +594,7 @@ func (b *BeaconClient) UpdateValidatorMapForever() {
 		}
 
 		currentEpoch := currentSlot / b.slotsInEpoch
+		b.updateValidators(currentEpoch)
 		slotProposerMap, err := fetchEpochProposersMap(b.endpoint, currentEpoch+1)
 		if err != nil {
 			log.Error("could not fetch validators map", "epoch", currentEpoch+1, "err", err)
@@ -272,6 +631,7 @@ type PayloadAttributesEvent struct {
 }
 
 type PayloadAttributesEventData struct {
+	ProposerIndex     uint64            `json:"proposer_index,string"`
 	ProposalSlot      uint64            `json:"proposal_slot,string"`
 	ParentBlockHash   common.Hash       `json:"parent_block_hash"`
 	PayloadAttributes PayloadAttributes `json:"payload_attributes"`
@@ -309,6 +669,13 @@ func (b *BeaconClient) SubscribeToPayloadAttributesEvents(payloadAttrC chan type
 					})
 				}
 
+				if err := b.checkProposerIndex(payloadAttributesResp.Data.ProposalSlot, payloadAttributesResp.Data.ProposerIndex); err != nil {
+					log.Warn("rejected payload attributes", "endpoint", b.endpoint, "slot", payloadAttributesResp.Data.ProposalSlot,
+						"parent", payloadAttributesResp.Data.ParentBlockHash, "reason", err)
+					markRejectedPayloadAttributes(err)
+					return
+				}
+
 				data := types.BuilderPayloadAttributes{
 					Slot:                  payloadAttributesResp.Data.ProposalSlot,
 					HeadHash:              payloadAttributesResp.Data.ParentBlockHash,
@@ -317,12 +684,23 @@ func (b *BeaconClient) SubscribeToPayloadAttributesEvents(payloadAttrC chan type
 					SuggestedFeeRecipient: payloadAttributesResp.Data.PayloadAttributes.SuggestedFeeRecipient,
 					Withdrawals:           withdrawals,
 				}
-				payloadAttrC <- data
+				// the receiver stops reading once the client is stopped
+				select {
+				case payloadAttrC <- data:
+				case <-b.ctx.Done():
+				}
 			}
 		})
+		if b.ctx.Err() != nil {
+			return
+		}
 		if err != nil {
 			log.Error("failed to subscribe to payload_attributes events", "err", err)
-			time.Sleep(1 * time.Second)
+			select {
+			case <-b.ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
 		}
 		log.Warn("beaconclient SubscribeRaw ended, reconnecting")
 	}
@@ -363,11 +741,34 @@ func fetchCurrentSlot(endpoint string) (uint64, error) {
 	return uint64(slot), nil
 }
 
-func fetchEpochProposersMap(endpoint string, epoch uint64) (map[uint64]PubkeyHex, error) {
+func fetchGenesisTime(endpoint string) (uint64, error) {
+	genesisRes := &struct {
+		Data struct {
+			GenesisTime uint64 `json:"genesis_time,string"`
+		} `json:"data"`
+	}{}
+
+	err := fetchBeacon(endpoint+"/eth/v1/beacon/genesis", genesisRes)
+	if err != nil {
+		return uint64(0), err
+	}
+	if genesisRes.Data.GenesisTime == 0 {
+		return uint64(0), errors.New("invalid response")
+	}
+	return genesisRes.Data.GenesisTime, nil
+}
+
+type proposerDuty struct {
+	Pubkey         PubkeyHex
+	ValidatorIndex uint64
+}
+
+func fetchEpochProposersMap(endpoint string, epoch uint64) (map[uint64]proposerDuty, error) {
 	proposerDutiesResponse := &struct {
 		Data []struct {
-			PubkeyHex string `json:"pubkey"`
-			Slot      string `json:"slot"`
+			PubkeyHex      string `json:"pubkey"`
+			ValidatorIndex uint64 `json:"validator_index,string"`
+			Slot           string `json:"slot"`
 		} `json:"data"`
 	}{}
 
@@ -376,20 +777,48 @@ func fetchEpochProposersMap(endpoint string, epoch uint64) (map[uint64]PubkeyHex
 		return nil, err
 	}
 
-	proposersMap := make(map[uint64]PubkeyHex)
-	for _, proposerDuty := range proposerDutiesResponse.Data {
-		slot, err := strconv.Atoi(proposerDuty.Slot)
+	proposersMap := make(map[uint64]proposerDuty)
+	for _, duty := range proposerDutiesResponse.Data {
+		slot, err := strconv.Atoi(duty.Slot)
 		if err != nil {
-			log.Error("could not parse slot", "Slot", proposerDuty.Slot, "err", err)
+			log.Error("could not parse slot", "Slot", duty.Slot, "err", err)
 			continue
 		}
-		proposersMap[uint64(slot)] = PubkeyHex(proposerDuty.PubkeyHex)
+		proposersMap[uint64(slot)] = proposerDuty{PubkeyHex(duty.PubkeyHex), duty.ValidatorIndex}
 	}
 
 	fmt.Println("fetchEpochProposersMap() proposersMap:", proposersMap)
 	return proposersMap, nil
 }
 
+// fetchActiveValidators returns the lowercase pubkeys among the given ones that belong to active validators at the
+// head state
+func fetchActiveValidators(endpoint string, pubkeys []PubkeyHex) (map[PubkeyHex]struct{}, error) {
+	ids := make([]string, len(pubkeys))
+	for i, pubkey := range pubkeys {
+		ids[i] = string(pubkey)
+	}
+
+	validatorsResponse := &struct {
+		Data []struct {
+			Validator struct {
+				Pubkey string `json:"pubkey"`
+			} `json:"validator"`
+		} `json:"data"`
+	}{}
+
+	err := fetchBeacon(endpoint+"/eth/v1/beacon/states/head/validators?status=active&id="+strings.Join(ids, ","), validatorsResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	validators := make(map[PubkeyHex]struct{}, len(validatorsResponse.Data))
+	for _, v := range validatorsResponse.Data {
+		validators[PubkeyHex(strings.ToLower(v.Validator.Pubkey))] = struct{}{}
+	}
+	return validators, nil
+}
+
 func fetchBeacon(url string, dst any) error {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -425,10 +854,10 @@ func fetchBeacon(url string, dst any) error {
 
 	err = json.Unmarshal(bodyBytes, dst)
 	if err != nil {
-		log.Error("could not unmarshal response", "url", url, "resp", string(bodyBytes), "dst", dst, "err", err)
+		log.Error("could not unmarshal response", "url", url, "err", err)
 		return err
 	}
 
-	log.Info("fetched", "url", url, "res", dst)
+	log.Trace("fetched", "url", url)
 	return nil
 }
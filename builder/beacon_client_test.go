@@ -1,11 +1,17 @@
 package builder
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/require"
 )
@@ -17,6 +23,10 @@ type mockBeaconNode struct {
 	forkResp       map[int][]byte
 	headersCode    int
 	headersResp    []byte
+	validatorsResp []byte
+	genesisResp    []byte
+
+	validatorsQueries []url.Values
 }
 
 func newMockBeaconNode() *mockBeaconNode {
@@ -55,6 +65,17 @@ func newMockBeaconNode() *mockBeaconNode {
 		w.Write(resp)
 	})
 
+	r.HandleFunc("/eth/v1/beacon/states/head/validators", func(w http.ResponseWriter, r *http.Request) {
+		mbn.validatorsQueries = append(mbn.validatorsQueries, r.URL.Query())
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(mbn.validatorsResp)
+	})
+
+	r.HandleFunc("/eth/v1/beacon/genesis", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(mbn.genesisResp)
+	})
+
 	r.HandleFunc("/eth/v1/beacon/headers", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(mbn.headersCode)
@@ -147,6 +168,28 @@ func TestFetchCurrentSlot(t *testing.T) {
 	require.Equal(t, uint64(0), slot)
 }
 
+func TestFetchGenesisTime(t *testing.T) {
+	mbn := newMockBeaconNode()
+	defer mbn.srv.Close()
+
+	mbn.genesisResp = []byte(`{"data":{"genesis_time":"1606824023","genesis_validators_root":"0x4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95","genesis_fork_version":"0x00000000"}}`)
+	genesisTime, err := fetchGenesisTime(mbn.srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1606824023), genesisTime)
+
+	b := NewBeaconClient(mbn.srv.URL, 32, 12, false)
+	_, found := b.genesisTime()
+	require.False(t, found)
+	b.updateGenesisTime()
+	genesisTime, found = b.genesisTime()
+	require.True(t, found)
+	require.Equal(t, uint64(1606824023), genesisTime)
+
+	mbn.genesisResp = []byte(`{"data":{}}`)
+	_, err = fetchGenesisTime(mbn.srv.URL)
+	require.EqualError(t, err, "invalid response")
+}
+
 func TestFetchEpochProposersMap(t *testing.T) {
 	mbn := newMockBeaconNode()
 	defer mbn.srv.Close()
@@ -171,6 +214,174 @@ func TestFetchEpochProposersMap(t *testing.T) {
 	proposersMap, err := fetchEpochProposersMap(mbn.srv.URL, 10)
 	require.NoError(t, err)
 	require.Equal(t, 2, len(proposersMap))
-	require.Equal(t, PubkeyHex("0x93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74a"), proposersMap[1])
-	require.Equal(t, PubkeyHex("0x93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74b"), proposersMap[2])
+	require.Equal(t, PubkeyHex("0x93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74a"), proposersMap[1].Pubkey)
+	require.Equal(t, uint64(1), proposersMap[1].ValidatorIndex)
+	require.Equal(t, PubkeyHex("0x93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74b"), proposersMap[2].Pubkey)
+	require.Equal(t, uint64(2), proposersMap[2].ValidatorIndex)
+}
+
+func TestBeaconClientCheckProposerIndex(t *testing.T) {
+	b := NewBeaconClient("http://node", 32, 12, false)
+	b.slotProposerMap[10] = proposerDuty{Pubkey: "0x01", ValidatorIndex: 5}
+
+	require.NoError(t, b.checkProposerIndex(10, 5))
+	require.ErrorIs(t, b.checkProposerIndex(10, 6), errPayloadAttrsProposer)
+	// unknown duties
+	require.NoError(t, b.checkProposerIndex(11, 6))
+}
+
+func TestBeaconClientProposerForSlot(t *testing.T) {
+	m := NewMultiBeaconClient([]string{"http://node0", "http://node1"}, 32, 12, 1, false)
+	b := m.clients[0]
+	b.slotProposerMap[10] = proposerDuty{Pubkey: "0x01", ValidatorIndex: 5}
+
+	proposer, found := b.proposerForSlot(10)
+	require.True(t, found)
+	require.Equal(t, PubkeyHex("0x01"), proposer)
+	_, found = b.proposerForSlot(11)
+	require.False(t, found)
+
+	// the multi client looks the duties up in every node
+	m.clients[1].slotProposerMap[11] = proposerDuty{Pubkey: "0x02", ValidatorIndex: 6}
+	proposer, found = m.proposerForSlot(11)
+	require.True(t, found)
+	require.Equal(t, PubkeyHex("0x02"), proposer)
+	_, found = m.proposerForSlot(12)
+	require.False(t, found)
+}
+
+func TestBeaconClientActiveValidators(t *testing.T) {
+	mbn := newMockBeaconNode()
+	defer mbn.srv.Close()
+
+	mbn.validatorsResp = []byte(`{
+  "execution_optimistic": false,
+  "data": [
+    {
+      "index": "1",
+      "balance": "32000000000",
+      "status": "active_ongoing",
+      "validator": {
+        "pubkey": "0x93247F2209ABCACF57B75A51DAFAE777F9DD38BC7053D1AF526F220A7489A6D3A2753E5F3E8B1CFE39B56F43611DF74A",
+        "withdrawal_credentials": "0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2",
+        "effective_balance": "32000000000",
+        "slashed": false,
+        "activation_eligibility_epoch": "0",
+        "activation_epoch": "0",
+        "exit_epoch": "18446744073709551615",
+        "withdrawable_epoch": "18446744073709551615"
+      }
+    }
+  ]
+}`)
+
+	validator := PubkeyHex("0x93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74a")
+	other := PubkeyHex("0x93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74b")
+
+	// without tracking every pubkey is accepted
+	b := NewBeaconClient(mbn.srv.URL, 32, 12, false)
+	active, err := b.activeValidators([]PubkeyHex{other})
+	require.NoError(t, err)
+	require.Equal(t, map[PubkeyHex]struct{}{other: {}}, active)
+	require.Empty(t, mbn.validatorsQueries)
+
+	// only the submitted pubkeys are looked up
+	b = NewBeaconClient(mbn.srv.URL, 32, 12, true)
+	b.updateValidators(1)
+	active, err = b.activeValidators([]PubkeyHex{PubkeyHex(strings.ToUpper(string(validator))), other})
+	require.NoError(t, err)
+	require.Equal(t, map[PubkeyHex]struct{}{validator: {}}, active)
+	require.Len(t, mbn.validatorsQueries, 1)
+	require.Equal(t, string(validator)+","+string(other), mbn.validatorsQueries[0].Get("id"))
+	require.Equal(t, "active", mbn.validatorsQueries[0].Get("status"))
+
+	// active validators are cached for the epoch
+	mbn.validatorsResp = []byte(`{"data": []}`)
+	active, err = b.activeValidators([]PubkeyHex{validator})
+	require.NoError(t, err)
+	require.Contains(t, active, validator)
+	require.Len(t, mbn.validatorsQueries, 1)
+	b.updateValidators(2)
+	active, err = b.activeValidators([]PubkeyHex{validator})
+	require.NoError(t, err)
+	require.Empty(t, active)
+	require.Len(t, mbn.validatorsQueries, 2)
+
+	// the lookup fails if the beacon node is not available
+	mbn.srv.Close()
+	_, err = b.activeValidators([]PubkeyHex{validator})
+	require.Error(t, err)
+}
+
+func TestMultiBeaconClientPayloadAttributes(t *testing.T) {
+	m := NewMultiBeaconClient([]string{"http://node0", "http://node1", "http://node2"}, 32, 12, 2, false)
+	now := time.Now()
+
+	attrs := &types.BuilderPayloadAttributes{Slot: 10, HeadHash: common.Hash{0x01}, Timestamp: 120}
+
+	// forwarded once the quorum announced them, duplicates are dropped
+	require.False(t, m.onPayloadAttributes(0, attrs, now))
+	require.False(t, m.onPayloadAttributes(0, attrs, now))
+	require.True(t, m.onPayloadAttributes(1, attrs, now))
+	require.False(t, m.onPayloadAttributes(2, attrs, now))
+
+	// a node on another head is not enough to build on it and is reported
+	forked := &types.BuilderPayloadAttributes{Slot: 10, HeadHash: common.Hash{0x02}, Timestamp: 120}
+	require.False(t, m.onPayloadAttributes(2, forked, now))
+	different := &types.BuilderPayloadAttributes{Slot: 10, HeadHash: common.Hash{0x01}, Timestamp: 121}
+	require.False(t, m.onPayloadAttributes(1, different, now))
+
+	status := m.nodesStatus(now)
+	require.Len(t, status, 3)
+	require.Equal(t, BeaconNodeStatus{Endpoint: "http://node0", Healthy: true, HeadSlot: 10, LastEventAt: now}, status[0])
+	require.Equal(t, uint64(1), status[1].Conflicts)
+	require.Equal(t, uint64(1), status[2].Conflicts)
+
+	// past slots are ignored and lagging nodes are unhealthy
+	require.False(t, m.onPayloadAttributes(1, &types.BuilderPayloadAttributes{Slot: 12, HeadHash: common.Hash{0x03}}, now))
+	require.False(t, m.onPayloadAttributes(0, attrs, now))
+	status = m.nodesStatus(now)
+	require.False(t, status[0].Healthy)
+	require.Equal(t, uint64(2), status[0].SlotLag)
+	require.True(t, status[1].Healthy)
+
+	clients := m.clientsByHealth()
+	require.Equal(t, "http://node1", clients[0].endpoint)
+	require.Equal(t, "http://node0", clients[1].endpoint)
+
+	// silent nodes are unhealthy
+	status = m.nodesStatus(now.Add(25 * time.Second))
+	require.False(t, status[1].Healthy)
+}
+
+func TestBeaconClientStopUnblocksEvents(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for slot := 1; r.Context().Err() == nil; slot++ {
+			fmt.Fprintf(w, "event: payload_attributes\ndata: {\"data\": {\"proposal_slot\": \"%d\", \"payload_attributes\": {\"timestamp\": \"12\"}}}\n\n", slot)
+			w.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	m := NewMultiBeaconClient([]string{srv.URL}, 32, 12, 1, false)
+	payloadAttrC := make(chan types.BuilderPayloadAttributes)
+	m.SubscribeToPayloadAttributesEvents(payloadAttrC)
+	<-payloadAttrC
+
+	// nothing reads the events anymore, the subscription of the node ends once the client is stopped
+	done := make(chan struct{})
+	go func() {
+		m.clients[0].SubscribeToPayloadAttributesEvents(make(chan types.BuilderPayloadAttributes))
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	m.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("subscription is blocked on an event nobody reads")
+	}
 }
@@ -0,0 +1,279 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/flashbotsextra"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	BidObserverPollIntervalDefault = 500 * time.Millisecond
+	// bidObserverSlotsKept is the number of slots for which the observed bids are kept in memory
+	bidObserverSlotsKept = 64
+
+	_PathBuilderBlocksReceived = "/relay/v1/data/bidtraces/builder_blocks_received"
+)
+
+// relayBidTrace is a bid received by the relay as served by the relay data API
+type relayBidTrace struct {
+	Slot          uint64      `json:"slot,string"`
+	ParentHash    common.Hash `json:"parent_hash"`
+	BlockHash     common.Hash `json:"block_hash"`
+	BuilderPubkey string      `json:"builder_pubkey"`
+	Value         string      `json:"value"`
+	TimestampMs   int64       `json:"timestamp_ms,string"`
+}
+
+type observedBidKey struct {
+	relay     string
+	blockHash common.Hash
+}
+
+// BidObserver polls the data API of the relays for the bids received for the current slot. Observed bids are kept
+// for recent slots, metered, and stored through the database service.
+type BidObserver struct {
+	relays        []RelayConfig
+	clients       []http.Client
+	builderPubkey string
+	ds            flashbotsextra.IDatabaseService
+	pollInterval  time.Duration
+
+	slotC    chan uint64
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	mu   sync.Mutex
+	bids map[uint64][]builderapi.ObservedBid
+	seen map[observedBidKey]struct{}
+	// updated is closed and replaced when new bids were observed
+	updated chan struct{}
+}
+
+func NewBidObserver(relays []RelayConfig, builderPubkey phase0.BLSPubKey, ds flashbotsextra.IDatabaseService, pollInterval time.Duration) *BidObserver {
+	if pollInterval <= 0 {
+		pollInterval = BidObserverPollIntervalDefault
+	}
+	if ds == nil {
+		ds = flashbotsextra.NilDbService{}
+	}
+
+	clients := make([]http.Client, len(relays))
+	for i, relay := range relays {
+		relays[i] = relay.withDefaults()
+		clients[i] = newRelayHTTPClient(relays[i])
+	}
+
+	return &BidObserver{
+		relays:        relays,
+		clients:       clients,
+		builderPubkey: strings.ToLower(builderPubkey.String()),
+		ds:            ds,
+		pollInterval:  pollInterval,
+		slotC:         make(chan uint64, 1),
+		stop:          make(chan struct{}),
+		bids:          make(map[uint64][]builderapi.ObservedBid),
+		seen:          make(map[observedBidKey]struct{}),
+		updated:       make(chan struct{}),
+	}
+}
+
+func (o *BidObserver) Start() {
+	go o.run()
+}
+
+func (o *BidObserver) Stop() {
+	o.stopOnce.Do(func() { close(o.stop) })
+}
+
+// ObserveSlot switches polling to the slot, the previous slot is polled a last time to get its final bids
+func (o *BidObserver) ObserveSlot(slot uint64) {
+	for {
+		select {
+		case o.slotC <- slot:
+			return
+		default:
+		}
+		// replace the slot not picked up yet
+		select {
+		case <-o.slotC:
+		default:
+		}
+	}
+}
+
+func (o *BidObserver) run() {
+	ticker := time.NewTicker(o.pollInterval)
+	defer ticker.Stop()
+
+	var slot uint64
+	for {
+		select {
+		case <-o.stop:
+			return
+		case newSlot := <-o.slotC:
+			if newSlot <= slot {
+				continue
+			}
+			if slot != 0 {
+				o.poll(slot)
+			}
+			slot = newSlot
+			o.prune(slot)
+			o.poll(slot)
+		case <-ticker.C:
+			if slot != 0 {
+				o.poll(slot)
+			}
+		}
+	}
+}
+
+// poll fetches the bids of the slot from all relays and records the ones not seen yet
+func (o *BidObserver) poll(slot uint64) {
+	var wg sync.WaitGroup
+	for i := range o.relays {
+		wg.Add(1)
+		go func(relay RelayConfig, client http.Client) {
+			defer wg.Done()
+
+			bids, err := o.fetchBids(relay, client, slot)
+			if err != nil {
+				log.Debug("could not fetch bids from relay", "relay", relayMetricsName(relay.Endpoint), "slot", slot, "err", err)
+				markBidPollFailure(relay.Endpoint)
+				return
+			}
+
+			newBids := o.record(slot, bids)
+			markObservedBids(relay.Endpoint, len(newBids))
+			if len(newBids) > 0 {
+				go o.ds.ConsumeObservedBids(newBids)
+			}
+		}(o.relays[i], o.clients[i])
+	}
+	wg.Wait()
+
+	if top := o.TopBid(slot, common.Hash{}); top != nil {
+		markTopBid(top)
+	}
+}
+
+func (o *BidObserver) fetchBids(relay RelayConfig, client http.Client, slot uint64) ([]builderapi.ObservedBid, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), relay.Timeout)
+	defer cancel()
+
+	var traces []relayBidTrace
+	url := fmt.Sprintf("%s%s?slot=%d", relay.Endpoint, _PathBuilderBlocksReceived, slot)
+	if _, err := SendHTTPRequest(ctx, client, http.MethodGet, url, nil, &traces); err != nil {
+		return nil, err
+	}
+
+	observedAt := time.Now()
+	bids := make([]builderapi.ObservedBid, 0, len(traces))
+	for _, trace := range traces {
+		value, ok := new(big.Int).SetString(trace.Value, 10)
+		if !ok || trace.Slot != slot {
+			log.Debug("ignoring malformed bid trace", "relay", relayMetricsName(relay.Endpoint), "slot", trace.Slot, "value", trace.Value)
+			continue
+		}
+		bids = append(bids, builderapi.ObservedBid{
+			Relay:         relay.Endpoint,
+			Slot:          trace.Slot,
+			ParentHash:    trace.ParentHash,
+			BlockHash:     trace.BlockHash,
+			BuilderPubkey: strings.ToLower(trace.BuilderPubkey),
+			Value:         value,
+			ReceivedAt:    time.UnixMilli(trace.TimestampMs),
+			ObservedAt:    observedAt,
+		})
+	}
+	return bids, nil
+}
+
+func (o *BidObserver) record(slot uint64, bids []builderapi.ObservedBid) []builderapi.ObservedBid {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var newBids []builderapi.ObservedBid
+	for _, bid := range bids {
+		key := observedBidKey{relay: bid.Relay, blockHash: bid.BlockHash}
+		if _, found := o.seen[key]; found {
+			continue
+		}
+		o.seen[key] = struct{}{}
+		newBids = append(newBids, bid)
+	}
+	o.bids[slot] = append(o.bids[slot], newBids...)
+	if len(newBids) > 0 {
+		close(o.updated)
+		o.updated = make(chan struct{})
+	}
+	return newBids
+}
+
+// prune drops the bids of the slots too far behind the current slot
+func (o *BidObserver) prune(currentSlot uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for slot, bids := range o.bids {
+		if slot+bidObserverSlotsKept > currentSlot {
+			continue
+		}
+		for _, bid := range bids {
+			delete(o.seen, observedBidKey{relay: bid.Relay, blockHash: bid.BlockHash})
+		}
+		delete(o.bids, slot)
+	}
+}
+
+// SlotBids returns the bids observed for the slot ordered by the time the relays received them
+func (o *BidObserver) SlotBids(slot uint64) []builderapi.ObservedBid {
+	o.mu.Lock()
+	bids := make([]builderapi.ObservedBid, len(o.bids[slot]))
+	copy(bids, o.bids[slot])
+	o.mu.Unlock()
+
+	sort.SliceStable(bids, func(i, j int) bool {
+		return bids[i].ReceivedAt.Before(bids[j].ReceivedAt)
+	})
+	return bids
+}
+
+// TopBidUpdated returns a channel closed once new bids were observed
+func (o *BidObserver) TopBidUpdated() <-chan struct{} {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.updated
+}
+
+// TopBid returns the highest bid of other builders observed for the slot and parent, nil if none were observed.
+// An empty parent hash matches the bids on every parent.
+func (o *BidObserver) TopBid(slot uint64, parentHash common.Hash) *big.Int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var top *big.Int
+	for _, bid := range o.bids[slot] {
+		if bid.BuilderPubkey == o.builderPubkey || (parentHash != (common.Hash{}) && bid.ParentHash != parentHash) {
+			continue
+		}
+		if top == nil || bid.Value.Cmp(top) > 0 {
+			top = bid.Value
+		}
+	}
+	if top == nil {
+		return nil
+	}
+	return new(big.Int).Set(top)
+}
@@ -0,0 +1,119 @@
+package builder
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/flashbotsextra"
+	"github.com/stretchr/testify/require"
+)
+
+type testBidsDbService struct {
+	flashbotsextra.NilDbService
+	bidsCh chan []builderapi.ObservedBid
+}
+
+func (ds testBidsDbService) ConsumeObservedBids(bids []builderapi.ObservedBid) {
+	ds.bidsCh <- bids
+}
+
+func TestBidObserver(t *testing.T) {
+	builderPubkey := phase0.BLSPubKey{0x01}
+	parentHash := common.HexToHash("0x0a")
+
+	traces := []map[string]string{
+		{"slot": "10", "parent_hash": parentHash.String(), "block_hash": common.HexToHash("0x01").String(), "builder_pubkey": "0x02", "value": "200", "timestamp_ms": "2000"},
+		{"slot": "10", "parent_hash": parentHash.String(), "block_hash": common.HexToHash("0x02").String(), "builder_pubkey": "0x03", "value": "100", "timestamp_ms": "1000"},
+		// bid of the builder itself
+		{"slot": "10", "parent_hash": parentHash.String(), "block_hash": common.HexToHash("0x03").String(), "builder_pubkey": builderPubkey.String(), "value": "500", "timestamp_ms": "3000"},
+		// bid on a different parent
+		{"slot": "10", "parent_hash": common.HexToHash("0x0b").String(), "block_hash": common.HexToHash("0x04").String(), "builder_pubkey": "0x02", "value": "300", "timestamp_ms": "4000"},
+		{"slot": "10", "parent_hash": parentHash.String(), "block_hash": common.HexToHash("0x05").String(), "builder_pubkey": "0x02", "value": "malformed", "timestamp_ms": "5000"},
+	}
+	requestedSlots := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, _PathBuilderBlocksReceived, r.URL.Path)
+		select {
+		case requestedSlots <- r.URL.Query().Get("slot"):
+		default:
+		}
+		json.NewEncoder(w).Encode(traces)
+	}))
+	defer srv.Close()
+
+	ds := testBidsDbService{bidsCh: make(chan []builderapi.ObservedBid, 10)}
+	observer := NewBidObserver([]RelayConfig{{Endpoint: srv.URL}}, builderPubkey, ds, time.Hour)
+
+	updated := observer.TopBidUpdated()
+	observer.poll(10)
+	require.Equal(t, "10", <-requestedSlots)
+	stored := <-ds.bidsCh
+	require.True(t, isClosed(updated))
+	require.Len(t, stored, 4)
+
+	bids := observer.SlotBids(10)
+	require.Len(t, bids, 4)
+	require.Equal(t, big.NewInt(100), bids[0].Value)
+	require.Equal(t, srv.URL, bids[0].Relay)
+	require.Equal(t, time.UnixMilli(1000), bids[0].ReceivedAt)
+	require.Empty(t, observer.SlotBids(11))
+
+	require.Equal(t, big.NewInt(200), observer.TopBid(10, parentHash))
+	require.Equal(t, big.NewInt(300), observer.TopBid(10, common.Hash{}))
+	require.Nil(t, observer.TopBid(11, parentHash))
+
+	// bids already observed are neither recorded nor stored again
+	updated = observer.TopBidUpdated()
+	observer.poll(10)
+	require.Len(t, observer.SlotBids(10), 4)
+	require.False(t, isClosed(updated))
+	select {
+	case <-ds.bidsCh:
+		t.Fatal("known bids stored again")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// bids of old slots are dropped
+	observer.prune(10 + bidObserverSlotsKept)
+	require.Empty(t, observer.SlotBids(10))
+}
+
+func TestBidObserverFollowsSlot(t *testing.T) {
+	requestedSlots := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case requestedSlots <- r.URL.Query().Get("slot"):
+		default:
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer srv.Close()
+
+	observer := NewBidObserver([]RelayConfig{{Endpoint: srv.URL}}, phase0.BLSPubKey{}, nil, time.Hour)
+	observer.Start()
+	defer observer.Stop()
+
+	observer.ObserveSlot(10)
+	require.Equal(t, "10", <-requestedSlots)
+
+	// the previous slot is polled a last time before the new one
+	observer.ObserveSlot(11)
+	require.Equal(t, "10", <-requestedSlots)
+	require.Equal(t, "11", <-requestedSlots)
+}
+
+func isClosed(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,225 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
+)
+
+const (
+	BidStrategyFullValue   = "full-value"
+	BidStrategyFixedMargin = "fixed-margin"
+	BidStrategyMatchTopBid = "match-top-bid"
+)
+
+// BidRequest is the state of the building job passed to a BidStrategy for every sealed block
+type BidRequest struct {
+	Slot       uint64
+	ParentHash common.Hash
+	// BlockValue is the value available to pay the proposer, the payment transaction fee is paid from it
+	BlockValue *big.Int
+	// SlotElapsed is the time passed since the start of the slot, negative before the slot starts
+	SlotElapsed time.Duration
+	// TopBid is the highest competing bid observed for the slot, nil if unknown
+	TopBid *big.Int
+}
+
+// Bid is the decision of a BidStrategy
+type Bid struct {
+	// Payment is the value used for the proposer payment, must be positive and at most the block value
+	Payment *big.Int
+	// Submit is false if the block should not be submitted
+	Submit bool
+}
+
+// BidStrategy decides how much of the block value is paid to the proposer and whether to submit the block,
+// the rest of the block value is kept by the builder
+type BidStrategy interface {
+	Bid(req BidRequest) Bid
+}
+
+// TopBidSource provides the highest competing bid observed for the slot
+type TopBidSource interface {
+	TopBid(slot uint64, parentHash common.Hash) *big.Int
+	// TopBidUpdated returns a channel closed once new bids were observed, the top bid may have changed then
+	TopBidUpdated() <-chan struct{}
+}
+
+// topBidStrategy is implemented by strategies whose bid follows the top bid, blocks are rebuilt when it changes
+type topBidStrategy interface {
+	BidStrategy
+	// fullValueAfter is the time into the slot from which the bid no longer follows the top bid, zero if never
+	fullValueAfter() time.Duration
+}
+
+// FullValueBidStrategy pays the whole block value to the proposer
+type FullValueBidStrategy struct{}
+
+func (FullValueBidStrategy) Bid(req BidRequest) Bid {
+	return Bid{Payment: new(big.Int).Set(req.BlockValue), Submit: true}
+}
+
+// FixedMarginBidStrategy keeps a fixed margin of the block value, blocks not worth more than the margin are not submitted
+type FixedMarginBidStrategy struct {
+	Margin *big.Int
+}
+
+func (s FixedMarginBidStrategy) Bid(req BidRequest) Bid {
+	payment := new(big.Int).Sub(req.BlockValue, s.Margin)
+	if payment.Sign() <= 0 {
+		return Bid{Payment: payment, Submit: false}
+	}
+	return Bid{Payment: payment, Submit: true}
+}
+
+// MatchTopBidStrategy outbids the top competing bid by Epsilon and keeps the rest of the block value.
+// The full block value is paid if the top bid is unknown, can not be outbid, or after FullValueAfter into the slot
+// when the top bid might not be up to date anymore.
+type MatchTopBidStrategy struct {
+	Epsilon        *big.Int
+	FullValueAfter time.Duration
+}
+
+func (s MatchTopBidStrategy) Bid(req BidRequest) Bid {
+	if req.TopBid == nil || (s.FullValueAfter > 0 && req.SlotElapsed >= s.FullValueAfter) {
+		return FullValueBidStrategy{}.Bid(req)
+	}
+
+	payment := new(big.Int).Add(req.TopBid, s.Epsilon)
+	if payment.Cmp(req.BlockValue) >= 0 {
+		return FullValueBidStrategy{}.Bid(req)
+	}
+	return Bid{Payment: payment, Submit: true}
+}
+
+func (s MatchTopBidStrategy) fullValueAfter() time.Duration {
+	return s.FullValueAfter
+}
+
+// proposerPaymentFn adapts the strategy to the miner proposer payment hook of a building job
+func proposerPaymentFn(strategy BidStrategy, topBids TopBidSource, attrs *types.BuilderPayloadAttributes, now func() time.Time) miner.ProposerPaymentFn {
+	slotStart := time.Unix(int64(attrs.Timestamp), 0)
+	return func(availableFunds *big.Int) (*big.Int, error) {
+		req := BidRequest{
+			Slot:        attrs.Slot,
+			ParentHash:  attrs.HeadHash,
+			BlockValue:  availableFunds,
+			SlotElapsed: now().Sub(slotStart),
+		}
+		if topBids != nil {
+			req.TopBid = topBids.TopBid(attrs.Slot, attrs.HeadHash)
+		}
+
+		bid := strategy.Bid(req)
+		if !bid.Submit {
+			log.Debug("bid strategy withheld block", "slot", attrs.Slot, "parent", attrs.HeadHash, "value", availableFunds)
+			return nil, miner.ErrProposerPaymentWithheld
+		}
+		return bid.Payment, nil
+	}
+}
+
+// watchBid signals bidChanged when the proposer payment of the job's blocks changes without new orders: when the top
+// bid on the parent changes and when the strategy starts paying the full value. The block sealed before keeps the
+// payment decided when it was built, it has to be rebuilt for a new bid.
+func (b *Builder) watchBid(ctx context.Context, attrs *types.BuilderPayloadAttributes, bidChanged chan<- struct{}) {
+	strategy, ok := b.bidStrategy.(topBidStrategy)
+	if !ok || b.topBids == nil {
+		return
+	}
+
+	signal := func() {
+		select {
+		case bidChanged <- struct{}{}:
+		default:
+		}
+	}
+
+	var deadlineC <-chan time.Time
+	if fullValueAfter := strategy.fullValueAfter(); fullValueAfter > 0 {
+		deadline := time.Unix(int64(attrs.Timestamp), 0).Add(fullValueAfter)
+		if !b.now().Before(deadline) {
+			return
+		}
+		deadlineC = b.after(deadline.Sub(b.now()))
+	}
+
+	updatedC := b.topBids.TopBidUpdated()
+	topBid := b.topBids.TopBid(attrs.Slot, attrs.HeadHash)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadlineC:
+			log.Debug("bid strategy pays the full value, rebuilding block", "slot", attrs.Slot, "parent", attrs.HeadHash)
+			signal()
+			return
+		case <-updatedC:
+			updatedC = b.topBids.TopBidUpdated()
+			top := b.topBids.TopBid(attrs.Slot, attrs.HeadHash)
+			if top == nil || (topBid != nil && top.Cmp(topBid) == 0) {
+				continue
+			}
+			log.Debug("top bid changed, rebuilding block", "slot", attrs.Slot, "parent", attrs.HeadHash, "topBid", top)
+			topBid = top
+			signal()
+		}
+	}
+}
+
+var errInvalidBidStrategy = errors.New("invalid bid strategy")
+
+// parseBidStrategy parses the strategy flag value, one of "full-value", "fixed-margin:<wei>" and
+// "match-top-bid:<wei>[:<full value after duration>]"
+func parseBidStrategy(s string) (BidStrategy, error) {
+	parts := strings.Split(s, ":")
+	parseWei := func(v string) (*big.Int, error) {
+		wei, ok := new(big.Int).SetString(v, 10)
+		if !ok || wei.Sign() < 0 {
+			return nil, fmt.Errorf("%w: invalid wei amount %q", errInvalidBidStrategy, v)
+		}
+		return wei, nil
+	}
+
+	switch parts[0] {
+	case "", BidStrategyFullValue:
+		if len(parts) != 1 {
+			break
+		}
+		return FullValueBidStrategy{}, nil
+	case BidStrategyFixedMargin:
+		if len(parts) != 2 {
+			break
+		}
+		margin, err := parseWei(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		return FixedMarginBidStrategy{Margin: margin}, nil
+	case BidStrategyMatchTopBid:
+		if len(parts) != 2 && len(parts) != 3 {
+			break
+		}
+		epsilon, err := parseWei(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		strategy := MatchTopBidStrategy{Epsilon: epsilon}
+		if len(parts) == 3 {
+			strategy.FullValueAfter, err = time.ParseDuration(parts[2])
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", errInvalidBidStrategy, err)
+			}
+		}
+		return strategy, nil
+	}
+	return nil, fmt.Errorf("%w: %q", errInvalidBidStrategy, s)
+}
@@ -0,0 +1,172 @@
+package builder
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/miner"
+	"github.com/stretchr/testify/require"
+)
+
+type testTopBidSource struct {
+	mu      sync.Mutex
+	topBid  *big.Int
+	updated chan struct{}
+}
+
+func newTestTopBidSource(topBid *big.Int) *testTopBidSource {
+	return &testTopBidSource{topBid: topBid, updated: make(chan struct{})}
+}
+
+func (s *testTopBidSource) TopBid(slot uint64, parentHash common.Hash) *big.Int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.topBid
+}
+
+func (s *testTopBidSource) TopBidUpdated() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.updated
+}
+
+func (s *testTopBidSource) setTopBid(topBid *big.Int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.topBid = topBid
+	close(s.updated)
+	s.updated = make(chan struct{})
+}
+
+func TestBidStrategies(t *testing.T) {
+	req := BidRequest{Slot: 1, BlockValue: big.NewInt(100)}
+
+	bid := FullValueBidStrategy{}.Bid(req)
+	require.True(t, bid.Submit)
+	require.Equal(t, big.NewInt(100), bid.Payment)
+
+	bid = FixedMarginBidStrategy{Margin: big.NewInt(30)}.Bid(req)
+	require.True(t, bid.Submit)
+	require.Equal(t, big.NewInt(70), bid.Payment)
+
+	bid = FixedMarginBidStrategy{Margin: big.NewInt(100)}.Bid(req)
+	require.False(t, bid.Submit)
+
+	matchTopBid := MatchTopBidStrategy{Epsilon: big.NewInt(1), FullValueAfter: 10 * time.Second}
+	// pays the full value when the top bid is unknown
+	bid = matchTopBid.Bid(req)
+	require.True(t, bid.Submit)
+	require.Equal(t, big.NewInt(100), bid.Payment)
+
+	req.TopBid = big.NewInt(50)
+	bid = matchTopBid.Bid(req)
+	require.True(t, bid.Submit)
+	require.Equal(t, big.NewInt(51), bid.Payment)
+
+	// pays the full value when the top bid can not be outbid
+	req.TopBid = big.NewInt(100)
+	bid = matchTopBid.Bid(req)
+	require.True(t, bid.Submit)
+	require.Equal(t, big.NewInt(100), bid.Payment)
+
+	// pays the full value late in the slot
+	req.TopBid = big.NewInt(50)
+	req.SlotElapsed = 11 * time.Second
+	bid = matchTopBid.Bid(req)
+	require.Equal(t, big.NewInt(100), bid.Payment)
+}
+
+func TestProposerPaymentFn(t *testing.T) {
+	clock := &testClock{now: time.Unix(100, 0)}
+	attrs := &types.BuilderPayloadAttributes{Slot: 1, Timestamp: hexutil.Uint64(100)}
+	strategy := MatchTopBidStrategy{Epsilon: big.NewInt(1), FullValueAfter: time.Second}
+
+	payment, err := proposerPaymentFn(strategy, newTestTopBidSource(big.NewInt(10)), attrs, clock.Now)(big.NewInt(100))
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(11), payment)
+
+	// the time into the slot is taken from the clock
+	clock.Advance(time.Second)
+	payment, err = proposerPaymentFn(strategy, newTestTopBidSource(big.NewInt(10)), attrs, clock.Now)(big.NewInt(100))
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(100), payment)
+
+	_, err = proposerPaymentFn(FixedMarginBidStrategy{Margin: big.NewInt(200)}, nil, attrs, clock.Now)(big.NewInt(100))
+	require.ErrorIs(t, err, miner.ErrProposerPaymentWithheld)
+}
+
+func TestWatchBid(t *testing.T) {
+	clock := &testClock{now: time.Unix(100, 0)}
+	attrs := &types.BuilderPayloadAttributes{Slot: 1, Timestamp: hexutil.Uint64(100)}
+	topBids := newTestTopBidSource(big.NewInt(10))
+	b := &Builder{bidStrategy: MatchTopBidStrategy{Epsilon: big.NewInt(1), FullValueAfter: 2 * time.Second}, topBids: topBids, clock: clock}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bidChanged := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		b.watchBid(ctx, attrs, bidChanged)
+		close(done)
+	}()
+
+	requireSignal := func(signalled bool) {
+		t.Helper()
+		select {
+		case <-bidChanged:
+			require.True(t, signalled, "unexpected rebuild")
+		case <-time.After(50 * time.Millisecond):
+			require.False(t, signalled, "missing rebuild")
+		}
+	}
+
+	// new bids not changing the top bid do not rebuild
+	topBids.setTopBid(big.NewInt(10))
+	requireSignal(false)
+	topBids.setTopBid(big.NewInt(20))
+	requireSignal(true)
+
+	// the block is rebuilt once the strategy pays the full value, the top bid is not followed anymore
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+	clock.Advance(2 * time.Second)
+	requireSignal(true)
+	<-done
+
+	// strategies not following the top bid do not rebuild
+	b.bidStrategy = FullValueBidStrategy{}
+	b.watchBid(ctx, attrs, bidChanged)
+	requireSignal(false)
+}
+
+func TestParseBidStrategy(t *testing.T) {
+	strategy, err := parseBidStrategy("")
+	require.NoError(t, err)
+	require.Equal(t, FullValueBidStrategy{}, strategy)
+
+	strategy, err = parseBidStrategy("full-value")
+	require.NoError(t, err)
+	require.Equal(t, FullValueBidStrategy{}, strategy)
+
+	strategy, err = parseBidStrategy("fixed-margin:1000")
+	require.NoError(t, err)
+	require.Equal(t, FixedMarginBidStrategy{Margin: big.NewInt(1000)}, strategy)
+
+	strategy, err = parseBidStrategy("match-top-bid:1")
+	require.NoError(t, err)
+	require.Equal(t, MatchTopBidStrategy{Epsilon: big.NewInt(1)}, strategy)
+
+	strategy, err = parseBidStrategy("match-top-bid:1:10s")
+	require.NoError(t, err)
+	require.Equal(t, MatchTopBidStrategy{Epsilon: big.NewInt(1), FullValueAfter: 10 * time.Second}, strategy)
+
+	for _, s := range []string{"unknown", "full-value:1", "fixed-margin", "fixed-margin:-1", "fixed-margin:abc", "match-top-bid:1:abc"} {
+		_, err = parseBidStrategy(s)
+		require.ErrorIs(t, err, errInvalidBidStrategy, s)
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
-	_ "os"
+	"sort"
 	"sync"
 	"time"
 
@@ -14,10 +14,12 @@ import (
 	bellatrixapi "github.com/attestantio/go-builder-client/api/bellatrix"
 	capellaapi "github.com/attestantio/go-builder-client/api/capella"
 	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
 	"github.com/attestantio/go-eth2-client/spec/bellatrix"
 	"github.com/attestantio/go-eth2-client/spec/capella"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/ethereum/go-ethereum/beacon/engine"
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	blockvalidation "github.com/ethereum/go-ethereum/eth/block-validation"
@@ -35,8 +37,9 @@ const (
 	RateLimitIntervalDefault     = 500 * time.Millisecond
 	RateLimitBurstDefault        = 10
 	BlockResubmitIntervalDefault = 500 * time.Millisecond
+	BlockRebuildIntervalDefault  = 100 * time.Millisecond
 
-	SubmissionOffsetFromEndOfSlotSecondsDefault = 3 * time.Second
+	MaxHeadsDefault = 2
 )
 
 type PubkeyHex string
@@ -48,8 +51,7 @@ type ValidatorData struct {
 }
 
 type IRelay interface {
-	SubmitBlock(msg *bellatrixapi.SubmitBlockRequest, vd ValidatorData) error
-	SubmitBlockCapella(msg *capellaapi.SubmitBlockRequest, vd ValidatorData) error
+	SubmitBlock(ctx context.Context, msg *builderapi.VersionedSubmitBlockRequest, vd ValidatorData) error
 	GetValidatorForSlot(nextSlot uint64) (ValidatorData, error)
 	Config() RelayConfig
 	Start() error
@@ -74,35 +76,67 @@ type Builder struct {
 	builderPublicKey            phase0.BLSPubKey
 	builderSigningDomain        phase0.Domain
 	builderResubmitInterval     time.Duration
+	builderRebuildInterval      time.Duration
 	discardRevertibleTxOnErr    bool
 
-	limiter                       *rate.Limiter
-	submissionOffsetFromEndOfSlot time.Duration
-
-	slotMu        sync.Mutex
-	slotAttrs     types.BuilderPayloadAttributes
-	slotCtx       context.Context
-	slotCtxCancel context.CancelFunc
+	limiter            *rate.Limiter
+	clock              Clock
+	slotTiming         SlotTiming
+	submissionSchedule SubmissionSchedule
+	maxHeads           int
+	secondsInSlot      uint64
+	bidStrategy        BidStrategy
+	topBids            TopBidSource
+	bidObserver        *BidObserver
+	history            *SlotHistory
+	payloads           *PayloadCache
+	shadowLog          *ShadowLog
+
+	slotMu   sync.Mutex
+	slot     uint64
+	slotJobs map[slotJobKey]*slotJob
 
 	stop chan struct{}
 }
 
+// slotJobKey identifies a building job, a job is run for every head announced for the slot
+type slotJobKey struct {
+	slot       uint64
+	parentHash common.Hash
+}
+
+type slotJob struct {
+	attrs     types.BuilderPayloadAttributes
+	startedAt time.Time
+	cancel    context.CancelFunc
+}
+
 // BuilderArgs is a struct that contains all the arguments needed to create a new Builder
 type BuilderArgs struct {
-	sk                            *bls.SecretKey
-	ds                            flashbotsextra.IDatabaseService
-	relay                         IRelay
-	builderSigningDomain          phase0.Domain
-	builderBlockResubmitInterval  time.Duration
-	discardRevertibleTxOnErr      bool
-	eth                           IEthereumService
-	dryRun                        bool
-	ignoreLatePayloadAttributes   bool
-	validator                     *blockvalidation.BlockValidationAPI
-	beaconClient                  IBeaconClient
-	submissionOffsetFromEndOfSlot time.Duration
+	sk                           *bls.SecretKey
+	ds                           flashbotsextra.IDatabaseService
+	relay                        IRelay
+	builderSigningDomain         phase0.Domain
+	builderBlockResubmitInterval time.Duration
+	builderBlockRebuildInterval  time.Duration
+	discardRevertibleTxOnErr     bool
+	eth                          IEthereumService
+	dryRun                       bool
+	ignoreLatePayloadAttributes  bool
+	validator                    *blockvalidation.BlockValidationAPI
+	beaconClient                 IBeaconClient
+	slotTiming                   SlotTiming
+	submissionSchedule           SubmissionSchedule
+	maxHeads                     int
+	secondsInSlot                uint64
+	bidStrategy                  BidStrategy
+	topBids                      TopBidSource
+	bidObserver                  *BidObserver
+	shadowLog                    *ShadowLog
 
 	limiter *rate.Limiter
+	// clock is the source of time of the slot timing, the system clock is used when nil
+	clock Clock
 }
 
 func NewBuilder(args BuilderArgs) (*Builder, error) {
@@ -123,29 +157,63 @@ func NewBuilder(args BuilderArgs) (*Builder, error) {
 		args.builderBlockResubmitInterval = BlockResubmitIntervalDefault
 	}
 
-	if args.submissionOffsetFromEndOfSlot == 0 {
-		args.submissionOffsetFromEndOfSlot = SubmissionOffsetFromEndOfSlotSecondsDefault
+	if args.builderBlockRebuildInterval == 0 {
+		args.builderBlockRebuildInterval = BlockRebuildIntervalDefault
+	}
+
+	if args.maxHeads <= 0 {
+		args.maxHeads = MaxHeadsDefault
+	}
+
+	if args.secondsInSlot == 0 {
+		args.secondsInSlot = DefaultConfig.SecondsInSlot
+	}
+
+	args.slotTiming = args.slotTiming.withDefaults(args.secondsInSlot)
+
+	if args.submissionSchedule.Start == 0 {
+		args.submissionSchedule.Start = args.slotTiming.SubmissionOffset
+	}
+	if args.submissionSchedule.clock == nil {
+		args.submissionSchedule.clock = args.clock
+	}
+
+	if args.bidStrategy == nil {
+		args.bidStrategy = FullValueBidStrategy{}
+	}
+
+	if args.topBids == nil && args.bidObserver != nil {
+		args.topBids = args.bidObserver
 	}
 
-	slotCtx, slotCtxCancel := context.WithCancel(context.Background())
 	return &Builder{
-		ds:                            args.ds,
-		relay:                         args.relay,
-		eth:                           args.eth,
-		dryRun:                        args.dryRun,
-		ignoreLatePayloadAttributes:   args.ignoreLatePayloadAttributes,
-		validator:                     args.validator,
-		beaconClient:                  args.beaconClient,
-		builderSecretKey:              args.sk,
-		builderPublicKey:              pk,
-		builderSigningDomain:          args.builderSigningDomain,
-		builderResubmitInterval:       args.builderBlockResubmitInterval,
-		discardRevertibleTxOnErr:      args.discardRevertibleTxOnErr,
-		submissionOffsetFromEndOfSlot: args.submissionOffsetFromEndOfSlot,
-
-		limiter:       args.limiter,
-		slotCtx:       slotCtx,
-		slotCtxCancel: slotCtxCancel,
+		ds:                          args.ds,
+		relay:                       args.relay,
+		eth:                         args.eth,
+		dryRun:                      args.dryRun,
+		ignoreLatePayloadAttributes: args.ignoreLatePayloadAttributes,
+		validator:                   args.validator,
+		beaconClient:                args.beaconClient,
+		builderSecretKey:            args.sk,
+		builderPublicKey:            pk,
+		builderSigningDomain:        args.builderSigningDomain,
+		builderResubmitInterval:     args.builderBlockResubmitInterval,
+		builderRebuildInterval:      args.builderBlockRebuildInterval,
+		discardRevertibleTxOnErr:    args.discardRevertibleTxOnErr,
+		slotTiming:                  args.slotTiming,
+		submissionSchedule:          args.submissionSchedule,
+		maxHeads:                    args.maxHeads,
+		secondsInSlot:               args.secondsInSlot,
+		bidStrategy:                 args.bidStrategy,
+		topBids:                     args.topBids,
+		bidObserver:                 args.bidObserver,
+		shadowLog:                   args.shadowLog,
+
+		limiter:  args.limiter,
+		clock:    args.clock,
+		history:  newSlotHistory(slotHistorySlotsKept),
+		payloads: NewPayloadCache(PayloadCacheSlotsDefault),
+		slotJobs: make(map[slotJobKey]*slotJob),
 
 		stop: make(chan struct{}, 1),
 	}, nil
@@ -164,14 +232,13 @@ func (b *Builder) Start() error {
 			case <-b.stop:
 				return
 			case payloadAttributes := <-c:
-				// Right now we are building only on a single head. This might change in the future!
+				// Every head announced for the current slot gets its own building job, up to maxHeads
 				if payloadAttributes.Slot < currentSlot {
 					continue
 				} else if payloadAttributes.Slot == currentSlot {
 					// Subsequent sse events should only be canonical!
 					if !b.ignoreLatePayloadAttributes {
 						err := b.OnPayloadAttribute(&payloadAttributes)
-						fmt.Println("ppp payloadAttributes", payloadAttributes)
 						if err != nil {
 							log.Error("error with builder processing on payload attribute",
 								"latestSlot", currentSlot,
@@ -181,12 +248,9 @@ func (b *Builder) Start() error {
 						}
 					}
 				} else if payloadAttributes.Slot > currentSlot {
-					fmt.Println("rrr payloadAttributes Slot", payloadAttributes.Slot)
-					fmt.Println("rrr payloadAttributes currentSlot", currentSlot)
 					currentSlot = payloadAttributes.Slot
 					err := b.OnPayloadAttribute(&payloadAttributes)
 					if err != nil {
-						fmt.Println("qqq payloadAttributes", payloadAttributes)
 						log.Error("error with builder processing on payload attribute",
 							"latestSlot", currentSlot,
 							"processedSlot", payloadAttributes.Slot,
@@ -198,53 +262,48 @@ func (b *Builder) Start() error {
 		}
 	}()
 
+	if b.bidObserver != nil {
+		b.bidObserver.Start()
+	}
+
 	return b.relay.Start()
 }
 
 func (b *Builder) Stop() error {
 	close(b.stop)
-	return nil
-}
-
-func (b *Builder) onSealedBlock(block *types.Block, blockValue *big.Int, ordersClosedAt, sealedAt time.Time,
-	commitedBundles, allBundles []types.SimulatedBundle, usedSbundles []types.UsedSBundle,
-	proposerPubkey phase0.BLSPubKey, vd ValidatorData, attrs *types.BuilderPayloadAttributes) error {
-	if b.eth.Config().IsShanghai(block.Time()) {
-		if err := b.submitCapellaBlock(block, blockValue, ordersClosedAt, sealedAt, commitedBundles, allBundles, usedSbundles, proposerPubkey, vd, attrs); err != nil {
-			return err
-		}
-	} else {
-		if err := b.submitBellatrixBlock(block, blockValue, ordersClosedAt, sealedAt, commitedBundles, allBundles, usedSbundles, proposerPubkey, vd, attrs); err != nil {
-			return err
-		}
+	b.relay.Stop()
+	if b.bidObserver != nil {
+		b.bidObserver.Stop()
 	}
 
-	log.Info("submitted block", "slot", attrs.Slot, "value", blockValue.String(), "parent", block.ParentHash,
-		"hash", block.Hash(), "#commitedBundles", len(commitedBundles))
+	b.slotMu.Lock()
+	defer b.slotMu.Unlock()
+	for key, job := range b.slotJobs {
+		job.cancel()
+		delete(b.slotJobs, key)
+	}
 
+	if b.shadowLog != nil {
+		return b.shadowLog.Close()
+	}
 	return nil
 }
 
-func (b *Builder) submitBellatrixBlock(block *types.Block, blockValue *big.Int, ordersClosedAt, sealedAt time.Time,
+func (b *Builder) onSealedBlock(ctx context.Context, block *types.Block, blockValue *big.Int, ordersClosedAt, sealedAt time.Time,
 	commitedBundles, allBundles []types.SimulatedBundle, usedSbundles []types.UsedSBundle,
 	proposerPubkey phase0.BLSPubKey, vd ValidatorData, attrs *types.BuilderPayloadAttributes) error {
 	executableData := engine.BlockToExecutableData(block, blockValue)
-	payload, err := executableDataToExecutionPayload(executableData.ExecutionPayload)
-	if err != nil {
-		log.Error("could not format execution payload", "err", err)
-		return err
-	}
 
 	value, overflow := uint256.FromBig(blockValue)
 	if overflow {
 		log.Error("could not set block value due to value overflow")
-		return err
+		return errors.New("block value overflow")
 	}
 
 	blockBidMsg := apiv1.BidTrace{
 		Slot:                 attrs.Slot,
-		ParentHash:           payload.ParentHash,
-		BlockHash:            payload.BlockHash,
+		ParentHash:           phase0.Hash32(executableData.ExecutionPayload.ParentHash),
+		BlockHash:            phase0.Hash32(executableData.ExecutionPayload.BlockHash),
 		BuilderPubkey:        b.builderPublicKey,
 		ProposerPubkey:       proposerPubkey,
 		ProposerFeeRecipient: vd.FeeRecipient,
@@ -259,87 +318,169 @@ func (b *Builder) submitBellatrixBlock(block *types.Block, blockValue *big.Int,
 		return err
 	}
 
-	blockSubmitReq := bellatrixapi.SubmitBlockRequest{
-		Signature:        signature,
-		Message:          &blockBidMsg,
-		ExecutionPayload: payload,
+	blockSubmitReq, err := b.getBlockSubmitRequest(executableData.ExecutionPayload, &blockBidMsg, signature)
+	if err != nil {
+		log.Error("could not format execution payload", "err", err)
+		return err
 	}
+	b.cachePayload(blockSubmitReq)
 
 	if b.dryRun {
-		err = b.validator.ValidateBuilderSubmissionV1(&blockvalidation.BuilderBlockValidationRequest{SubmitBlockRequest: blockSubmitReq, RegisteredGasLimit: vd.GasLimit})
+		validatedAt := time.Now()
+		err = b.validator.ValidateSubmission(blockSubmitReq, vd.GasLimit)
+		validationLatency := time.Since(validatedAt)
 		if err != nil {
-			log.Error("could not validate bellatrix block", "err", err)
+			log.Error("could not validate block", "version", blockSubmitReq.Version, "err", err)
+		}
+		if b.shadowLog != nil {
+			b.recordShadowSubmission(blockSubmitReq, attrs.Slot, vd.GasLimit, blockValue, ordersClosedAt, sealedAt, validatedAt, validationLatency, err)
 		}
 	} else {
-		go b.ds.ConsumeBuiltBlock(block, blockValue, ordersClosedAt, sealedAt, commitedBundles, allBundles, usedSbundles, &blockBidMsg)
-		err = b.relay.SubmitBlock(&blockSubmitReq, vd)
+		submittedAt := time.Now()
+		onSubmitted := func(results []builderapi.RelaySubmissionResult) {
+			b.history.recordSubmissions(attrs.Slot, block.Hash(), submittedAt, results)
+			go b.ds.ConsumeBuiltBlock(block, blockValue, ordersClosedAt, sealedAt, commitedBundles, allBundles, usedSbundles, blockSubmitReq, results)
+			if logRelaySubmissionResults(attrs.Slot, block.Hash(), results) {
+				log.Info("payload already delivered for slot, stopping building", "slot", attrs.Slot, "parent", attrs.HeadHash)
+				b.stopSlot(attrs.Slot)
+			}
+		}
+		err = b.submitBlock(ctx, blockSubmitReq, vd, onSubmitted)
 		if err != nil {
-			log.Error("could not submit bellatrix block", "err", err, "#commitedBundles", len(commitedBundles))
+			log.Error("could not submit block", "version", blockSubmitReq.Version, "err", err, "#commitedBundles", len(commitedBundles))
 			return err
 		}
 	}
 
-	log.Info("submitted bellatrix block", "slot", blockBidMsg.Slot, "value", blockBidMsg.Value.String(), "parent", blockBidMsg.ParentHash, "hash", block.Hash(), "#commitedBundles", len(commitedBundles))
+	log.Info("submitted block", "version", blockSubmitReq.Version, "slot", attrs.Slot, "value", blockValue.String(), "parent", block.ParentHash,
+		"hash", block.Hash(), "#commitedBundles", len(commitedBundles))
 
 	return nil
 }
 
-func (b *Builder) submitCapellaBlock(block *types.Block, blockValue *big.Int, ordersClosedAt, sealedAt time.Time,
-	commitedBundles, allBundles []types.SimulatedBundle, usedSbundles []types.UsedSBundle,
-	proposerPubkey phase0.BLSPubKey, vd ValidatorData, attrs *types.BuilderPayloadAttributes) error {
-	executableData := engine.BlockToExecutableData(block, blockValue)
-	payload, err := executableDataToCapellaExecutionPayload(executableData.ExecutionPayload)
-	if err != nil {
-		log.Error("could not format execution payload", "err", err)
-		return err
+// cachePayload keeps the sealed payload so that it can be served after a more valuable block replaced it
+func (b *Builder) cachePayload(msg *builderapi.VersionedSubmitBlockRequest) {
+	entry, err := NewCachedPayload(msg)
+	if errors.Is(err, builderapi.ErrUnsupportedVersion) {
+		return
+	} else if err != nil {
+		log.Error("could not cache payload", "version", msg.Version, "err", err)
+		return
 	}
+	b.payloads.Add(entry)
+}
 
-	value, overflow := uint256.FromBig(blockValue)
-	if overflow {
-		log.Error("could not set block value due to value overflow")
-		return err
-	}
+// IRelayFanOut is implemented by relays that submit to several relays in the background and report the outcome for
+// each of them once all of them completed
+type IRelayFanOut interface {
+	SubmitBlockToRelays(ctx context.Context, msg *builderapi.VersionedSubmitBlockRequest, vd ValidatorData, onDone func([]builderapi.RelaySubmissionResult)) error
+}
 
-	blockBidMsg := apiv1.BidTrace{
-		Slot:                 attrs.Slot,
-		ParentHash:           payload.ParentHash,
-		BlockHash:            payload.BlockHash,
-		BuilderPubkey:        b.builderPublicKey,
-		ProposerPubkey:       proposerPubkey,
-		ProposerFeeRecipient: vd.FeeRecipient,
-		GasLimit:             executableData.ExecutionPayload.GasLimit,
-		GasUsed:              executableData.ExecutionPayload.GasUsed,
-		Value:                value,
+// submitBlock submits the block to the relay and calls onSubmitted with the outcome for every relay it was sent to.
+// A fan out relay returns as soon as the block was handed to its relays and calls onSubmitted later, other relays
+// call it before returning. The errors of the relays are only reported to onSubmitted, the returned error is set if
+// the block could not be handed to the relays.
+func (b *Builder) submitBlock(ctx context.Context, msg *builderapi.VersionedSubmitBlockRequest, vd ValidatorData, onSubmitted func([]builderapi.RelaySubmissionResult)) error {
+	onDone := func(results []builderapi.RelaySubmissionResult) {
+		for _, result := range results {
+			markRelaySubmission(result)
+		}
+		onSubmitted(results)
 	}
 
-	signature, err := ssz.SignMessage(&blockBidMsg, b.builderSigningDomain, b.builderSecretKey)
-	if err != nil {
-		log.Error("could not sign builder bid", "err", err)
-		return err
+	if fanOut, ok := b.relay.(IRelayFanOut); ok {
+		return fanOut.SubmitBlockToRelays(ctx, msg, vd, onDone)
 	}
 
-	blockSubmitReq := capellaapi.SubmitBlockRequest{
-		Signature:        signature,
-		Message:          &blockBidMsg,
-		ExecutionPayload: payload,
+	start := time.Now()
+	err := b.relay.SubmitBlock(ctx, msg, vd)
+	onDone([]builderapi.RelaySubmissionResult{{Relay: b.relay.Config().Endpoint, Latency: time.Since(start), Err: err}})
+	return nil
+}
+
+// logRelaySubmissionResults logs the outcome of the submission to every relay and reports whether one of the relays
+// already delivered the payload of the slot
+func logRelaySubmissionResults(slot uint64, hash common.Hash, results []builderapi.RelaySubmissionResult) (delivered bool) {
+	for _, result := range results {
+		relay := relayMetricsName(result.Relay)
+		switch {
+		case result.Err == nil:
+			log.Debug("relay accepted block", "slot", slot, "hash", hash, "relay", relay, "latency", result.Latency)
+		case errors.Is(result.Err, ErrRelayPayloadAlreadyDelivered):
+			delivered = true
+		case errors.Is(result.Err, errRelaySubmissionSuperseded), errors.Is(result.Err, errRelaySubmitterStopped),
+			errors.Is(result.Err, context.Canceled), errors.Is(result.Err, context.DeadlineExceeded):
+			log.Debug("block not sent to relay", "slot", slot, "hash", hash, "relay", relay, "err", result.Err)
+		case errors.Is(result.Err, ErrRelayBidTooLow), errors.Is(result.Err, ErrRelaySimulationFailed), errors.Is(result.Err, ErrRelayRateLimited):
+			log.Warn("relay rejected block", "slot", slot, "hash", hash, "relay", relay, "latency", result.Latency, "err", result.Err)
+		default:
+			log.Error("could not submit block to relay", "slot", slot, "hash", hash, "relay", relay, "latency", result.Latency, "err", result.Err)
+		}
 	}
+	return delivered
+}
 
-	if b.dryRun {
-		err = b.validator.ValidateBuilderSubmissionV2(&blockvalidation.BuilderBlockValidationRequestV2{SubmitBlockRequest: blockSubmitReq, RegisteredGasLimit: vd.GasLimit})
+// errDenebSubmissionUnsupported is returned for blocks built after cancun. Deneb submissions are out of scope: the
+// execution header does not carry the blob gas fields and the miner does not include blob transactions, so there
+// is neither a deneb payload nor a blobs bundle to submit.
+var errDenebSubmissionUnsupported = fmt.Errorf("%w: deneb payloads are not submitted", builderapi.ErrUnsupportedVersion)
+
+// getBlockSubmitRequest wraps the payload into the submission request of the fork active at the payload timestamp
+func (b *Builder) getBlockSubmitRequest(data *engine.ExecutableData, bidTrace *apiv1.BidTrace, signature phase0.BLSSignature) (*builderapi.VersionedSubmitBlockRequest, error) {
+	switch builderapi.DataVersionAt(b.eth.Config(), data.Timestamp) {
+	case consensusspec.DataVersionDeneb:
+		return nil, errDenebSubmissionUnsupported
+	case consensusspec.DataVersionCapella:
+		payload, err := executableDataToCapellaExecutionPayload(data)
 		if err != nil {
-			log.Error("could not validate block for capella", "err", err)
+			return nil, err
 		}
-	} else {
-		go b.ds.ConsumeBuiltBlock(block, blockValue, ordersClosedAt, sealedAt, commitedBundles, allBundles, usedSbundles, &blockBidMsg)
-		err = b.relay.SubmitBlockCapella(&blockSubmitReq, vd)
+		return &builderapi.VersionedSubmitBlockRequest{
+			Version: consensusspec.DataVersionCapella,
+			Capella: &capellaapi.SubmitBlockRequest{
+				Message:          bidTrace,
+				ExecutionPayload: payload,
+				Signature:        signature,
+			},
+		}, nil
+	default:
+		payload, err := executableDataToExecutionPayload(data)
 		if err != nil {
-			log.Error("could not submit capella block", "err", err, "#commitedBundles", len(commitedBundles))
-			return err
+			return nil, err
 		}
+		return &builderapi.VersionedSubmitBlockRequest{
+			Version: consensusspec.DataVersionBellatrix,
+			Bellatrix: &bellatrixapi.SubmitBlockRequest{
+				Message:          bidTrace,
+				ExecutionPayload: payload,
+				Signature:        signature,
+			},
+		}, nil
 	}
+}
 
-	log.Info("submitted capella block", "slot", blockBidMsg.Slot, "value", blockBidMsg.Value.String(), "parent", blockBidMsg.ParentHash, "hash", block.Hash(), "#commitedBundles", len(commitedBundles))
-	return nil
+// recordShadowSubmission appends the dry run submission and the outcome of its validation to the shadow log
+func (b *Builder) recordShadowSubmission(req *builderapi.VersionedSubmitBlockRequest, slot, registeredGasLimit uint64, blockValue *big.Int,
+	ordersClosedAt, sealedAt, validatedAt time.Time, validationLatency time.Duration, validationErr error,
+) {
+	submission, err := newShadowSubmission(req, slot, registeredGasLimit, blockValue)
+	if errors.Is(err, builderapi.ErrUnsupportedVersion) {
+		log.Warn("not recording shadow submission, submissions of its version can not be validated", "version", req.Version, "slot", slot)
+		return
+	} else if err != nil {
+		log.Error("could not encode shadow submission", "version", req.Version, "err", err)
+		return
+	}
+	submission.OrdersClosedAt = ordersClosedAt
+	submission.SealedAt = sealedAt
+	submission.ValidatedAt = validatedAt
+	submission.ValidationLatency = validationLatency
+	if validationErr != nil {
+		submission.ValidationError = validationErr.Error()
+	}
+	if err := b.shadowLog.Append(submission); err != nil {
+		log.Error("could not record shadow submission", "err", err)
+	}
 }
 
 func (b *Builder) OnPayloadAttribute(attrs *types.BuilderPayloadAttributes) error {
@@ -347,11 +488,15 @@ func (b *Builder) OnPayloadAttribute(attrs *types.BuilderPayloadAttributes) erro
 		return nil
 	}
 
-	fmt.Println("OnPayloadAttribute attrs", attrs)
+	slotTime := time.Unix(int64(attrs.Timestamp), 0)
+	if late := b.now().Sub(slotTime); late > b.slotTiming.PayloadAttributesGrace {
+		log.Warn("rejected payload attributes", "slot", attrs.Slot, "parent", attrs.HeadHash, "timestamp", attrs.Timestamp, "late", late)
+		markRejectedPayloadAttributes(errPayloadAttrsLate)
+		return fmt.Errorf("invalid payload attributes: %w", errPayloadAttrsLate)
+	}
+
 	vd, err := b.relay.GetValidatorForSlot(attrs.Slot)
 	if err != nil {
-		// TODO 这里出错了
-		fmt.Println("vd: ", vd)
 		return fmt.Errorf("could not get validator while submitting block for slot %d - %w", attrs.Slot, err)
 	}
 
@@ -360,6 +505,13 @@ func (b *Builder) OnPayloadAttribute(attrs *types.BuilderPayloadAttributes) erro
 		return fmt.Errorf("parent block hash not found in block tree given head block hash %s", attrs.HeadHash)
 	}
 
+	genesisTime, _ := b.beaconClient.genesisTime()
+	if err := validatePayloadAttributes(attrs, parentBlock, genesisTime, b.secondsInSlot); err != nil {
+		log.Warn("rejected payload attributes", "slot", attrs.Slot, "parent", attrs.HeadHash, "timestamp", attrs.Timestamp, "reason", err)
+		markRejectedPayloadAttributes(err)
+		return fmt.Errorf("invalid payload attributes: %w", err)
+	}
+
 	attrs.SuggestedFeeRecipient = [20]byte(vd.FeeRecipient)
 	attrs.GasLimit = core.CalcGasLimit(parentBlock.GasLimit(), vd.GasLimit)
 
@@ -375,24 +527,97 @@ func (b *Builder) OnPayloadAttribute(attrs *types.BuilderPayloadAttributes) erro
 	b.slotMu.Lock()
 	defer b.slotMu.Unlock()
 
-	if attrs.Equal(&b.slotAttrs) {
-		log.Debug("ignoring known payload attribute", "slot", attrs.Slot, "hash", attrs.HeadHash)
+	if attrs.Slot < b.slot {
+		log.Debug("ignoring payload attribute for past slot", "slot", attrs.Slot, "currentSlot", b.slot)
 		return nil
 	}
 
-	if b.slotCtxCancel != nil {
-		b.slotCtxCancel()
+	// A new slot makes all the jobs of the previous one obsolete
+	if attrs.Slot > b.slot {
+		for key, job := range b.slotJobs {
+			job.cancel()
+			delete(b.slotJobs, key)
+		}
+		b.slot = attrs.Slot
+		if b.bidObserver != nil {
+			b.bidObserver.ObserveSlot(attrs.Slot)
+		}
+	}
+
+	key := slotJobKey{slot: attrs.Slot, parentHash: attrs.HeadHash}
+	if job, found := b.slotJobs[key]; found {
+		if attrs.Equal(&job.attrs) {
+			log.Debug("ignoring known payload attribute", "slot", attrs.Slot, "hash", attrs.HeadHash)
+			return nil
+		}
+		// Attributes for a known head changed, rebuild with the new ones
+		job.cancel()
+		delete(b.slotJobs, key)
+	}
+
+	if len(b.slotJobs) >= b.maxHeads {
+		b.evictOldestSlotJob()
 	}
 
-	slotCtx, slotCtxCancel := context.WithTimeout(context.Background(), 12*time.Second)
-	b.slotAttrs = *attrs
-	b.slotCtx = slotCtx
-	b.slotCtxCancel = slotCtxCancel
+	slotCtx, slotCtxCancel := context.WithTimeout(context.Background(), slotTime.Add(b.slotTiming.SlotDeadline).Sub(b.now()))
+	b.slotJobs[key] = &slotJob{
+		attrs:     *attrs,
+		startedAt: time.Now(),
+		cancel:    slotCtxCancel,
+	}
 
-	go b.runBuildingJob(b.slotCtx, proposerPubkey, vd, attrs)
+	log.Info("starting building job", "slot", attrs.Slot, "parent", attrs.HeadHash, "heads", len(b.slotJobs))
+	go b.runBuildingJob(slotCtx, proposerPubkey, vd, attrs)
 	return nil
 }
 
+func (b *Builder) now() time.Time {
+	if b.clock == nil {
+		return time.Now()
+	}
+	return b.clock.Now()
+}
+
+func (b *Builder) after(d time.Duration) <-chan time.Time {
+	if b.clock == nil {
+		return time.After(d)
+	}
+	return b.clock.After(d)
+}
+
+// stopSlot stops all building jobs of the slot, the jobs stay known so repeated payload attributes do not restart them
+func (b *Builder) stopSlot(slot uint64) {
+	b.slotMu.Lock()
+	defer b.slotMu.Unlock()
+
+	for key, job := range b.slotJobs {
+		if key.slot == slot {
+			job.cancel()
+		}
+	}
+}
+
+// evictOldestSlotJob stops the job that was started first to make room for a new head,
+// later heads are more likely to be canonical. Must be called with slotMu held.
+func (b *Builder) evictOldestSlotJob() {
+	var (
+		oldestKey slotJobKey
+		oldestJob *slotJob
+	)
+	for key, job := range b.slotJobs {
+		if oldestJob == nil || job.startedAt.Before(oldestJob.startedAt) {
+			oldestKey, oldestJob = key, job
+		}
+	}
+	if oldestJob == nil {
+		return
+	}
+
+	log.Info("max heads reached, stopping building job", "slot", oldestKey.slot, "parent", oldestKey.parentHash, "maxHeads", b.maxHeads)
+	oldestJob.cancel()
+	delete(b.slotJobs, oldestKey)
+}
+
 type blockQueueEntry struct {
 	block           *types.Block
 	blockValue      *big.Int
@@ -403,8 +628,61 @@ type blockQueueEntry struct {
 	usedSbundles    []types.UsedSBundle
 }
 
+// Status returns the current slot, the heads being built on and the health of the relays
+func (b *Builder) Status() BuilderStatus {
+	b.slotMu.Lock()
+	slot := b.slot
+	jobs := make([]*slotJob, 0, len(b.slotJobs))
+	for _, job := range b.slotJobs {
+		jobs = append(jobs, job)
+	}
+	b.slotMu.Unlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].startedAt.Before(jobs[j].startedAt)
+	})
+	heads := make([]common.Hash, len(jobs))
+	for i, job := range jobs {
+		heads[i] = job.attrs.HeadHash
+	}
+
+	var relays []RelayStatus
+	if s, ok := b.relay.(IRelayStatus); ok {
+		relays = s.RelayStatus()
+	} else {
+		relays = []RelayStatus{{Endpoint: b.relay.Config().Endpoint}}
+	}
+
+	return BuilderStatus{
+		Slot:   slot,
+		Heads:  heads,
+		Synced: b.eth.Synced(),
+		DryRun: b.dryRun,
+		Relays: b.history.relayStatus(relays),
+	}
+}
+
+// SlotHistory returns the blocks sealed for one of the recent slots and the outcome of their submissions
+func (b *Builder) SlotHistory(slot uint64) []SealedBlockRecord {
+	return b.history.Slot(slot)
+}
+
+// BestBlock returns the most valuable block sealed for the latest slot, nil if none was sealed
+func (b *Builder) BestBlock() *SealedBlockRecord {
+	return b.history.BestBlock()
+}
+
+// Payload returns the payload of one of the blocks sealed for the recent slots, nil if it is not cached
+func (b *Builder) Payload(blockHash common.Hash) *CachedPayload {
+	entry, found := b.payloads.Get(phase0.Hash32(blockHash))
+	if !found {
+		return nil
+	}
+	return entry
+}
+
 func (b *Builder) runBuildingJob(slotCtx context.Context, proposerPubkey phase0.BLSPubKey, vd ValidatorData, attrs *types.BuilderPayloadAttributes) {
-	ctx, cancel := context.WithTimeout(slotCtx, 12*time.Second)
+	ctx, cancel := context.WithCancel(slotCtx)
 	defer cancel()
 
 	// Submission queue for the given payload attributes
@@ -424,28 +702,29 @@ func (b *Builder) runBuildingJob(slotCtx context.Context, proposerPubkey phase0.
 
 	log.Debug("runBuildingJob", "slot", attrs.Slot, "parent", attrs.HeadHash, "payloadTimestamp", uint64(attrs.Timestamp))
 
+	// the outcome of the submissions to the relays is handled when they complete, see onSealedBlock
 	submitBestBlock := func() {
 		queueMu.Lock()
-		if queueBestEntry.block.Hash() != queueLastSubmittedHash {
-			err := b.onSealedBlock(queueBestEntry.block, queueBestEntry.blockValue, queueBestEntry.ordersCloseTime, queueBestEntry.sealedAt,
-				queueBestEntry.commitedBundles, queueBestEntry.allBundles, queueBestEntry.usedSbundles, proposerPubkey, vd, attrs)
+		defer queueMu.Unlock()
+		if queueBestEntry.block.Hash() == queueLastSubmittedHash {
+			return
+		}
 
-			if err != nil {
-				log.Error("could not run sealed block hook", "err", err)
-			} else {
-				queueLastSubmittedHash = queueBestEntry.block.Hash()
-			}
+		err := b.onSealedBlock(ctx, queueBestEntry.block, queueBestEntry.blockValue, queueBestEntry.ordersCloseTime, queueBestEntry.sealedAt,
+			queueBestEntry.commitedBundles, queueBestEntry.allBundles, queueBestEntry.usedSbundles, proposerPubkey, vd, attrs)
+		if err != nil {
+			log.Error("could not run sealed block hook", "err", err)
+			return
 		}
-		queueMu.Unlock()
+		queueLastSubmittedHash = queueBestEntry.block.Hash()
 	}
 
 	// Avoid submitting early into a given slot. For example if slots have 12 second interval, submissions should
-	// not begin until 8 seconds into the slot.
+	// not begin until 8 seconds into the slot. All jobs of the slot follow the same submission schedule.
 	slotTime := time.Unix(int64(attrs.Timestamp), 0).UTC()
-	slotSubmitStartTime := slotTime.Add(-b.submissionOffsetFromEndOfSlot)
 
 	// Empties queue, submits the best block for current job with rate limit (global for all jobs)
-	go runResubmitLoop(ctx, b.limiter, queueSignal, submitBestBlock, slotSubmitStartTime)
+	go runResubmitLoop(ctx, b.limiter, queueSignal, submitBestBlock, b.submissionSchedule, slotTime)
 
 	// Populates queue with submissions that increase block profit
 	blockHook := func(block *types.Block, blockValue *big.Int, ordersCloseTime time.Time,
@@ -456,6 +735,7 @@ func (b *Builder) runBuildingJob(slotCtx context.Context, proposerPubkey phase0.
 		}
 
 		sealedAt := time.Now()
+		b.history.recordSealedBlock(attrs, block, blockValue, ordersCloseTime, sealedAt, committedBundles, usedSbundles)
 
 		queueMu.Lock()
 		defer queueMu.Unlock()
@@ -477,14 +757,37 @@ func (b *Builder) runBuildingJob(slotCtx context.Context, proposerPubkey phase0.
 		}
 	}
 
-	// resubmits block builder requests every builderBlockResubmitInterval
-	runRetryLoop(ctx, b.builderResubmitInterval, func() {
-		log.Debug("retrying BuildBlock",
+	// Decides the proposer payment of every sealed block
+	proposerPayment := proposerPaymentFn(b.bidStrategy, b.topBids, attrs, b.now)
+
+	session, err := b.eth.NewBuildingSession(attrs, proposerPayment, blockHook)
+	if err != nil {
+		log.Warn("Failed to start building session, building blocks from scratch", "slot", attrs.Slot, "parent", attrs.HeadHash, "err", err)
+
+		// resubmits block builder requests every builderBlockResubmitInterval
+		runRetryLoop(ctx, b.builderResubmitInterval, func() {
+			log.Debug("retrying BuildBlock",
+				"slot", attrs.Slot,
+				"parent", attrs.HeadHash,
+				"resubmit-interval", b.builderResubmitInterval.String())
+			err := b.eth.BuildBlock(attrs, proposerPayment, blockHook)
+			if err != nil {
+				log.Warn("Failed to build block", "err", err)
+			}
+		})
+		return
+	}
+	defer session.Close()
+
+	// rebuilds the block when the orders or the bid changed, at most every builderBlockRebuildInterval
+	bidChanged := make(chan struct{}, 1)
+	go b.watchBid(ctx, attrs, bidChanged)
+	runBuildLoop(ctx, b.builderRebuildInterval, session.Changed(), bidChanged, func() {
+		log.Debug("rebuilding block",
 			"slot", attrs.Slot,
 			"parent", attrs.HeadHash,
-			"resubmit-interval", b.builderResubmitInterval.String())
-		err := b.eth.BuildBlock(attrs, blockHook)
-		if err != nil {
+			"rebuild-interval", b.builderRebuildInterval.String())
+		if err := session.Build(); err != nil {
 			log.Warn("Failed to build block", "err", err)
 		}
 	})
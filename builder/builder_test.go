@@ -8,13 +8,16 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 
 	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
 	"github.com/attestantio/go-eth2-client/spec/bellatrix"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/ethereum/go-ethereum/beacon/engine"
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/flashbotsextra"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/flashbots/go-boost-utils/bls"
 	"github.com/flashbots/go-boost-utils/ssz"
 	"github.com/flashbots/go-boost-utils/utils"
@@ -78,7 +81,7 @@ func TestOnPayloadAttributes(t *testing.T) {
 	require.NoError(t, err)
 
 	testPayloadAttributes := &types.BuilderPayloadAttributes{
-		Timestamp:             hexutil.Uint64(104),
+		Timestamp:             hexutil.Uint64(117),
 		Random:                common.Hash{0x05, 0x10},
 		SuggestedFeeRecipient: common.Address{0x04, 0x10},
 		GasLimit:              uint64(payloadAttributeGasLimit),
@@ -97,6 +100,8 @@ func TestOnPayloadAttributes(t *testing.T) {
 		validator:                   nil,
 		beaconClient:                &testBeacon,
 		limiter:                     nil,
+		slotTiming:                  SlotTiming{SlotDeadline: 12 * time.Second},
+		clock:                       newSlotClock(uint64(testPayloadAttributes.Timestamp)),
 	}
 	builder, err := NewBuilder(builderArgs)
 	require.NoError(t, err)
@@ -123,7 +128,18 @@ func TestOnPayloadAttributes(t *testing.T) {
 		Value:                &uint256.Int{0x0a},
 	}
 	copy(expectedMessage.BlockHash[:], hexutil.MustDecode("0x68e516c8827b589fcb749a9e672aa16b9643437459508c467f66a9ed1de66a6c")[:])
-	require.Equal(t, expectedMessage, *testRelay.submittedMsg.Message)
+	require.Equal(t, expectedMessage, *testRelay.submittedMsg.Bellatrix.Message)
+
+	// sealed blocks and their submissions are kept in the slot history
+	history := builder.SlotHistory(25)
+	require.NotEmpty(t, history)
+	require.Equal(t, testBlock.Hash(), history[0].BlockHash)
+	require.Len(t, history[0].Submissions, 1)
+	require.Equal(t, testBlock.Hash(), builder.BestBlock().BlockHash)
+	status := builder.Status()
+	require.Equal(t, uint64(25), status.Slot)
+	require.Len(t, status.Relays, 1)
+	require.Equal(t, uint64(1), status.Relays[0].Submissions)
 
 	expectedExecutionPayload := bellatrix.ExecutionPayload{
 		ParentHash:    [32]byte(testExecutableData.ParentHash),
@@ -142,12 +158,18 @@ func TestOnPayloadAttributes(t *testing.T) {
 		Transactions:  []bellatrix.Transaction{},
 	}
 
-	require.Equal(t, expectedExecutionPayload, *testRelay.submittedMsg.ExecutionPayload)
+	require.Equal(t, expectedExecutionPayload, *testRelay.submittedMsg.Bellatrix.ExecutionPayload)
+
+	// sealed payloads are cached
+	cached := builder.Payload(testBlock.Hash())
+	require.NotNil(t, cached)
+	require.Equal(t, expectedExecutionPayload, *cached.Payload.Bellatrix)
+	require.Nil(t, builder.Payload(common.Hash{0x01}))
 
 	expectedSignature, err := utils.HexToSignature("0x8d1dc346d469b0678ee72baa559315433af0966d2d05dad0de9ce60ff5e4954d4e28a85643496df279494d105bc4a771034fefcdd83d71df5f1b81c9369942b20d6d574b544a93588f6182ba8b09585eb1cf3e1b6551ccbd9e76a4db8eb579fe")
 
 	require.NoError(t, err)
-	require.Equal(t, expectedSignature, testRelay.submittedMsg.Signature)
+	require.Equal(t, expectedSignature, testRelay.submittedMsg.Bellatrix.Signature)
 
 	require.Equal(t, uint64(25), testRelay.requestedSlot)
 
@@ -169,3 +191,92 @@ func TestOnPayloadAttributes(t *testing.T) {
 	time.Sleep(2200 * time.Millisecond)
 	require.NotNil(t, testRelay.submittedMsg)
 }
+
+func TestOnPayloadAttributesMultipleHeads(t *testing.T) {
+	vsk, err := bls.SecretKeyFromBytes(hexutil.MustDecode("0x370bb8c1a6e62b2882f6ec76762a67b39609002076b95aae5b023997cf9b2dc9"))
+	require.NoError(t, err)
+	validator := &ValidatorPrivateData{
+		sk: vsk,
+		Pk: hexutil.MustDecode("0xb67d2c11bcab8c4394fc2faa9601d0b99c7f4b37e14911101da7d97077917862eed4563203d34b91b5cf0aa44d6cfa05"),
+	}
+
+	testRelay := testRelay{
+		gvsVd: ValidatorData{
+			Pubkey:   PubkeyHex(validator.Pk.String()),
+			GasLimit: 30_000_000,
+		},
+	}
+
+	sk, err := bls.SecretKeyFromBytes(hexutil.MustDecode("0x31ee185dad1220a8c88ca5275e64cf5a5cb09cb621cb30df52c9bee8fbaaf8d7"))
+	require.NoError(t, err)
+
+	testBlock := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10), GasLimit: 30_000_000})
+	testEthService := &testEthereumService{synced: true, testBlock: testBlock, testBlockValue: big.NewInt(10)}
+	builder, err := NewBuilder(BuilderArgs{
+		sk:           sk,
+		ds:           flashbotsextra.NilDbService{},
+		relay:        &testRelay,
+		eth:          testEthService,
+		beaconClient: &testBeaconClient{validator: validator},
+		maxHeads:     2,
+		clock:        newSlotClock(12),
+	})
+	require.NoError(t, err)
+	defer builder.Stop()
+
+	attrsForHead := func(slot uint64, head common.Hash) *types.BuilderPayloadAttributes {
+		return &types.BuilderPayloadAttributes{Timestamp: hexutil.Uint64(12), Random: common.Hash{0x01}, Slot: slot, HeadHash: head}
+	}
+	runningHeads := func() []common.Hash {
+		builder.slotMu.Lock()
+		defer builder.slotMu.Unlock()
+		heads := make([]common.Hash, 0, len(builder.slotJobs))
+		for key := range builder.slotJobs {
+			heads = append(heads, key.parentHash)
+		}
+		return heads
+	}
+
+	headA, headB, headC := common.Hash{0x0a}, common.Hash{0x0b}, common.Hash{0x0c}
+
+	require.NoError(t, builder.OnPayloadAttribute(attrsForHead(25, headA)))
+	require.NoError(t, builder.OnPayloadAttribute(attrsForHead(25, headB)))
+	require.ElementsMatch(t, []common.Hash{headA, headB}, runningHeads())
+
+	// known attributes do not restart the job
+	require.NoError(t, builder.OnPayloadAttribute(attrsForHead(25, headB)))
+	require.ElementsMatch(t, []common.Hash{headA, headB}, runningHeads())
+
+	// the earliest head is dropped once max heads is reached
+	require.NoError(t, builder.OnPayloadAttribute(attrsForHead(25, headC)))
+	require.ElementsMatch(t, []common.Hash{headB, headC}, runningHeads())
+
+	// past slots are ignored
+	require.NoError(t, builder.OnPayloadAttribute(attrsForHead(24, headA)))
+	require.ElementsMatch(t, []common.Hash{headB, headC}, runningHeads())
+
+	// a new slot stops all jobs of the previous one
+	require.NoError(t, builder.OnPayloadAttribute(attrsForHead(26, headA)))
+	require.ElementsMatch(t, []common.Hash{headA}, runningHeads())
+}
+
+func TestGetBlockSubmitRequestDeneb(t *testing.T) {
+	sk, err := bls.SecretKeyFromBytes(hexutil.MustDecode("0x31ee185dad1220a8c88ca5275e64cf5a5cb09cb621cb30df52c9bee8fbaaf8d7"))
+	require.NoError(t, err)
+
+	config := *params.TestChainConfig
+	shanghaiTime, cancunTime := uint64(0), uint64(100)
+	config.ShanghaiTime, config.CancunTime = &shanghaiTime, &cancunTime
+	builder, err := NewBuilder(BuilderArgs{sk: sk, ds: flashbotsextra.NilDbService{}, eth: &testEthereumService{config: &config}})
+	require.NoError(t, err)
+
+	data := &engine.ExecutableData{LogsBloom: types.Bloom{}.Bytes(), BaseFeePerGas: big.NewInt(1), Timestamp: 99}
+	req, err := builder.getBlockSubmitRequest(data, &apiv1.BidTrace{}, phase0.BLSSignature{})
+	require.NoError(t, err)
+	require.Equal(t, consensusspec.DataVersionCapella, req.Version)
+
+	// deneb payloads are not submitted
+	data.Timestamp = 100
+	_, err = builder.getBlockSubmitRequest(data, &apiv1.BidTrace{}, phase0.BLSSignature{})
+	require.ErrorIs(t, err, builderapi.ErrUnsupportedVersion)
+}
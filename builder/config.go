@@ -10,14 +10,17 @@ type Config struct {
 	SecondsInSlot                    uint64        `toml:",omitempty"`
 	DisableBundleFetcher             bool          `toml:",omitempty"`
 	DryRun                           bool          `toml:",omitempty"`
+	DryRunLog                        string        `toml:",omitempty"`
 	IgnoreLatePayloadAttributes      bool          `toml:",omitempty"`
 	BuilderSecretKey                 string        `toml:",omitempty"`
 	RelaySecretKey                   string        `toml:",omitempty"`
 	ListenAddr                       string        `toml:",omitempty"`
 	GenesisForkVersion               string        `toml:",omitempty"`
 	BellatrixForkVersion             string        `toml:",omitempty"`
+	CapellaForkVersion               string        `toml:",omitempty"`
 	GenesisValidatorsRoot            string        `toml:",omitempty"`
 	BeaconEndpoints                  []string      `toml:",omitempty"`
+	BeaconQuorum                     int           `toml:",omitempty"`
 	RemoteRelayEndpoint              string        `toml:",omitempty"`
 	SecondaryRemoteRelayEndpoints    []string      `toml:",omitempty"`
 	ValidationBlocklist              string        `toml:",omitempty"`
@@ -25,9 +28,15 @@ type Config struct {
 	BuilderRateLimitDuration         string        `toml:",omitempty"`
 	BuilderRateLimitMaxBurst         int           `toml:",omitempty"`
 	BuilderRateLimitResubmitInterval string        `toml:",omitempty"`
+	BuilderRebuildInterval           time.Duration `toml:",omitempty"`
 	BuilderSubmissionOffset          time.Duration `toml:",omitempty"`
+	BuilderSubmissionSchedule        string        `toml:",omitempty"`
+	SlotTiming                       string        `toml:",omitempty"`
 	DiscardRevertibleTxOnErr         bool          `toml:",omitempty"`
 	EnableCancellations              bool          `toml:",omitempty"`
+	MaxHeads                         int           `toml:",omitempty"`
+	BidStrategy                      string        `toml:",omitempty"`
+	ObserveBids                      bool          `toml:",omitempty"`
 }
 
 // DefaultConfig is the default config for the builder.
@@ -39,27 +48,73 @@ var DefaultConfig = Config{
 	SecondsInSlot:                 12,
 	DisableBundleFetcher:          false,
 	DryRun:                        false,
+	DryRunLog:                     "",
 	IgnoreLatePayloadAttributes:   false,
 	BuilderSecretKey:              "0x2fc12ae741f29701f8e30f5de6350766c020cb80768a0ff01e6838ffd2431e11",
 	RelaySecretKey:                "0x2fc12ae741f29701f8e30f5de6350766c020cb80768a0ff01e6838ffd2431e11",
 	ListenAddr:                    ":28545",
 	GenesisForkVersion:            "0x00000000",
 	BellatrixForkVersion:          "0x02000000",
+	CapellaForkVersion:            "0x03000000",
 	GenesisValidatorsRoot:         "0x0000000000000000000000000000000000000000000000000000000000000000",
 	BeaconEndpoints:               []string{"http://127.0.0.1:5052"},
+	BeaconQuorum:                  BeaconQuorumDefault,
 	RemoteRelayEndpoint:           "",
 	SecondaryRemoteRelayEndpoints: nil,
 	ValidationBlocklist:           "",
 	ValidationUseCoinbaseDiff:     false,
 	BuilderRateLimitDuration:      RateLimitIntervalDefault.String(),
 	BuilderRateLimitMaxBurst:      RateLimitBurstDefault,
+	BuilderRebuildInterval:        BlockRebuildIntervalDefault,
 	DiscardRevertibleTxOnErr:      false,
 	EnableCancellations:           false,
+	MaxHeads:                      MaxHeadsDefault,
+	BidStrategy:                   BidStrategyFullValue,
+	ObserveBids:                   false,
 }
 
+const (
+	RelayTimeoutDefault           = 2 * time.Second
+	RelayValidatorsTimeoutDefault = 10 * time.Second
+	RelayRetryBackoffDefault      = 100 * time.Millisecond
+	RelayMaxIdleConnsDefault      = 10
+)
+
 // RelayConfig is the config for a single remote relay.
 type RelayConfig struct {
 	Endpoint    string
 	SszEnabled  bool
 	GzipEnabled bool
+
+	// HTTP client settings, zero values are replaced with the defaults
+	Timeout time.Duration
+	// ValidatorsTimeout bounds fetching the validator registrations, which can take longer than a submission
+	ValidatorsTimeout time.Duration
+	Retries           int
+	RetryBackoff      time.Duration
+	MaxIdleConns      int
+	DisableHTTP2      bool
+
+	// RateLimitInterval is the minimum time between submissions to the relay, zero disables the per-relay limit
+	RateLimitInterval time.Duration
+}
+
+// withDefaults returns the config with unset HTTP client settings replaced by the defaults
+func (c RelayConfig) withDefaults() RelayConfig {
+	if c.Timeout <= 0 {
+		c.Timeout = RelayTimeoutDefault
+	}
+	if c.ValidatorsTimeout <= 0 {
+		c.ValidatorsTimeout = RelayValidatorsTimeoutDefault
+	}
+	if c.Retries < 0 {
+		c.Retries = 0
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = RelayRetryBackoffDefault
+	}
+	if c.MaxIdleConns <= 0 {
+		c.MaxIdleConns = RelayMaxIdleConnsDefault
+	}
+	return c
 }
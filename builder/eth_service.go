@@ -15,12 +15,23 @@ import (
 )
 
 type IEthereumService interface {
-	BuildBlock(attrs *types.BuilderPayloadAttributes, sealedBlockCallback miner.BlockHookFn) error
+	BuildBlock(attrs *types.BuilderPayloadAttributes, proposerPayment miner.ProposerPaymentFn, sealedBlockCallback miner.BlockHookFn) error
+	NewBuildingSession(attrs *types.BuilderPayloadAttributes, proposerPayment miner.ProposerPaymentFn, sealedBlockCallback miner.BlockHookFn) (BuildingSession, error)
 	GetBlockByHash(hash common.Hash) *types.Block
 	Config() *params.ChainConfig
 	Synced() bool
 }
 
+// BuildingSession builds the blocks of a building job from an environment prepared once, following the orders
+// entering the pool
+type BuildingSession interface {
+	// Changed is signalled when the orders changed since the last build
+	Changed() <-chan struct{}
+	// Build builds a block with the current orders, sealed blocks are passed to the callback of the session
+	Build() error
+	Close()
+}
+
 type testEthereumService struct {
 	synced             bool
 	testExecutableData *engine.ExecutableData
@@ -29,39 +40,76 @@ type testEthereumService struct {
 	testBundlesMerged  []types.SimulatedBundle
 	testAllBundles     []types.SimulatedBundle
 	testUsedSbundles   []types.UsedSBundle
+	// config is the chain config of the service, params.TestChainConfig when nil
+	config *params.ChainConfig
 }
 
-func (t *testEthereumService) BuildBlock(attrs *types.BuilderPayloadAttributes, sealedBlockCallback miner.BlockHookFn) error {
-	sealedBlockCallback(t.testBlock, t.testBlockValue, time.Now(), t.testBundlesMerged, t.testAllBundles, t.testUsedSbundles)
+func (t *testEthereumService) BuildBlock(attrs *types.BuilderPayloadAttributes, proposerPayment miner.ProposerPaymentFn, sealedBlockCallback miner.BlockHookFn) error {
+	blockValue := t.testBlockValue
+	if proposerPayment != nil {
+		payment, err := proposerPayment(new(big.Int).Set(blockValue))
+		if err != nil {
+			return err
+		}
+		blockValue = payment
+	}
+	sealedBlockCallback(t.testBlock, blockValue, time.Now(), t.testBundlesMerged, t.testAllBundles, t.testUsedSbundles)
 	return nil
 }
 
+// NewBuildingSession returns a session whose orders change continuously
+func (t *testEthereumService) NewBuildingSession(attrs *types.BuilderPayloadAttributes, proposerPayment miner.ProposerPaymentFn, sealedBlockCallback miner.BlockHookFn) (BuildingSession, error) {
+	changed := make(chan struct{})
+	close(changed)
+	return &testBuildingSession{changed: changed, build: func() error {
+		return t.BuildBlock(attrs, proposerPayment, sealedBlockCallback)
+	}}, nil
+}
+
+type testBuildingSession struct {
+	changed chan struct{}
+	build   func() error
+}
+
+func (s *testBuildingSession) Changed() <-chan struct{} { return s.changed }
+
+func (s *testBuildingSession) Build() error { return s.build() }
+
+func (s *testBuildingSession) Close() {}
+
 func (t *testEthereumService) GetBlockByHash(hash common.Hash) *types.Block { return t.testBlock }
 
-func (t *testEthereumService) Config() *params.ChainConfig { return params.TestChainConfig }
+func (t *testEthereumService) Config() *params.ChainConfig {
+	if t.config != nil {
+		return t.config
+	}
+	return params.TestChainConfig
+}
 
 func (t *testEthereumService) Synced() bool { return t.synced }
 
 type EthereumService struct {
-	eth *eth.Ethereum
+	eth          *eth.Ethereum
+	buildTimeout time.Duration
 }
 
-func NewEthereumService(eth *eth.Ethereum) *EthereumService {
-	return &EthereumService{eth: eth}
+func NewEthereumService(eth *eth.Ethereum, buildTimeout time.Duration) *EthereumService {
+	return &EthereumService{eth: eth, buildTimeout: buildTimeout}
 }
 
 // TODO: we should move to a setup similar to catalyst local blocks & payload ids
-func (s *EthereumService) BuildBlock(attrs *types.BuilderPayloadAttributes, sealedBlockCallback miner.BlockHookFn) error {
+func (s *EthereumService) BuildBlock(attrs *types.BuilderPayloadAttributes, proposerPayment miner.ProposerPaymentFn, sealedBlockCallback miner.BlockHookFn) error {
 	// Send a request to generate a full block in the background.
 	// The result can be obtained via the returned channel.
 	args := &miner.BuildPayloadArgs{
-		Parent:       attrs.HeadHash,
-		Timestamp:    uint64(attrs.Timestamp),
-		FeeRecipient: attrs.SuggestedFeeRecipient,
-		GasLimit:     attrs.GasLimit,
-		Random:       attrs.Random,
-		Withdrawals:  attrs.Withdrawals,
-		BlockHook:    sealedBlockCallback,
+		Parent:          attrs.HeadHash,
+		Timestamp:       uint64(attrs.Timestamp),
+		FeeRecipient:    attrs.SuggestedFeeRecipient,
+		GasLimit:        attrs.GasLimit,
+		Random:          attrs.Random,
+		Withdrawals:     attrs.Withdrawals,
+		BlockHook:       sealedBlockCallback,
+		ProposerPayment: proposerPayment,
 	}
 
 	payload, err := s.eth.Miner().BuildPayload(args)
@@ -75,7 +123,7 @@ func (s *EthereumService) BuildBlock(attrs *types.BuilderPayloadAttributes, seal
 		resCh <- payload.ResolveFull()
 	}()
 
-	timer := time.NewTimer(4 * time.Second)
+	timer := time.NewTimer(s.buildTimeout)
 	defer timer.Stop()
 
 	select {
@@ -91,6 +139,44 @@ func (s *EthereumService) BuildBlock(attrs *types.BuilderPayloadAttributes, seal
 	}
 }
 
+func (s *EthereumService) NewBuildingSession(attrs *types.BuilderPayloadAttributes, proposerPayment miner.ProposerPaymentFn, sealedBlockCallback miner.BlockHookFn) (BuildingSession, error) {
+	args := &miner.BuildPayloadArgs{
+		Parent:          attrs.HeadHash,
+		Timestamp:       uint64(attrs.Timestamp),
+		FeeRecipient:    attrs.SuggestedFeeRecipient,
+		GasLimit:        attrs.GasLimit,
+		Random:          attrs.Random,
+		Withdrawals:     attrs.Withdrawals,
+		BlockHook:       sealedBlockCallback,
+		ProposerPayment: proposerPayment,
+	}
+
+	session, err := s.eth.Miner().NewBuildingSession(args)
+	if err != nil {
+		return nil, err
+	}
+	return &ethBuildingSession{session: session, buildTimeout: s.buildTimeout}, nil
+}
+
+// ethBuildingSession interrupts the builds of the miner session after the build timeout
+type ethBuildingSession struct {
+	session      *miner.BuildingSession
+	buildTimeout time.Duration
+}
+
+func (s *ethBuildingSession) Changed() <-chan struct{} {
+	return s.session.Changed()
+}
+
+func (s *ethBuildingSession) Build() error {
+	_, _, err := s.session.Build(s.buildTimeout)
+	return err
+}
+
+func (s *ethBuildingSession) Close() {
+	s.session.Close()
+}
+
 func (s *EthereumService) GetBlockByHash(hash common.Hash) *types.Block {
 	return s.eth.BlockChain().GetBlockByHash(hash)
 }
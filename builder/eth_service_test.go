@@ -91,10 +91,10 @@ func TestBuildBlock(t *testing.T) {
 		Slot:                  uint64(25),
 	}
 
-	service := NewEthereumService(ethservice)
+	service := NewEthereumService(ethservice, 4*time.Second)
 	service.eth.APIBackend.Miner().SetEtherbase(common.Address{0x05, 0x11})
 
-	err := service.BuildBlock(testPayloadAttributes, func(block *types.Block, blockValue *big.Int, _ time.Time, _, _ []types.SimulatedBundle, _ []types.UsedSBundle) {
+	err := service.BuildBlock(testPayloadAttributes, nil, func(block *types.Block, blockValue *big.Int, _ time.Time, _, _ []types.SimulatedBundle, _ []types.UsedSBundle) {
 		executableData := engine.BlockToExecutableData(block, blockValue)
 		require.Equal(t, common.Address{0x05, 0x11}, executableData.ExecutionPayload.FeeRecipient)
 		require.Equal(t, common.Hash{0x05, 0x10}, executableData.ExecutionPayload.Random)
@@ -107,3 +107,42 @@ func TestBuildBlock(t *testing.T) {
 
 	require.NoError(t, err)
 }
+
+func TestBuildingSession(t *testing.T) {
+	genesis, blocks := generatePreMergeChain(10)
+	n, ethservice := startEthService(t, genesis, blocks)
+	defer n.Close()
+
+	parent := ethservice.BlockChain().CurrentBlock()
+
+	testPayloadAttributes := &types.BuilderPayloadAttributes{
+		Timestamp:             hexutil.Uint64(parent.Time + 1),
+		Random:                common.Hash{0x05, 0x10},
+		SuggestedFeeRecipient: common.Address{0x04, 0x10},
+		GasLimit:              uint64(4800000),
+		Slot:                  uint64(25),
+	}
+
+	service := NewEthereumService(ethservice, 4*time.Second)
+	service.eth.APIBackend.Miner().SetEtherbase(common.Address{0x05, 0x11})
+
+	sealed := make(chan *types.Block, 1)
+	session, err := service.NewBuildingSession(testPayloadAttributes, nil, func(block *types.Block, blockValue *big.Int, _ time.Time, _, _ []types.SimulatedBundle, _ []types.UsedSBundle) {
+		sealed <- block
+	})
+	require.NoError(t, err)
+	defer session.Close()
+
+	// the first build is due right away
+	select {
+	case <-session.Changed():
+	default:
+		t.Fatal("first build not due")
+	}
+	require.NoError(t, session.Build())
+
+	block := <-sealed
+	require.Equal(t, parent.Hash(), block.ParentHash())
+	require.Equal(t, parent.Time+1, block.Time())
+	require.Equal(t, common.Address{0x05, 0x11}, block.Coinbase())
+}
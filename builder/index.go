@@ -53,13 +53,15 @@ func parseIndexTemplate() (*template.Template, error) {
             <ul>
                 <li>Genesis fork version {{ .GenesisForkVersion }}</li>
                 <li>Bellatrix fork version {{ .BellatrixForkVersion }}</li>
+                <li>Capella fork version {{ .CapellaForkVersion }}</li>
                 <li>Genesis validators root {{ .GenesisValidatorsRoot }}</li>
             </ul>
             </p>
             <p>
             <ul>
                 <li>Builder signing domain {{ .BuilderSigningDomain }}</li>
-                <li>Proposer signing domain {{ .ProposerSigningDomain }}</li>
+                <li>Bellatrix proposer signing domain {{ .BellatrixDomain }}</li>
+                <li>Capella proposer signing domain {{ .CapellaDomain }}</li>
             </ul>
             </p>
 
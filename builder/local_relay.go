@@ -2,41 +2,72 @@ package builder
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/attestantio/go-builder-client/api"
 	bellatrixapi "github.com/attestantio/go-builder-client/api/bellatrix"
 	capellaapi "github.com/attestantio/go-builder-client/api/capella"
 	apiv1 "github.com/attestantio/go-builder-client/api/v1"
 	"github.com/attestantio/go-builder-client/spec"
 	apiv1bellatrix "github.com/attestantio/go-eth2-client/api/v1/bellatrix"
+	apiv1capella "github.com/attestantio/go-eth2-client/api/v1/capella"
 	consensusspec "github.com/attestantio/go-eth2-client/spec"
 	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/attestantio/go-eth2-client/spec/capella"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
 	bellatrixutil "github.com/attestantio/go-eth2-client/util/bellatrix"
+	capellautil "github.com/attestantio/go-eth2-client/util/capella"
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/flashbots/go-boost-utils/bls"
 	"github.com/flashbots/go-boost-utils/ssz"
 	"github.com/flashbots/go-boost-utils/utils"
 	"github.com/gorilla/mux"
-	"github.com/holiman/uint256"
 )
 
 type ForkData struct {
 	GenesisForkVersion    string
 	BellatrixForkVersion  string
+	CapellaForkVersion    string
 	GenesisValidatorsRoot string
 }
 
+// ProposerSigningDomains are the domains proposers sign their blinded blocks with, by fork
+type ProposerSigningDomains map[consensusspec.DataVersion]phase0.Domain
+
+// NewProposerSigningDomains computes the proposer signing domain of every fork with a fork version in fd
+func NewProposerSigningDomains(fd ForkData) (ProposerSigningDomains, error) {
+	genesisValidatorsRoot := phase0.Root(common.HexToHash(fd.GenesisValidatorsRoot))
+	domains := make(ProposerSigningDomains)
+	for version, forkVersionHex := range map[consensusspec.DataVersion]string{
+		consensusspec.DataVersionBellatrix: fd.BellatrixForkVersion,
+		consensusspec.DataVersionCapella:   fd.CapellaForkVersion,
+	} {
+		if forkVersionHex == "" {
+			continue
+		}
+		forkVersionBytes, err := hexutil.Decode(forkVersionHex)
+		if err != nil || len(forkVersionBytes) != 4 {
+			return nil, fmt.Errorf("invalid %s fork version %q", version, forkVersionHex)
+		}
+		var forkVersion [4]byte
+		copy(forkVersion[:], forkVersionBytes)
+		domains[version] = ssz.ComputeDomain(ssz.DomainTypeBeaconProposer, forkVersion, genesisValidatorsRoot)
+	}
+	return domains, nil
+}
+
 type FullValidatorData struct {
 	ValidatorData
 	Timestamp uint64
@@ -49,24 +80,21 @@ type LocalRelay struct {
 	relayPublicKey        phase0.BLSPubKey
 	serializedRelayPubkey hexutil.Bytes
 
-	builderSigningDomain  phase0.Domain
-	proposerSigningDomain phase0.Domain
+	builderSigningDomain   phase0.Domain
+	proposerSigningDomains ProposerSigningDomains
 
 	validatorsLock sync.RWMutex
 	validators     map[PubkeyHex]FullValidatorData
 
 	enableBeaconChecks bool
 
-	bestDataLock sync.Mutex
-	bestHeader   *bellatrix.ExecutionPayloadHeader
-	bestPayload  *bellatrix.ExecutionPayload
-	profit       *uint256.Int
+	payloadCache *PayloadCache
 
 	indexTemplate *template.Template
 	fd            ForkData
 }
 
-func NewLocalRelay(sk *bls.SecretKey, beaconClient IBeaconClient, builderSigningDomain, proposerSigningDomain phase0.Domain, fd ForkData, enableBeaconChecks bool) (*LocalRelay, error) {
+func NewLocalRelay(sk *bls.SecretKey, beaconClient IBeaconClient, builderSigningDomain phase0.Domain, proposerSigningDomains ProposerSigningDomains, fd ForkData, enableBeaconChecks bool) (*LocalRelay, error) {
 	blsPk, err := bls.PublicKeyFromSecretKey(sk)
 	if err != nil {
 		return nil, err
@@ -88,14 +116,16 @@ func NewLocalRelay(sk *bls.SecretKey, beaconClient IBeaconClient, builderSigning
 		relaySecretKey: sk,
 		relayPublicKey: pk,
 
-		builderSigningDomain:  builderSigningDomain,
-		proposerSigningDomain: proposerSigningDomain,
-		serializedRelayPubkey: bls.PublicKeyToBytes(blsPk),
+		builderSigningDomain:   builderSigningDomain,
+		proposerSigningDomains: proposerSigningDomains,
+		serializedRelayPubkey:  bls.PublicKeyToBytes(blsPk),
 
 		validators: make(map[PubkeyHex]FullValidatorData),
 
 		enableBeaconChecks: enableBeaconChecks,
 
+		payloadCache: NewPayloadCache(PayloadCacheSlotsDefault),
+
 		indexTemplate: indexTemplate,
 		fd:            fd,
 	}, nil
@@ -110,15 +140,14 @@ func (r *LocalRelay) Stop() {
 	r.beaconClient.Stop()
 }
 
-func (r *LocalRelay) SubmitBlock(msg *bellatrixapi.SubmitBlockRequest, _ ValidatorData) error {
-	log.Info("submitting block to local relay", "block", msg.ExecutionPayload.BlockHash.String())
-	return r.submitBlock(msg)
-}
-
-func (r *LocalRelay) SubmitBlockCapella(msg *capellaapi.SubmitBlockRequest, _ ValidatorData) error {
-	log.Info("submitting block to local relay", "block", msg.ExecutionPayload.BlockHash.String())
+func (r *LocalRelay) SubmitBlock(_ context.Context, msg *builderapi.VersionedSubmitBlockRequest, _ ValidatorData) error {
+	bidTrace, err := msg.BidTrace()
+	if err != nil {
+		return err
+	}
+	log.Info("submitting block to local relay", "version", msg.Version, "block", bidTrace.BlockHash.String())
 
-	return r.submitBlockCapella(msg)
+	return r.submitBlock(msg)
 }
 
 func (r *LocalRelay) Config() RelayConfig {
@@ -126,23 +155,18 @@ func (r *LocalRelay) Config() RelayConfig {
 	return RelayConfig{}
 }
 
-// TODO: local relay support for capella
-func (r *LocalRelay) submitBlockCapella(msg *capellaapi.SubmitBlockRequest) error {
-	return nil
-}
-
-func (r *LocalRelay) submitBlock(msg *bellatrixapi.SubmitBlockRequest) error {
-	header, err := PayloadToPayloadHeader(msg.ExecutionPayload)
-	if err != nil {
+func (r *LocalRelay) submitBlock(msg *builderapi.VersionedSubmitBlockRequest) error {
+	entry, err := NewCachedPayload(msg)
+	if errors.Is(err, builderapi.ErrUnsupportedVersion) {
+		return fmt.Errorf("%w: local relay does not serve %s blocks", builderapi.ErrUnsupportedVersion, msg.Version)
+	} else if err != nil {
 		log.Error("could not convert payload to header", "err", err)
 		return err
 	}
 
-	r.bestDataLock.Lock()
-	r.bestHeader = header
-	r.bestPayload = msg.ExecutionPayload
-	r.profit = msg.Message.Value
-	r.bestDataLock.Unlock()
+	if !r.payloadCache.Add(entry) {
+		return fmt.Errorf("slot %d is too old to be cached", entry.Slot)
+	}
 
 	return nil
 }
@@ -181,9 +205,19 @@ func (r *LocalRelay) handleRegisterValidator(w http.ResponseWriter, req *http.Re
 		}
 	}
 
-	for _, registerRequest := range payload {
-		pubkeyHex := PubkeyHex(registerRequest.Message.Pubkey.String())
-		if !r.beaconClient.isValidator(pubkeyHex) {
+	pubkeys := make([]PubkeyHex, len(payload))
+	for i, registerRequest := range payload {
+		pubkeys[i] = PubkeyHex(strings.ToLower(registerRequest.Message.Pubkey.String()))
+	}
+	// the registrations are deferred if the validators cannot be looked up, they are sent again every epoch
+	activeValidators, err := r.beaconClient.activeValidators(pubkeys)
+	if err != nil {
+		log.Error("could not look up registered validators", "err", err)
+		respondError(w, http.StatusServiceUnavailable, "validators are not available, retry later")
+		return
+	}
+	for _, pubkey := range pubkeys {
+		if _, ok := activeValidators[pubkey]; !ok {
 			respondError(w, http.StatusBadRequest, "not a validator")
 			return
 		}
@@ -245,7 +279,6 @@ func (r *LocalRelay) handleGetHeader(w http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	slot, err := strconv.Atoi(vars["slot"])
 	if err != nil {
-		println("handle get header 1111111")
 		respondError(w, http.StatusBadRequest, "incorrect slot")
 		return
 	}
@@ -262,68 +295,120 @@ func (r *LocalRelay) handleGetHeader(w http.ResponseWriter, req *http.Request) {
 	// Only check if slot is within a couple of the expected one, otherwise will force validators resync
 	vd, err := r.GetValidatorForSlot(uint64(slot))
 	if err != nil {
-		println("handle get header 222222")
 		respondError(w, http.StatusBadRequest, "unknown validator")
 		return
 	}
 	if vd.Pubkey != pubkeyHex {
-		println("handle get header 333333")
 		respondError(w, http.StatusBadRequest, "unknown validator")
 		return
 	}
 
-	r.bestDataLock.Lock()
-	bestHeader := r.bestHeader
-	profit := r.profit
-	r.bestDataLock.Unlock()
-	log.Info("best header is:", "bestheader", bestHeader)
+	parentHash := phase0.Hash32(common.HexToHash(parentHashHex))
+	best, found := r.payloadCache.Best(uint64(slot), parentHash)
 	log.Info("parentHashHex is : ", "parentHash", parentHashHex)
-	if bestHeader == nil || bestHeader.ParentHash.String() != parentHashHex {
-		println("handle get header 44444")
+	if !found {
 		respondError(w, http.StatusBadRequest, "unknown payload")
 		return
 	}
 
-	bid := bellatrixapi.BuilderBid{
-		Header: bestHeader,
-		Value:  profit,
-		Pubkey: r.relayPublicKey,
-	}
-	signature, err := ssz.SignMessage(&bid, r.builderSigningDomain, r.relaySecretKey)
+	response, err := r.signBuilderBid(best)
 	if err != nil {
-		println("handle get header 555555")
 		respondError(w, http.StatusInternalServerError, "internal server error")
 		return
 	}
 
-	response := &spec.VersionedSignedBuilderBid{
-		Version:   consensusspec.DataVersionBellatrix,
-		Bellatrix: &bellatrixapi.SignedBuilderBid{Message: &bid, Signature: signature},
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		println("handle get header 666666")
 		respondError(w, http.StatusInternalServerError, "internal server error")
 		return
 	}
 }
 
+// signBuilderBid signs the bid for the cached payload in the format matching its version
+func (r *LocalRelay) signBuilderBid(entry *CachedPayload) (*spec.VersionedSignedBuilderBid, error) {
+	log.Debug("signing builder bid", "version", entry.Payload.Version, "blockHash", entry.BlockHash(), "value", entry.Value)
+	switch entry.Payload.Version {
+	case consensusspec.DataVersionBellatrix:
+		bid := bellatrixapi.BuilderBid{
+			Header: entry.BellatrixHeader,
+			Value:  entry.Value,
+			Pubkey: r.relayPublicKey,
+		}
+		signature, err := ssz.SignMessage(&bid, r.builderSigningDomain, r.relaySecretKey)
+		if err != nil {
+			return nil, err
+		}
+		return &spec.VersionedSignedBuilderBid{
+			Version:   consensusspec.DataVersionBellatrix,
+			Bellatrix: &bellatrixapi.SignedBuilderBid{Message: &bid, Signature: signature},
+		}, nil
+	case consensusspec.DataVersionCapella:
+		bid := capellaapi.BuilderBid{
+			Header: entry.CapellaHeader,
+			Value:  entry.Value,
+			Pubkey: r.relayPublicKey,
+		}
+		signature, err := ssz.SignMessage(&bid, r.builderSigningDomain, r.relaySecretKey)
+		if err != nil {
+			return nil, err
+		}
+		return &spec.VersionedSignedBuilderBid{
+			Version: consensusspec.DataVersionCapella,
+			Capella: &capellaapi.SignedBuilderBid{Message: &bid, Signature: signature},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported payload version %s", entry.Payload.Version)
+	}
+}
+
 func (r *LocalRelay) handleGetPayload(w http.ResponseWriter, req *http.Request) {
-	payload := new(apiv1bellatrix.SignedBlindedBeaconBlock)
-	if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
-		log.Error("failed to decode payload", "error", err)
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		log.Error("failed to read payload", "error", err)
 		respondError(w, http.StatusBadRequest, "invalid payload")
 		return
 	}
 
-	if len(payload.Signature) != 96 {
+	var (
+		version      consensusspec.DataVersion
+		slot         phase0.Slot
+		message      ssz.ObjWithHashTreeRoot
+		signature    phase0.BLSSignature
+		blockHash    phase0.Hash32
+		headerEquals func(*CachedPayload) bool
+	)
+
+	// Capella blocks are tried first, bellatrix headers lack the withdrawals root and fail to decode as capella
+	capellaPayload := new(apiv1capella.SignedBlindedBeaconBlock)
+	if err := json.Unmarshal(body, capellaPayload); err == nil {
+		signedHeader := capellaPayload.Message.Body.ExecutionPayloadHeader
+		version = consensusspec.DataVersionCapella
+		slot, message, signature, blockHash = capellaPayload.Message.Slot, capellaPayload.Message, capellaPayload.Signature, signedHeader.BlockHash
+		headerEquals = func(cached *CachedPayload) bool {
+			return cached.CapellaHeader != nil && CapellaExecutionPayloadHeaderEqual(cached.CapellaHeader, signedHeader)
+		}
+	} else {
+		bellatrixPayload := new(apiv1bellatrix.SignedBlindedBeaconBlock)
+		if err := json.Unmarshal(body, bellatrixPayload); err != nil {
+			log.Error("failed to decode payload", "error", err)
+			respondError(w, http.StatusBadRequest, "invalid payload")
+			return
+		}
+		signedHeader := bellatrixPayload.Message.Body.ExecutionPayloadHeader
+		version = consensusspec.DataVersionBellatrix
+		slot, message, signature, blockHash = bellatrixPayload.Message.Slot, bellatrixPayload.Message, bellatrixPayload.Signature, signedHeader.BlockHash
+		headerEquals = func(cached *CachedPayload) bool {
+			return cached.BellatrixHeader != nil && ExecutionPayloadHeaderEqual(cached.BellatrixHeader, signedHeader)
+		}
+	}
+
+	if len(signature) != 96 {
 		respondError(w, http.StatusBadRequest, "invalid signature")
 		return
 	}
 
-	nextSlotProposerPubkeyHex, err := r.beaconClient.getProposerForNextSlot(uint64(payload.Message.Slot))
+	nextSlotProposerPubkeyHex, err := r.beaconClient.getProposerForNextSlot(uint64(slot))
 	if err != nil {
 		if r.enableBeaconChecks {
 			respondError(w, http.StatusBadRequest, "unknown validator")
@@ -339,39 +424,34 @@ func (r *LocalRelay) handleGetPayload(w http.ResponseWriter, req *http.Request)
 		}
 	}
 
-	ok, err := ssz.VerifySignature(payload.Message, r.proposerSigningDomain, nextSlotProposerPubkeyBytes[:], payload.Signature[:])
-	if !ok || err != nil {
+	// the blinded block is signed with the domain of the fork of its payload
+	proposerSigningDomain, found := r.proposerSigningDomains[version]
+	ok, err := ssz.VerifySignature(message, proposerSigningDomain, nextSlotProposerPubkeyBytes[:], signature[:])
+	if !found || !ok || err != nil {
 		if r.enableBeaconChecks {
 			respondError(w, http.StatusBadRequest, "invalid signature")
 			return
 		}
 	}
 
-	r.bestDataLock.Lock()
-	bestHeader := r.bestHeader
-	bestPayload := r.bestPayload
-	r.bestDataLock.Unlock()
-
-	log.Info("Received blinded block", "payload", payload, "bestHeader", bestHeader)
-
-	if bestHeader == nil || bestPayload == nil {
+	if r.payloadCache.Len() == 0 {
 		respondError(w, http.StatusInternalServerError, "no payloads")
 		return
 	}
 
-	if !ExecutionPayloadHeaderEqual(bestHeader, payload.Message.Body.ExecutionPayloadHeader) {
+	// Serve the payload matching the exact header that was signed, even if it was replaced by a later submission
+	cached, found := r.payloadCache.Get(blockHash)
+
+	log.Info("Received blinded block", "slot", slot, "blockHash", blockHash, "found", found)
+
+	if !found || !headerEquals(cached) {
 		respondError(w, http.StatusBadRequest, "unknown payload")
 		return
 	}
 
-	response := &api.VersionedExecutionPayload{
-		Version:   consensusspec.DataVersionBellatrix,
-		Bellatrix: bestPayload,
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	if err := json.NewEncoder(w).Encode(cached.Payload); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		respondError(w, http.StatusInternalServerError, "internal server error")
 		return
@@ -388,10 +468,17 @@ func (r *LocalRelay) handleIndex(w http.ResponseWriter, req *http.Request) {
 	r.validatorsLock.RUnlock()
 	validatorsStats := fmt.Sprint(noValidators) + " validators registered"
 
-	r.bestDataLock.Lock()
-	header := r.bestHeader
-	payload := r.bestPayload
-	r.bestDataLock.Lock()
+	var (
+		header  any
+		payload any
+	)
+	if latest := r.payloadCache.Latest(); latest != nil {
+		if latest.CapellaHeader != nil {
+			header, payload = latest.CapellaHeader, latest.Payload.Capella
+		} else {
+			header, payload = latest.BellatrixHeader, latest.Payload.Bellatrix
+		}
+	}
 
 	headerData, err := json.MarshalIndent(header, "", "  ")
 	if err != nil {
@@ -408,18 +495,32 @@ func (r *LocalRelay) handleIndex(w http.ResponseWriter, req *http.Request) {
 		ValidatorsStats       string
 		GenesisForkVersion    string
 		BellatrixForkVersion  string
+		CapellaForkVersion    string
 		GenesisValidatorsRoot string
 		BuilderSigningDomain  string
-		ProposerSigningDomain string
+		BellatrixDomain       string
+		CapellaDomain         string
 		Header                string
 		Blocks                string
-	}{hexutil.Encode(r.serializedRelayPubkey), validatorsStats, r.fd.GenesisForkVersion, r.fd.BellatrixForkVersion, r.fd.GenesisValidatorsRoot, hexutil.Encode(r.builderSigningDomain[:]), hexutil.Encode(r.proposerSigningDomain[:]), string(headerData), string(payloadData)}
+	}{
+		hexutil.Encode(r.serializedRelayPubkey), validatorsStats, r.fd.GenesisForkVersion, r.fd.BellatrixForkVersion, r.fd.CapellaForkVersion,
+		r.fd.GenesisValidatorsRoot, hexutil.Encode(r.builderSigningDomain[:]), r.proposerSigningDomainHex(consensusspec.DataVersionBellatrix),
+		r.proposerSigningDomainHex(consensusspec.DataVersionCapella), string(headerData), string(payloadData),
+	}
 
 	if err := r.indexTemplate.Execute(w, statusData); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
+func (r *LocalRelay) proposerSigningDomainHex(version consensusspec.DataVersion) string {
+	domain, found := r.proposerSigningDomains[version]
+	if !found {
+		return ""
+	}
+	return hexutil.Encode(domain[:])
+}
+
 type httpErrorResp struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
@@ -441,6 +542,10 @@ func ExecutionPayloadHeaderEqual(l, r *bellatrix.ExecutionPayloadHeader) bool {
 	return l.ParentHash == r.ParentHash && l.FeeRecipient == r.FeeRecipient && l.StateRoot == r.StateRoot && l.ReceiptsRoot == r.ReceiptsRoot && l.LogsBloom == r.LogsBloom && l.PrevRandao == r.PrevRandao && l.BlockNumber == r.BlockNumber && l.GasLimit == r.GasLimit && l.GasUsed == r.GasUsed && l.Timestamp == r.Timestamp && l.BaseFeePerGas == r.BaseFeePerGas && bytes.Equal(l.ExtraData, r.ExtraData) && l.BlockHash == r.BlockHash && l.TransactionsRoot == r.TransactionsRoot
 }
 
+func CapellaExecutionPayloadHeaderEqual(l, r *capella.ExecutionPayloadHeader) bool {
+	return l.ParentHash == r.ParentHash && l.FeeRecipient == r.FeeRecipient && l.StateRoot == r.StateRoot && l.ReceiptsRoot == r.ReceiptsRoot && l.LogsBloom == r.LogsBloom && l.PrevRandao == r.PrevRandao && l.BlockNumber == r.BlockNumber && l.GasLimit == r.GasLimit && l.GasUsed == r.GasUsed && l.Timestamp == r.Timestamp && l.BaseFeePerGas == r.BaseFeePerGas && bytes.Equal(l.ExtraData, r.ExtraData) && l.BlockHash == r.BlockHash && l.TransactionsRoot == r.TransactionsRoot && l.WithdrawalsRoot == r.WithdrawalsRoot
+}
+
 // PayloadToPayloadHeader converts an ExecutionPayload to ExecutionPayloadHeader
 func PayloadToPayloadHeader(p *bellatrix.ExecutionPayload) (*bellatrix.ExecutionPayloadHeader, error) {
 	if p == nil {
@@ -476,3 +581,40 @@ func PayloadToPayloadHeader(p *bellatrix.ExecutionPayload) (*bellatrix.Execution
 		TransactionsRoot: txroot,
 	}, nil
 }
+
+// CapellaPayloadToPayloadHeader converts a capella ExecutionPayload to ExecutionPayloadHeader
+func CapellaPayloadToPayloadHeader(p *capella.ExecutionPayload) (*capella.ExecutionPayloadHeader, error) {
+	if p == nil {
+		return nil, errors.New("nil payload")
+	}
+
+	transactions := bellatrixutil.ExecutionPayloadTransactions{Transactions: p.Transactions}
+	txroot, err := transactions.HashTreeRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	withdrawals := capellautil.ExecutionPayloadWithdrawals{Withdrawals: p.Withdrawals}
+	withdrawalsRoot, err := withdrawals.HashTreeRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	return &capella.ExecutionPayloadHeader{
+		ParentHash:       p.ParentHash,
+		FeeRecipient:     p.FeeRecipient,
+		StateRoot:        p.StateRoot,
+		ReceiptsRoot:     p.ReceiptsRoot,
+		LogsBloom:        p.LogsBloom,
+		PrevRandao:       p.PrevRandao,
+		BlockNumber:      p.BlockNumber,
+		GasLimit:         p.GasLimit,
+		GasUsed:          p.GasUsed,
+		Timestamp:        p.Timestamp,
+		ExtraData:        p.ExtraData,
+		BaseFeePerGas:    p.BaseFeePerGas,
+		BlockHash:        p.BlockHash,
+		TransactionsRoot: txroot,
+		WithdrawalsRoot:  withdrawalsRoot,
+	}, nil
+}
@@ -2,6 +2,7 @@ package builder
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"math/big"
@@ -14,13 +15,18 @@ import (
 
 	"github.com/attestantio/go-builder-client/api"
 	bellatrixapi "github.com/attestantio/go-builder-client/api/bellatrix"
+	capellaapi "github.com/attestantio/go-builder-client/api/capella"
 	apiv1 "github.com/attestantio/go-builder-client/api/v1"
 	"github.com/attestantio/go-builder-client/spec"
 	consensusapiv1bellatrix "github.com/attestantio/go-eth2-client/api/v1/bellatrix"
+	consensusapiv1capella "github.com/attestantio/go-eth2-client/api/v1/capella"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
 	"github.com/attestantio/go-eth2-client/spec/altair"
 	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/attestantio/go-eth2-client/spec/capella"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/ethereum/go-ethereum/beacon/engine"
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/flashbotsextra"
@@ -36,14 +42,26 @@ const (
 	testLocalRelayValidatorGasLimit = 15_000_000
 )
 
+var testForkData = ForkData{
+	GenesisForkVersion:    "0x00000000",
+	BellatrixForkVersion:  "0x02000000",
+	CapellaForkVersion:    "0x03000000",
+	GenesisValidatorsRoot: "0x0000000000000000000000000000000000000000000000000000000000000000",
+}
+
+// testProposerSigningDomain computes the domain a proposer signs its blinded blocks with for the given fork version
+func testProposerSigningDomain(forkVersion byte) phase0.Domain {
+	return ssz.ComputeDomain(ssz.DomainTypeBeaconProposer, [4]byte{forkVersion, 0x0, 0x0, 0x0}, phase0.Root{})
+}
+
 func newTestBackend(t *testing.T, forkchoiceData *engine.ExecutableData, block *types.Block, blockValue *big.Int) (*Builder, *LocalRelay, *ValidatorPrivateData) {
 	validator := NewRandomValidator()
 	sk, _ := bls.GenerateRandomSecretKey()
 	bDomain := ssz.ComputeDomain(ssz.DomainTypeAppBuilder, [4]byte{0x02, 0x0, 0x0, 0x0}, phase0.Root{})
-	genesisValidatorsRoot := phase0.Root(common.HexToHash("0x0000000000000000000000000000000000000000000000000000000000000000"))
-	cDomain := ssz.ComputeDomain(ssz.DomainTypeBeaconProposer, [4]byte{0x02, 0x0, 0x0, 0x0}, genesisValidatorsRoot)
+	proposerDomains, err := NewProposerSigningDomains(testForkData)
+	require.NoError(t, err)
 	beaconClient := &testBeaconClient{validator: validator}
-	localRelay, _ := NewLocalRelay(sk, beaconClient, bDomain, cDomain, ForkData{}, true)
+	localRelay, _ := NewLocalRelay(sk, beaconClient, bDomain, proposerDomains, testForkData, true)
 	ethService := &testEthereumService{synced: true, testExecutableData: forkchoiceData, testBlock: block, testBlockValue: blockValue}
 	builderArgs := BuilderArgs{
 		sk:                          sk,
@@ -56,6 +74,7 @@ func newTestBackend(t *testing.T, forkchoiceData *engine.ExecutableData, block *
 		validator:                   nil,
 		beaconClient:                beaconClient,
 		limiter:                     nil,
+		clock:                       newSlotClock(12),
 	}
 	backend, _ := NewBuilder(builderArgs)
 
@@ -98,6 +117,16 @@ func TestValidatorRegistration(t *testing.T) {
 	rr = testRequest(t, relay, "POST", "/eth/v1/builder/validators", payload)
 	require.Equal(t, http.StatusOK, rr.Code)
 
+	// registrations are deferred while the validators cannot be looked up
+	relay.beaconClient = NewBeaconClient("http://127.0.0.1:0", 32, 12, true)
+	rr = testRequest(t, relay, "POST", "/eth/v1/builder/validators", payload)
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	relay.beaconClient = &NilBeaconClient{}
+	rr = testRequest(t, relay, "POST", "/eth/v1/builder/validators", payload)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, `{"code":400,"message":"not a validator"}`+"\n", rr.Body.String())
+
 	payload[0].Message.Timestamp = payload[0].Message.Timestamp.Add(time.Second)
 	// Invalid signature
 	payload[0].Signature[len(payload[0].Signature)-1] = 0x00
@@ -171,7 +200,7 @@ func TestGetHeader(t *testing.T) {
 	require.Equal(t, ``, rr.Body.String())
 	require.Equal(t, 204, rr.Code)
 
-	attrs := &types.BuilderPayloadAttributes{}
+	attrs := &types.BuilderPayloadAttributes{Slot: 1, Timestamp: 12, Random: common.Hash{0x01}}
 	err = backend.OnPayloadAttribute(attrs)
 	require.NoError(t, err)
 
@@ -180,7 +209,7 @@ func TestGetHeader(t *testing.T) {
 
 	time.Sleep(2 * time.Second)
 
-	path = fmt.Sprintf("/eth/v1/builder/header/%d/%s/%s", 0, forkchoiceData.ParentHash.Hex(), validator.Pk.String())
+	path = fmt.Sprintf("/eth/v1/builder/header/%d/%s/%s", 1, forkchoiceData.ParentHash.Hex(), validator.Pk.String())
 	rr = testRequest(t, relay, "GET", path, nil)
 	require.Equal(t, http.StatusOK, rr.Code)
 
@@ -224,11 +253,11 @@ func TestGetPayload(t *testing.T) {
 	backend, relay, validator := newTestBackend(t, forkchoiceData, forkchoiceBlock, forkchoiceBlockProfit)
 
 	registerValidator(t, validator, relay)
-	err = backend.OnPayloadAttribute(&types.BuilderPayloadAttributes{})
+	err = backend.OnPayloadAttribute(&types.BuilderPayloadAttributes{Slot: 1, Timestamp: 12, Random: common.Hash{0x01}})
 	require.NoError(t, err)
 	time.Sleep(2 * time.Second)
 
-	path := fmt.Sprintf("/eth/v1/builder/header/%d/%s/%s", 0, forkchoiceData.ParentHash.Hex(), validator.Pk.String())
+	path := fmt.Sprintf("/eth/v1/builder/header/%d/%s/%s", 1, forkchoiceData.ParentHash.Hex(), validator.Pk.String())
 	rr := testRequest(t, relay, "GET", path, nil)
 	require.Equal(t, http.StatusOK, rr.Code)
 
@@ -264,10 +293,19 @@ func TestGetPayload(t *testing.T) {
 		},
 	}
 
-	// TODO: test wrong signing domain
-	signature, err := validator.Sign(msg, relay.proposerSigningDomain)
+	signature, err := validator.Sign(msg, testProposerSigningDomain(0x02))
 	require.NoError(t, err)
 
+	// Call getPayload signed with the domain of another fork
+	capellaSignature, err := validator.Sign(msg, testProposerSigningDomain(0x03))
+	require.NoError(t, err)
+	rr = testRequest(t, relay, "POST", "/eth/v1/builder/blinded_blocks", &consensusapiv1bellatrix.SignedBlindedBeaconBlock{
+		Message:   msg,
+		Signature: capellaSignature,
+	})
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, `{"code":400,"message":"invalid signature"}`+"\n", rr.Body.String())
+
 	// Call getPayload with invalid signature
 	rr = testRequest(t, relay, "POST", "/eth/v1/builder/blinded_blocks", &consensusapiv1bellatrix.SignedBlindedBeaconBlock{
 		Message:   msg,
@@ -289,3 +327,105 @@ func TestGetPayload(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, bid.Bellatrix.Message.Header.BlockHash, getPayloadResponse.Bellatrix.BlockHash)
 }
+
+func TestGetHeaderGetPayloadCapella(t *testing.T) {
+	executableData := &engine.ExecutableData{
+		ParentHash:    common.HexToHash("0xafafafa"),
+		FeeRecipient:  common.Address{0x01},
+		LogsBloom:     types.Bloom{}.Bytes(),
+		BlockHash:     common.HexToHash("0xc4a012b67027b3ab6c00acd31aeee24aa1515d6a5d7e81b0ee2e69517fdc387f"),
+		BaseFeePerGas: big.NewInt(12),
+		ExtraData:     []byte{},
+		Withdrawals:   []*types.Withdrawal{{Index: 1, Validator: 2, Address: common.Address{0x03}, Amount: 4}},
+	}
+
+	_, relay, validator := newTestBackend(t, nil, nil, nil)
+	registerValidator(t, validator, relay)
+
+	executionPayload, err := executableDataToCapellaExecutionPayload(executableData)
+	require.NoError(t, err)
+	value := uint256.NewInt(10)
+	err = relay.SubmitBlock(context.Background(), &builderapi.VersionedSubmitBlockRequest{
+		Version: consensusspec.DataVersionCapella,
+		Capella: &capellaapi.SubmitBlockRequest{
+			Message:          &apiv1.BidTrace{Slot: 1, ParentHash: executionPayload.ParentHash, BlockHash: executionPayload.BlockHash, Value: value},
+			ExecutionPayload: executionPayload,
+		},
+	}, ValidatorData{})
+	require.NoError(t, err)
+
+	path := fmt.Sprintf("/eth/v1/builder/header/%d/%s/%s", 1, executableData.ParentHash.Hex(), validator.Pk.String())
+	rr := testRequest(t, relay, "GET", path, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	bid := new(spec.VersionedSignedBuilderBid)
+	err = json.Unmarshal(rr.Body.Bytes(), bid)
+	require.NoError(t, err)
+	require.Equal(t, consensusspec.DataVersionCapella, bid.Version)
+
+	expectedHeader, err := CapellaPayloadToPayloadHeader(executionPayload)
+	require.NoError(t, err)
+	require.EqualValues(t, &capellaapi.BuilderBid{
+		Header: expectedHeader,
+		Value:  value,
+		Pubkey: relay.relayPublicKey,
+	}, bid.Capella.Message)
+	require.NotEqual(t, phase0.Root{}, bid.Capella.Message.Header.WithdrawalsRoot)
+
+	ok, err := ssz.VerifySignature(bid.Capella.Message, relay.builderSigningDomain, relay.relayPublicKey[:], bid.Capella.Signature[:])
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	blockHash := [32]byte{0x06}
+	syncCommitteeBits := [64]byte{0x07}
+	msg := &consensusapiv1capella.BlindedBeaconBlock{
+		Slot:          1,
+		ProposerIndex: 2,
+		ParentRoot:    phase0.Root{0x03},
+		StateRoot:     phase0.Root{0x04},
+		Body: &consensusapiv1capella.BlindedBeaconBlockBody{
+			ETH1Data: &phase0.ETH1Data{
+				DepositRoot:  phase0.Root{0x05},
+				DepositCount: 5,
+				BlockHash:    blockHash[:],
+			},
+			ProposerSlashings: []*phase0.ProposerSlashing{},
+			AttesterSlashings: []*phase0.AttesterSlashing{},
+			Attestations:      []*phase0.Attestation{},
+			Deposits:          []*phase0.Deposit{},
+			VoluntaryExits:    []*phase0.SignedVoluntaryExit{},
+			SyncAggregate: &altair.SyncAggregate{
+				SyncCommitteeBits:      syncCommitteeBits[:],
+				SyncCommitteeSignature: phase0.BLSSignature{0x08},
+			},
+			ExecutionPayloadHeader: bid.Capella.Message.Header,
+			BLSToExecutionChanges:  []*capella.SignedBLSToExecutionChange{},
+		},
+	}
+
+	// capella blinded blocks signed with the bellatrix domain are rejected
+	bellatrixSignature, err := validator.Sign(msg, testProposerSigningDomain(0x02))
+	require.NoError(t, err)
+	rr = testRequest(t, relay, "POST", "/eth/v1/builder/blinded_blocks", &consensusapiv1capella.SignedBlindedBeaconBlock{
+		Message:   msg,
+		Signature: bellatrixSignature,
+	})
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, `{"code":400,"message":"invalid signature"}`+"\n", rr.Body.String())
+
+	signature, err := validator.Sign(msg, testProposerSigningDomain(0x03))
+	require.NoError(t, err)
+
+	rr = testRequest(t, relay, "POST", "/eth/v1/builder/blinded_blocks", &consensusapiv1capella.SignedBlindedBeaconBlock{
+		Message:   msg,
+		Signature: signature,
+	})
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	getPayloadResponse := new(api.VersionedExecutionPayload)
+	err = json.Unmarshal(rr.Body.Bytes(), getPayloadResponse)
+	require.NoError(t, err)
+	require.Equal(t, consensusspec.DataVersionCapella, getPayloadResponse.Version)
+	require.Equal(t, executionPayload.BlockHash, getPayloadResponse.Capella.BlockHash)
+	require.Equal(t, executionPayload.Withdrawals, getPayloadResponse.Capella.Withdrawals)
+}
@@ -0,0 +1,145 @@
+package builder
+
+import (
+	"errors"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// relayMetricsName returns the relay or beacon node host to be used in metric names, credentials and paths of the
+// endpoint are dropped
+func relayMetricsName(endpoint string) string {
+	if endpoint == "" {
+		return "local"
+	}
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		endpoint = u.Host
+	}
+	return strings.NewReplacer("/", "_", ":", "_", ".", "_").Replace(endpoint)
+}
+
+// markInvalidValidatorRegistration meters a registration rejected by verifyValidatorRegistration by the reason of rejection
+func markInvalidValidatorRegistration(endpoint string, err error) {
+	if !metrics.EnabledBuilder {
+		return
+	}
+
+	reason := "malformed"
+	switch {
+	case errors.Is(err, errRegistrationInvalidSignature):
+		reason = "signature"
+	case errors.Is(err, errRegistrationNotProposer):
+		reason = "proposer"
+	}
+	metrics.GetOrRegisterMeter("builder/relay/"+relayMetricsName(endpoint)+"/validators/invalid/"+reason, nil).Mark(1)
+}
+
+// markValidatorsStaleness updates the age of the registrations fetched from the relay and how many slots behind
+// the looked up slot they were fetched
+func markValidatorsStaleness(endpoint string, updatedAt time.Time, lastRequestedSlot, slot uint64, found bool) {
+	if !metrics.EnabledBuilder {
+		return
+	}
+
+	prefix := "builder/relay/" + relayMetricsName(endpoint) + "/validators"
+	if !updatedAt.IsZero() {
+		metrics.GetOrRegisterGauge(prefix+"/age", nil).Update(time.Since(updatedAt).Milliseconds())
+	}
+	if slot >= lastRequestedSlot {
+		metrics.GetOrRegisterGauge(prefix+"/slots_behind", nil).Update(int64(slot - lastRequestedSlot))
+	}
+	if !found {
+		metrics.GetOrRegisterMeter(prefix+"/miss", nil).Mark(1)
+	}
+}
+
+func markBeaconNodeEvent(endpoint string) {
+	if metrics.EnabledBuilder {
+		metrics.GetOrRegisterMeter("builder/beacon/"+relayMetricsName(endpoint)+"/events", nil).Mark(1)
+	}
+}
+
+func markBeaconNodeConflict(endpoint string) {
+	if metrics.EnabledBuilder {
+		metrics.GetOrRegisterMeter("builder/beacon/"+relayMetricsName(endpoint)+"/conflicts", nil).Mark(1)
+	}
+}
+
+func markBeaconNodeLag(endpoint string, lag uint64) {
+	if metrics.EnabledBuilder {
+		metrics.GetOrRegisterGauge("builder/beacon/"+relayMetricsName(endpoint)+"/slot_lag", nil).Update(int64(lag))
+	}
+}
+
+func markBeaconDuplicateEvent() {
+	if metrics.EnabledBuilder {
+		metrics.GetOrRegisterMeter("builder/beacon/duplicates", nil).Mark(1)
+	}
+}
+
+// markRejectedPayloadAttributes meters payload attributes rejected by the sanity checks by the reason of rejection
+func markRejectedPayloadAttributes(err error) {
+	if !metrics.EnabledBuilder {
+		return
+	}
+
+	reason := "other"
+	switch {
+	case errors.Is(err, errPayloadAttrsTimestamp):
+		reason = "timestamp"
+	case errors.Is(err, errPayloadAttrsWithdrawals):
+		reason = "withdrawals"
+	case errors.Is(err, errPayloadAttrsPrevRandao):
+		reason = "prev_randao"
+	case errors.Is(err, errPayloadAttrsProposer):
+		reason = "proposer"
+	case errors.Is(err, errPayloadAttrsLate):
+		reason = "late"
+	}
+	metrics.GetOrRegisterMeter("builder/payload_attributes/rejected/"+reason, nil).Mark(1)
+}
+
+func markRelaySubmission(result builderapi.RelaySubmissionResult) {
+	if !metrics.EnabledBuilder {
+		return
+	}
+
+	prefix := "builder/relay/" + relayMetricsName(result.Relay) + "/submit"
+	if errors.Is(result.Err, errRelaySubmissionSuperseded) {
+		metrics.GetOrRegisterMeter(prefix+"/superseded", nil).Mark(1)
+		return
+	}
+
+	metrics.GetOrRegisterTimer(prefix+"/latency", nil).Update(result.Latency)
+	if result.Err != nil {
+		metrics.GetOrRegisterMeter(prefix+"/failure", nil).Mark(1)
+	} else {
+		metrics.GetOrRegisterMeter(prefix+"/success", nil).Mark(1)
+	}
+}
+
+func markObservedBids(endpoint string, count int) {
+	if metrics.EnabledBuilder {
+		metrics.GetOrRegisterMeter("builder/relay/"+relayMetricsName(endpoint)+"/bids/observed", nil).Mark(int64(count))
+	}
+}
+
+func markBidPollFailure(endpoint string) {
+	if metrics.EnabledBuilder {
+		metrics.GetOrRegisterMeter("builder/relay/"+relayMetricsName(endpoint)+"/bids/poll_failure", nil).Mark(1)
+	}
+}
+
+// markTopBid updates the value of the top competing bid of the current slot in gwei
+func markTopBid(value *big.Int) {
+	if metrics.EnabledBuilder {
+		gwei := new(big.Int).Div(value, big.NewInt(params.GWei))
+		metrics.GetOrRegisterGauge("builder/bids/top_gwei", nil).Update(gwei.Int64())
+	}
+}
@@ -0,0 +1,55 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Reasons for rejecting payload attributes announced by the beacon nodes
+var (
+	errPayloadAttrsTimestamp   = errors.New("timestamp does not follow the parent block")
+	errPayloadAttrsWithdrawals = errors.New("malformed withdrawals")
+	errPayloadAttrsPrevRandao  = errors.New("zero prev_randao")
+	errPayloadAttrsProposer    = errors.New("proposer index does not match the proposer duties")
+	errPayloadAttrsLate        = errors.New("payload attributes arrived too late")
+)
+
+// validatePayloadAttributes checks the payload attributes against the parent block they build on:
+// the timestamp must be a whole number of slots after the parent and, if the genesis time is known (non zero), the
+// start of the slot, withdrawals must continue the indexes of the parent's withdrawals and prev_randao must be set
+func validatePayloadAttributes(attrs *types.BuilderPayloadAttributes, parent *types.Block, genesisTime, secondsInSlot uint64) error {
+	timestamp := uint64(attrs.Timestamp)
+	if timestamp <= parent.Time() {
+		return fmt.Errorf("%w: timestamp %d, parent timestamp %d", errPayloadAttrsTimestamp, timestamp, parent.Time())
+	}
+	if elapsed := timestamp - parent.Time(); elapsed%secondsInSlot != 0 || elapsed/secondsInSlot > attrs.Slot {
+		return fmt.Errorf("%w: timestamp %d, parent timestamp %d, slot %d", errPayloadAttrsTimestamp, timestamp, parent.Time(), attrs.Slot)
+	}
+	if genesisTime != 0 && timestamp != genesisTime+attrs.Slot*secondsInSlot {
+		return fmt.Errorf("%w: timestamp %d, slot %d starts at %d", errPayloadAttrsTimestamp, timestamp, attrs.Slot, genesisTime+attrs.Slot*secondsInSlot)
+	}
+
+	if attrs.Random == (common.Hash{}) {
+		return errPayloadAttrsPrevRandao
+	}
+
+	for i, w := range attrs.Withdrawals {
+		if w == nil {
+			return fmt.Errorf("%w: withdrawal %d is missing", errPayloadAttrsWithdrawals, i)
+		}
+		if i > 0 && w.Index != attrs.Withdrawals[i-1].Index+1 {
+			return fmt.Errorf("%w: withdrawal index %d follows %d", errPayloadAttrsWithdrawals, w.Index, attrs.Withdrawals[i-1].Index)
+		}
+	}
+	// an empty withdrawals list does not advance the index, the indexes can only be checked against a parent with withdrawals
+	if parentWithdrawals := parent.Withdrawals(); len(parentWithdrawals) > 0 && len(attrs.Withdrawals) > 0 {
+		last := parentWithdrawals[len(parentWithdrawals)-1].Index
+		if first := attrs.Withdrawals[0].Index; first != last+1 {
+			return fmt.Errorf("%w: first withdrawal index %d, parent's last withdrawal index %d", errPayloadAttrsWithdrawals, first, last)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package builder
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePayloadAttributes(t *testing.T) {
+	const genesisTime = 1000
+	parent := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10), Time: 1120}).WithWithdrawals([]*types.Withdrawal{{Index: 4}, {Index: 5}})
+	validAttrs := func() *types.BuilderPayloadAttributes {
+		return &types.BuilderPayloadAttributes{
+			Slot:        11,
+			Timestamp:   hexutil.Uint64(1132),
+			Random:      common.Hash{0x01},
+			Withdrawals: types.Withdrawals{{Index: 6}, {Index: 7}},
+		}
+	}
+
+	require.NoError(t, validatePayloadAttributes(validAttrs(), parent, 0, 12))
+
+	// missed slots
+	attrs := validAttrs()
+	attrs.Slot = 12
+	attrs.Timestamp = 1144
+	require.NoError(t, validatePayloadAttributes(attrs, parent, 0, 12))
+
+	// empty withdrawals
+	attrs = validAttrs()
+	attrs.Withdrawals = nil
+	require.NoError(t, validatePayloadAttributes(attrs, parent, 0, 12))
+
+	for _, timestamp := range []hexutil.Uint64{1120, 1110, 1133, 1120 + 12*12} {
+		attrs = validAttrs()
+		attrs.Timestamp = timestamp
+		require.ErrorIs(t, validatePayloadAttributes(attrs, parent, 0, 12), errPayloadAttrsTimestamp)
+	}
+
+	// the timestamp must be the start of the slot if the genesis time is known, not a slot later
+	require.NoError(t, validatePayloadAttributes(validAttrs(), parent, genesisTime, 12))
+	attrs = validAttrs()
+	attrs.Timestamp = 1144
+	require.ErrorIs(t, validatePayloadAttributes(attrs, parent, genesisTime, 12), errPayloadAttrsTimestamp)
+	attrs.Slot = 12
+	require.NoError(t, validatePayloadAttributes(attrs, parent, genesisTime, 12))
+
+	attrs = validAttrs()
+	attrs.Random = common.Hash{}
+	require.ErrorIs(t, validatePayloadAttributes(attrs, parent, 0, 12), errPayloadAttrsPrevRandao)
+
+	for _, withdrawals := range []types.Withdrawals{{{Index: 6}, nil}, {{Index: 6}, {Index: 8}}, {{Index: 7}, {Index: 6}}, {{Index: 5}}} {
+		attrs = validAttrs()
+		attrs.Withdrawals = withdrawals
+		require.ErrorIs(t, validatePayloadAttributes(attrs, parent, 0, 12), errPayloadAttrsWithdrawals)
+	}
+
+	// withdrawal indexes can not be checked against a parent without withdrawals
+	attrs = validAttrs()
+	attrs.Withdrawals = types.Withdrawals{{Index: 100}}
+	require.NoError(t, validatePayloadAttributes(attrs, types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10), Time: 1120}), 0, 12))
+}
@@ -0,0 +1,184 @@
+package builder
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/attestantio/go-builder-client/api"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
+	"github.com/holiman/uint256"
+)
+
+const PayloadCacheSlotsDefault = 32
+
+// CachedPayload is a single submitted payload together with the header and the value it was bid with.
+// Only the header matching the payload version is set.
+type CachedPayload struct {
+	Slot            uint64
+	Value           *uint256.Int
+	Payload         *api.VersionedExecutionPayload
+	BellatrixHeader *bellatrix.ExecutionPayloadHeader
+	CapellaHeader   *capella.ExecutionPayloadHeader
+}
+
+// NewCachedPayload creates the cache entry of a block submission
+func NewCachedPayload(msg *builderapi.VersionedSubmitBlockRequest) (*CachedPayload, error) {
+	switch msg.Version {
+	case consensusspec.DataVersionBellatrix:
+		header, err := PayloadToPayloadHeader(msg.Bellatrix.ExecutionPayload)
+		if err != nil {
+			return nil, err
+		}
+		return &CachedPayload{
+			Slot:            msg.Bellatrix.Message.Slot,
+			Value:           msg.Bellatrix.Message.Value,
+			Payload:         &api.VersionedExecutionPayload{Version: consensusspec.DataVersionBellatrix, Bellatrix: msg.Bellatrix.ExecutionPayload},
+			BellatrixHeader: header,
+		}, nil
+	case consensusspec.DataVersionCapella:
+		header, err := CapellaPayloadToPayloadHeader(msg.Capella.ExecutionPayload)
+		if err != nil {
+			return nil, err
+		}
+		return &CachedPayload{
+			Slot:          msg.Capella.Message.Slot,
+			Value:         msg.Capella.Message.Value,
+			Payload:       &api.VersionedExecutionPayload{Version: consensusspec.DataVersionCapella, Capella: msg.Capella.ExecutionPayload},
+			CapellaHeader: header,
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s payloads are not cached", builderapi.ErrUnsupportedVersion, msg.Version)
+	}
+}
+
+func (p *CachedPayload) ParentHash() phase0.Hash32 {
+	if p.Payload.Version == consensusspec.DataVersionCapella {
+		return p.CapellaHeader.ParentHash
+	}
+	return p.BellatrixHeader.ParentHash
+}
+
+func (p *CachedPayload) BlockHash() phase0.Hash32 {
+	if p.Payload.Version == consensusspec.DataVersionCapella {
+		return p.CapellaHeader.BlockHash
+	}
+	return p.BellatrixHeader.BlockHash
+}
+
+type payloadCacheKey struct {
+	slot       uint64
+	parentHash phase0.Hash32
+}
+
+// PayloadCache keeps every payload submitted for the most recent slots, indexed by slot and parent hash
+// for serving headers and by block hash for unblinding. Payloads older than maxSlots behind the highest
+// seen slot are evicted.
+type PayloadCache struct {
+	mu sync.RWMutex
+
+	maxSlots    uint64
+	highestSlot uint64
+
+	latest     *CachedPayload
+	best       map[payloadCacheKey]*CachedPayload
+	byHash     map[phase0.Hash32]*CachedPayload
+	slotHashes map[uint64][]phase0.Hash32
+}
+
+func NewPayloadCache(maxSlots uint64) *PayloadCache {
+	if maxSlots == 0 {
+		maxSlots = PayloadCacheSlotsDefault
+	}
+	return &PayloadCache{
+		maxSlots:   maxSlots,
+		best:       make(map[payloadCacheKey]*CachedPayload),
+		byHash:     make(map[phase0.Hash32]*CachedPayload),
+		slotHashes: make(map[uint64][]phase0.Hash32),
+	}
+}
+
+// Add stores the payload and makes it the one served for its slot and parent hash unless a more valuable
+// payload was added for them. Payloads for slots that already fell out of the window are ignored.
+func (c *PayloadCache) Add(entry *CachedPayload) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry.Slot+c.maxSlots <= c.highestSlot {
+		return false
+	}
+
+	blockHash := entry.BlockHash()
+	if _, found := c.byHash[blockHash]; !found {
+		c.slotHashes[entry.Slot] = append(c.slotHashes[entry.Slot], blockHash)
+	}
+	c.byHash[blockHash] = entry
+	key := payloadCacheKey{entry.Slot, entry.ParentHash()}
+	if best, found := c.best[key]; !found || entry.Value.Cmp(best.Value) >= 0 {
+		c.best[key] = entry
+	}
+	c.latest = entry
+
+	if entry.Slot > c.highestSlot {
+		c.highestSlot = entry.Slot
+		c.evict()
+	}
+	return true
+}
+
+// evict removes all slots outside of the window, must be called with the lock held
+func (c *PayloadCache) evict() {
+	for slot, hashes := range c.slotHashes {
+		if slot+c.maxSlots > c.highestSlot {
+			continue
+		}
+		for _, hash := range hashes {
+			if entry, found := c.byHash[hash]; found {
+				key := payloadCacheKey{slot, entry.ParentHash()}
+				if c.best[key] == entry {
+					delete(c.best, key)
+				}
+				delete(c.byHash, hash)
+			}
+		}
+		delete(c.slotHashes, slot)
+	}
+}
+
+// Best returns the most valuable payload submitted for the given slot and parent hash, the most recent one
+// of equally valuable payloads
+func (c *PayloadCache) Best(slot uint64, parentHash phase0.Hash32) (*CachedPayload, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, found := c.best[payloadCacheKey{slot, parentHash}]
+	return entry, found
+}
+
+// Get returns the payload with the given block hash if it is still cached
+func (c *PayloadCache) Get(blockHash phase0.Hash32) (*CachedPayload, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, found := c.byHash[blockHash]
+	return entry, found
+}
+
+// Latest returns the last added payload regardless of slot, nil if nothing was added
+func (c *PayloadCache) Latest() *CachedPayload {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.latest
+}
+
+// Len returns the number of cached payloads
+func (c *PayloadCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.byHash)
+}
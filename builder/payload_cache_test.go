@@ -0,0 +1,93 @@
+package builder
+
+import (
+	"testing"
+
+	"github.com/attestantio/go-builder-client/api"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func testCachedPayload(slot uint64, parentHash, blockHash phase0.Hash32, value uint64) *CachedPayload {
+	return &CachedPayload{
+		Slot:            slot,
+		Value:           uint256.NewInt(value),
+		Payload:         &api.VersionedExecutionPayload{Version: consensusspec.DataVersionBellatrix, Bellatrix: &bellatrix.ExecutionPayload{ParentHash: parentHash, BlockHash: blockHash}},
+		BellatrixHeader: &bellatrix.ExecutionPayloadHeader{ParentHash: parentHash, BlockHash: blockHash},
+	}
+}
+
+func testCachedCapellaPayload(slot uint64, parentHash, blockHash phase0.Hash32, value uint64) *CachedPayload {
+	return &CachedPayload{
+		Slot:          slot,
+		Value:         uint256.NewInt(value),
+		Payload:       &api.VersionedExecutionPayload{Version: consensusspec.DataVersionCapella, Capella: &capella.ExecutionPayload{ParentHash: parentHash, BlockHash: blockHash}},
+		CapellaHeader: &capella.ExecutionPayloadHeader{ParentHash: parentHash, BlockHash: blockHash},
+	}
+}
+
+func TestPayloadCache(t *testing.T) {
+	cache := NewPayloadCache(2)
+
+	parentA := phase0.Hash32{0xa}
+	parentB := phase0.Hash32{0xb}
+
+	entry1 := testCachedPayload(10, parentA, phase0.Hash32{0x1}, 1)
+	require.True(t, cache.Add(entry1))
+
+	entry2 := testCachedPayload(10, parentA, phase0.Hash32{0x2}, 2)
+	require.True(t, cache.Add(entry2))
+
+	entry3 := testCachedCapellaPayload(10, parentB, phase0.Hash32{0x3}, 3)
+	require.True(t, cache.Add(entry3))
+
+	// a less valuable later submission does not replace the served one
+	entryLow := testCachedPayload(10, parentA, phase0.Hash32{0x6}, 1)
+	require.True(t, cache.Add(entryLow))
+
+	// most valuable submission for the parent is served
+	best, found := cache.Best(10, parentA)
+	require.True(t, found)
+	require.Equal(t, entry2, best)
+
+	best, found = cache.Best(10, parentB)
+	require.True(t, found)
+	require.Equal(t, entry3, best)
+	require.Equal(t, parentB, best.ParentHash())
+
+	_, found = cache.Best(11, parentA)
+	require.False(t, found)
+
+	// replaced payloads can still be unblinded
+	entry, found := cache.Get(entry1.BlockHash())
+	require.True(t, found)
+	require.Equal(t, entry1, entry)
+	require.Equal(t, 4, cache.Len())
+	require.Equal(t, entryLow, cache.Latest())
+
+	entry4 := testCachedPayload(11, parentA, phase0.Hash32{0x4}, 4)
+	require.True(t, cache.Add(entry4))
+	require.Equal(t, 5, cache.Len())
+
+	// slot 10 falls out of the window
+	entry5 := testCachedPayload(12, parentA, phase0.Hash32{0x5}, 5)
+	require.True(t, cache.Add(entry5))
+	require.Equal(t, 2, cache.Len())
+
+	_, found = cache.Get(entry1.BlockHash())
+	require.False(t, found)
+	_, found = cache.Best(10, parentA)
+	require.False(t, found)
+
+	entry, found = cache.Get(entry4.BlockHash())
+	require.True(t, found)
+	require.Equal(t, entry4, entry)
+
+	// late payloads for evicted slots are rejected
+	require.False(t, cache.Add(entry1))
+	require.Equal(t, 2, cache.Len())
+}
@@ -2,7 +2,7 @@ package builder
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net/http"
@@ -10,14 +10,52 @@ import (
 	"sync"
 	"time"
 
-	"github.com/attestantio/go-builder-client/api/bellatrix"
-	"github.com/attestantio/go-builder-client/api/capella"
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/flashbots/go-boost-utils/ssz"
 	"github.com/flashbots/go-boost-utils/utils"
 )
 
 var ErrValidatorNotFound = errors.New("validator not found")
 
+// Reasons for rejecting a validator registration served by a relay
+var (
+	errRegistrationMalformed        = errors.New("malformed registration")
+	errRegistrationInvalidSignature = errors.New("invalid registration signature")
+	errRegistrationNotProposer      = errors.New("registration pubkey is not the slot proposer")
+)
+
+// Well-known relay rejections of block submissions, submission errors can be matched against them with errors.Is
+var (
+	ErrRelayBidTooLow               = errors.New("bid too low")
+	ErrRelaySimulationFailed        = errors.New("simulation failed")
+	ErrRelayRateLimited             = errors.New("rate limited")
+	ErrRelayPayloadAlreadyDelivered = errors.New("payload already delivered")
+)
+
+// classifyRelayError maps a relay error response to one of the well-known rejections, nil if it is none of them
+func classifyRelayError(statusCode int, message string) error {
+	if statusCode == http.StatusTooManyRequests {
+		return ErrRelayRateLimited
+	}
+
+	message = strings.ToLower(message)
+	switch {
+	case strings.Contains(message, "already delivered"):
+		return ErrRelayPayloadAlreadyDelivered
+	case strings.Contains(message, "simulation failed"):
+		return ErrRelaySimulationFailed
+	case strings.Contains(message, "too low"), strings.Contains(message, "below floor"):
+		return ErrRelayBidTooLow
+	case strings.Contains(message, "rate limit"), strings.Contains(message, "too many requests"):
+		return ErrRelayRateLimited
+	default:
+		return nil
+	}
+}
+
 type RemoteRelay struct {
 	client http.Client
 	config RelayConfig
@@ -26,17 +64,38 @@ type RemoteRelay struct {
 
 	cancellationsEnabled bool
 
+	// registrations served by the relay are verified against the builder domain and, if the beacon client
+	// is set, against the proposer duties
+	builderSigningDomain phase0.Domain
+	beaconClient         IBeaconClient
+
+	slotsInEpoch uint64
+	refreshC     chan struct{}
+	stop         chan struct{}
+	stopOnce     sync.Once
+
 	validatorsLock       sync.RWMutex
 	validatorSyncOngoing bool
 	lastRequestedSlot    uint64
+	refreshSlot          uint64
+	validatorsUpdatedAt  time.Time
 	validatorSlotMap     map[uint64]ValidatorData
 }
 
-func NewRemoteRelay(config RelayConfig, localRelay *LocalRelay, cancellationsEnabled bool) *RemoteRelay {
+func NewRemoteRelay(config RelayConfig, localRelay *LocalRelay, cancellationsEnabled bool, builderSigningDomain phase0.Domain, beaconClient IBeaconClient, slotsInEpoch uint64) *RemoteRelay {
+	config = config.withDefaults()
+	if slotsInEpoch == 0 {
+		slotsInEpoch = DefaultConfig.SlotsInEpoch
+	}
 	r := &RemoteRelay{
-		client:               http.Client{Timeout: time.Second},
+		client:               newRelayHTTPClient(config),
 		localRelay:           localRelay,
 		cancellationsEnabled: cancellationsEnabled,
+		builderSigningDomain: builderSigningDomain,
+		beaconClient:         beaconClient,
+		slotsInEpoch:         slotsInEpoch,
+		refreshC:             make(chan struct{}, 1),
+		stop:                 make(chan struct{}),
 		validatorSyncOngoing: false,
 		lastRequestedSlot:    0,
 		validatorSlotMap:     make(map[uint64]ValidatorData),
@@ -50,7 +109,21 @@ func NewRemoteRelay(config RelayConfig, localRelay *LocalRelay, cancellationsEna
 	return r
 }
 
-type GetValidatorRelayResponse []struct {
+func newRelayHTTPClient(config RelayConfig) http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = config.MaxIdleConns
+	transport.MaxIdleConnsPerHost = config.MaxIdleConns
+	if config.DisableHTTP2 {
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	// the timeout is applied per request, fetching the validators of large relays can take longer than a submission
+	return http.Client{Transport: transport}
+}
+
+type GetValidatorRelayResponse []RelayValidatorEntry
+
+type RelayValidatorEntry struct {
 	Slot  uint64 `json:"slot,string"`
 	Entry struct {
 		Message struct {
@@ -63,8 +136,68 @@ type GetValidatorRelayResponse []struct {
 	} `json:"entry"`
 }
 
+// signedRegistration decodes the entry into the registration signed by the validator
+func (e *RelayValidatorEntry) signedRegistration() (*apiv1.SignedValidatorRegistration, error) {
+	feeRecipient, err := utils.HexToAddress(e.Entry.Message.FeeRecipient)
+	if err != nil {
+		return nil, fmt.Errorf("%w: fee_recipient: %v", errRegistrationMalformed, err)
+	}
+	pubkey, err := utils.HexToPubkey(e.Entry.Message.Pubkey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: pubkey: %v", errRegistrationMalformed, err)
+	}
+	signature, err := utils.HexToSignature(e.Entry.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", errRegistrationMalformed, err)
+	}
+
+	return &apiv1.SignedValidatorRegistration{
+		Message: &apiv1.ValidatorRegistration{
+			FeeRecipient: feeRecipient,
+			GasLimit:     e.Entry.Message.GasLimit,
+			Timestamp:    time.Unix(int64(e.Entry.Message.Timestamp), 0),
+			Pubkey:       pubkey,
+		},
+		Signature: signature,
+	}, nil
+}
+
+// verifyValidatorRegistration checks that the registration served for the slot was signed by the validator
+// and, if proposer duties are known, that the validator is the proposer of the slot
+func (r *RemoteRelay) verifyValidatorRegistration(slot uint64, registration *apiv1.SignedValidatorRegistration) error {
+	ok, err := ssz.VerifySignature(registration.Message, r.builderSigningDomain, registration.Message.Pubkey[:], registration.Signature[:])
+	if err != nil || !ok {
+		return errRegistrationInvalidSignature
+	}
+
+	if r.beaconClient == nil {
+		return nil
+	}
+	proposer, found := r.beaconClient.proposerForSlot(slot)
+	if !found || proposer == "" {
+		// duties for the slot are not known yet
+		return nil
+	}
+	if !strings.EqualFold(string(proposer), registration.Message.Pubkey.String()) {
+		return fmt.Errorf("%w: expected %s", errRegistrationNotProposer, proposer)
+	}
+	return nil
+}
+
+// validatorsRefreshDue reports whether the registrations fetched for lastSlot should be refreshed for nextSlot,
+// they are refreshed twice per epoch to pick up the registrations of the next epoch's proposers ahead of time
+func validatorsRefreshDue(lastSlot, nextSlot, slotsInEpoch uint64) bool {
+	if lastSlot == 0 {
+		return true
+	}
+	period := slotsInEpoch / 2
+	if period == 0 {
+		period = 1
+	}
+	return nextSlot/period > lastSlot/period
+}
+
 func (r *RemoteRelay) updateValidatorsMap(currentSlot uint64, retries int) error {
-	fmt.Println("updateValidatorsMap() start")
 	r.validatorsLock.Lock()
 	if r.validatorSyncOngoing {
 		r.validatorsLock.Unlock()
@@ -73,193 +206,235 @@ func (r *RemoteRelay) updateValidatorsMap(currentSlot uint64, retries int) error
 	r.validatorSyncOngoing = true
 	r.validatorsLock.Unlock()
 
-	fmt.Println("updateValidatorsMap() loading 1")
-	log.Info("requesting ", "currentSlot", currentSlot)
+	log.Debug("requesting validators from relay", "endpoint", r.config.Endpoint, "currentSlot", currentSlot)
 	newMap, err := r.getSlotValidatorMapFromRelay()
 	for err != nil && retries > 0 {
-		log.Error("111 getSlotValidatorMapFromRelay() error")
 		log.Error("could not get validators map from relay, retrying", "err", err)
-		time.Sleep(time.Second)
-		newMap, err = r.getSlotValidatorMapFromRelay()
-		retries -= 1
+		select {
+		case <-r.stop:
+			retries = 0
+		case <-time.After(time.Second):
+			newMap, err = r.getSlotValidatorMapFromRelay()
+			retries -= 1
+		}
 	}
 	r.validatorsLock.Lock()
 	r.validatorSyncOngoing = false
-	fmt.Println("updateValidatorsMap() loading 2")
 	if err != nil {
 		r.validatorsLock.Unlock()
-		log.Error("222 error")
 		log.Error("could not get validators map from relay", "err", err)
 		return err
 	}
-	fmt.Println("updateValidatorsMap() loading 3")
-	fmt.Println("updateValidatorsMap() newMap:", newMap)
+
+	// keep the registrations of the current epoch which the relay may no longer serve
+	windowStart := currentSlot - currentSlot%r.slotsInEpoch
+	for slot, vd := range r.validatorSlotMap {
+		if _, found := newMap[slot]; !found && slot >= windowStart {
+			newMap[slot] = vd
+		}
+	}
 	r.validatorSlotMap = newMap
 	r.lastRequestedSlot = currentSlot
+	r.validatorsUpdatedAt = time.Now()
 	r.validatorsLock.Unlock()
 
 	log.Info("Updated validators", "count", len(newMap), "slot", currentSlot)
 	return nil
 }
 
+// requestValidatorsRefresh schedules a refresh of the registrations for the slot without waiting for it
+func (r *RemoteRelay) requestValidatorsRefresh(slot uint64) {
+	r.validatorsLock.Lock()
+	if slot > r.refreshSlot {
+		r.refreshSlot = slot
+	}
+	r.validatorsLock.Unlock()
+
+	select {
+	case r.refreshC <- struct{}{}:
+	default:
+	}
+}
+
+// refreshValidatorsForever fetches the registrations whenever a refresh is requested until the relay is stopped
+func (r *RemoteRelay) refreshValidatorsForever() {
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-r.refreshC:
+		}
+
+		r.validatorsLock.RLock()
+		slot := r.refreshSlot
+		due := validatorsRefreshDue(r.lastRequestedSlot, slot, r.slotsInEpoch)
+		r.validatorsLock.RUnlock()
+		if !due {
+			continue
+		}
+
+		if err := r.updateValidatorsMap(slot, 1); err != nil {
+			log.Error("could not update validators map", "err", err)
+		}
+	}
+}
+
+// GetValidatorForSlot looks the registration up in the prefetched registrations, it never waits for the relay
 func (r *RemoteRelay) GetValidatorForSlot(nextSlot uint64) (ValidatorData, error) {
 	// next slot is expected to be the actual chain's next slot, not something requested by the user!
 	// if not sanitized it will force resync of validator data and possibly is a DoS vector
 
-	// 手动添加一段代码
-	fmt.Println("手动updateValidatorsMap")
-	err := r.updateValidatorsMap(nextSlot, 1)
-	if err != nil {
-		fmt.Println("手动 updateValidatorsMap 失败")
-		log.Error("could not update validators map", "err", err)
-	} else {
-		fmt.Println("手动 updateValidatorsMap 成功")
-	}
-
-	fmt.Println("GetValidatorForSlot start")
 	r.validatorsLock.RLock()
-	// TODO 主要是因为这个32被写死了
-	if r.lastRequestedSlot == 0 || nextSlot/32 > r.lastRequestedSlot/32 {
-		fmt.Println("GetValidatorForSlot loading 0.5")
-		// Every epoch request validators map
-		go func() {
-			fmt.Println("r.updateValidatorsMap ", "nextSlot", nextSlot)
-			err := r.updateValidatorsMap(nextSlot, 1)
-			if err != nil {
-				log.Error("could not update validators map", "err", err)
-			}
-		}()
-	}
-	fmt.Println("GetValidatorForSlot loading 1")
-	fmt.Println("r.validatorSlotMap:", r.validatorSlotMap)
+	refreshDue := validatorsRefreshDue(r.lastRequestedSlot, nextSlot, r.slotsInEpoch)
+	lastRequestedSlot, updatedAt := r.lastRequestedSlot, r.validatorsUpdatedAt
 	vd, found := r.validatorSlotMap[nextSlot]
 	r.validatorsLock.RUnlock()
 
+	if refreshDue {
+		r.requestValidatorsRefresh(nextSlot)
+	}
+	markValidatorsStaleness(r.config.Endpoint, updatedAt, lastRequestedSlot, nextSlot, found)
+
 	if r.localRelay != nil {
-		fmt.Println("LOCAL RELAY is active")
 		localValidator, err := r.localRelay.GetValidatorForSlot(nextSlot)
-		fmt.Println("RemoteRelay qqq GetValidatorForSlot() r.localRelay != nil, localValidator:", localValidator)
 		if err == nil {
 			log.Info("Validator registration overwritten by local data", "slot", nextSlot, "validator", localValidator)
 			return localValidator, nil
 		}
-	} else {
-		fmt.Println("LOCAL RELAY is inactive")
 	}
-	fmt.Println("GetValidatorForSlot loading 2")
 
 	if found {
-		fmt.Println("RemoteRelay qqq GetValidatorForSlot() found:", found)
-		fmt.Println("RemoteRelay qqq GetValidatorForSlot() vd:", vd)
 		return vd, nil
 	}
 
-	fmt.Println("GetValidatorForSlot end: ", ValidatorData{}, ErrValidatorNotFound)
-
 	return ValidatorData{}, ErrValidatorNotFound
 }
 
+// RelayStatus reports the freshness of the validator registrations served by the relay
+func (r *RemoteRelay) RelayStatus() []RelayStatus {
+	r.validatorsLock.RLock()
+	defer r.validatorsLock.RUnlock()
+
+	return []RelayStatus{{
+		Endpoint:            r.config.Endpoint,
+		ValidatorsSlot:      r.lastRequestedSlot,
+		ValidatorsUpdatedAt: r.validatorsUpdatedAt,
+		Validators:          len(r.validatorSlotMap),
+	}}
+}
+
 func (r *RemoteRelay) Start() error {
+	go r.refreshValidatorsForever()
 	return nil
 }
 
-func (r *RemoteRelay) Stop() {}
-
-func (r *RemoteRelay) SubmitBlock(msg *bellatrix.SubmitBlockRequest, _ ValidatorData) error {
-	log.Info("submitting block to remote relay", "endpoint", r.config.Endpoint)
-	endpoint := r.config.Endpoint + "/relay/v1/builder/blocks"
-	if r.cancellationsEnabled {
-		endpoint = endpoint + "?cancellations=1"
-	}
-	code, err := SendHTTPRequest(context.TODO(), *http.DefaultClient, http.MethodPost, endpoint, msg, nil)
-	if err != nil {
-		return fmt.Errorf("error sending http request to relay %s. err: %w", r.config.Endpoint, err)
-	}
-	if code > 299 {
-		return fmt.Errorf("non-ok response code %d from relay %s", code, r.config.Endpoint)
-	}
-
-	if r.localRelay != nil {
-		r.localRelay.submitBlock(msg)
-	}
-
-	return nil
+func (r *RemoteRelay) Stop() {
+	r.stopOnce.Do(func() { close(r.stop) })
 }
 
-func (r *RemoteRelay) SubmitBlockCapella(msg *capella.SubmitBlockRequest, _ ValidatorData) error {
-	log.Info("submitting block to remote relay", "endpoint", r.config.Endpoint)
+func (r *RemoteRelay) SubmitBlock(ctx context.Context, msg *builderapi.VersionedSubmitBlockRequest, _ ValidatorData) error {
+	log.Info("submitting block to remote relay", "endpoint", r.config.Endpoint, "version", msg.Version)
 
 	endpoint := r.config.Endpoint + "/relay/v1/builder/blocks"
 	if r.cancellationsEnabled {
 		endpoint = endpoint + "?cancellations=1"
 	}
 
+	var bodyBytes []byte
 	if r.config.SszEnabled {
-		bodyBytes, err := msg.MarshalSSZ()
-		if err != nil {
+		var err error
+		bodyBytes, err = msg.MarshalSSZ()
+		if errors.Is(err, builderapi.ErrUnsupportedVersion) {
+			log.Debug("ssz encoding not available, submitting json", "version", msg.Version)
+			bodyBytes = nil
+		} else if err != nil {
 			return fmt.Errorf("error marshaling ssz: %w", err)
 		}
-		log.Debug("submitting block to remote relay", "endpoint", r.config.Endpoint)
-		code, err := SendSSZRequest(context.TODO(), *http.DefaultClient, http.MethodPost, endpoint, bodyBytes, r.config.GzipEnabled)
-		if err != nil {
-			return fmt.Errorf("error sending http request to relay %s. err: %w", r.config.Endpoint, err)
-		}
-		if code > 299 {
-			return fmt.Errorf("non-ok response code %d from relay %s", code, r.config.Endpoint)
-		}
-	} else {
-		code, err := SendHTTPRequest(context.TODO(), *http.DefaultClient, http.MethodPost, endpoint, msg, nil)
-		if err != nil {
-			return fmt.Errorf("error sending http request to relay %s. err: %w", r.config.Endpoint, err)
+	}
+
+	send := func() error {
+		sendCtx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+		defer cancel()
+
+		var err error
+		if bodyBytes != nil {
+			log.Debug("submitting block to remote relay", "endpoint", r.config.Endpoint)
+			_, err = SendSSZRequest(sendCtx, r.client, http.MethodPost, endpoint, bodyBytes, r.config.GzipEnabled)
+		} else {
+			_, err = SendHTTPRequest(sendCtx, r.client, http.MethodPost, endpoint, msg, nil)
 		}
-		if code > 299 {
-			return fmt.Errorf("non-ok response code %d from relay %s", code, r.config.Endpoint)
+		return err
+	}
+
+	err := send()
+	for attempt := 1; err != nil && attempt <= r.config.Retries && isRetryableRelayError(ctx, err); attempt++ {
+		log.Debug("retrying block submission", "endpoint", r.config.Endpoint, "attempt", attempt, "err", err)
+		t := time.NewTimer(r.config.RetryBackoff * time.Duration(attempt))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("error sending http request to relay %s. err: %w", r.config.Endpoint, ctx.Err())
+		case <-t.C:
 		}
+		err = send()
+	}
+	if err != nil {
+		return fmt.Errorf("error sending http request to relay %s. err: %w", r.config.Endpoint, err)
 	}
 
 	if r.localRelay != nil {
-		r.localRelay.submitBlockCapella(msg)
+		r.localRelay.submitBlock(msg)
 	}
 
 	return nil
 }
 
+// isRetryableRelayError reports whether a failed submission can be retried, relay rejections of the
+// submission itself and abandoned slots are final while timed out attempts and server errors are not
+func isRetryableRelayError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var errResp *HTTPErrorResponse
+	if errors.As(err, &errResp) {
+		return errResp.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
+
 func (r *RemoteRelay) getSlotValidatorMapFromRelay() (map[uint64]ValidatorData, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.ValidatorsTimeout)
+	defer cancel()
+
 	var dst GetValidatorRelayResponse
-	code, err := SendHTTPRequest(context.TODO(), *http.DefaultClient, http.MethodGet, r.config.Endpoint+"/relay/v1/builder/validators", nil, &dst)
+	code, err := SendHTTPRequest(ctx, r.client, http.MethodGet, r.config.Endpoint+"/relay/v1/builder/validators", nil, &dst)
 	if err != nil {
 		return nil, err
 	}
-	jsonDst, _ := json.Marshal(dst)
-	// 这里的输出是正常的
-	fmt.Println("000 getSlotValidatorMapFromRelay:", string(jsonDst))
 	if code > 299 {
 		return nil, fmt.Errorf("non-ok response code %d from relay", code)
 	}
 
 	res := make(map[uint64]ValidatorData)
 	for _, data := range dst {
-		fmt.Println("111 data:", data)
-		fmt.Println("111 data.Entry.Message:", data.Entry.Message)
-		fmt.Println("111 data.Entry.Message.FeeRecipient:", data.Entry.Message.FeeRecipient)
-		feeRecipient, err := utils.HexToAddress(data.Entry.Message.FeeRecipient)
-		//feeRecipient, err := utils.HexToAddress("0x123463a4b065722e99115d6c222f267d9cabb524")
-
+		registration, err := data.signedRegistration()
+		if err == nil {
+			err = r.verifyValidatorRegistration(data.Slot, registration)
+		}
 		if err != nil {
-			log.Error("Ill-formatted fee_recipient from relay", "data", data)
+			log.Error("Rejected validator registration from relay", "endpoint", r.config.Endpoint, "slot", data.Slot, "pubkey", data.Entry.Message.Pubkey, "err", err)
+			markInvalidValidatorRegistration(r.config.Endpoint, err)
 			continue
 		}
-		// 这里是正常的
-		fmt.Println("这里是正常的 111 feeRecipient:", feeRecipient)
-
 		pubkeyHex := PubkeyHex(strings.ToLower(data.Entry.Message.Pubkey))
 
 		res[data.Slot] = ValidatorData{
 			Pubkey:       pubkeyHex,
-			FeeRecipient: feeRecipient,
-			GasLimit:     data.Entry.Message.GasLimit,
+			FeeRecipient: registration.Message.FeeRecipient,
+			GasLimit:     registration.Message.GasLimit,
 		}
 	}
+	log.Debug("Fetched validator registrations from relay", "endpoint", r.config.Endpoint, "registrations", len(dst), "valid", len(res))
 
 	return res, nil
 }
@@ -1,17 +1,34 @@
 package builder
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
-	"github.com/attestantio/go-builder-client/api/bellatrix"
-	"github.com/attestantio/go-builder-client/api/capella"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
 	"github.com/ethereum/go-ethereum/log"
 )
 
+const (
+	rateLimitedBackoffInitial = 500 * time.Millisecond
+	rateLimitedBackoffMax     = 4 * time.Second
+)
+
+var (
+	// errRelaySubmissionSuperseded is the outcome of a submission replaced by a newer block of the same slot and
+	// parent before it was sent to the relay
+	errRelaySubmissionSuperseded = errors.New("submission superseded by a newer block")
+	// errRelaySubmitterStopped is the outcome of a submission waiting for a relay that was stopped
+	errRelaySubmitterStopped = errors.New("relay stopped")
+)
+
 type RemoteRelayAggregator struct {
-	relays []IRelay // in order of precedence, primary first
+	relays     []IRelay // in order of precedence, primary first
+	submitters map[IRelay]*relaySubmitter
 
 	registrationsCacheLock sync.RWMutex
 	registrationsCacheSlot uint64
@@ -19,10 +36,28 @@ type RemoteRelayAggregator struct {
 }
 
 func NewRemoteRelayAggregator(primary IRelay, secondary []IRelay) *RemoteRelayAggregator {
-	relays := []IRelay{primary}
+	relays := append([]IRelay{primary}, secondary...)
+	submitters := make(map[IRelay]*relaySubmitter, len(relays))
+	for _, relay := range relays {
+		submitters[relay] = newRelaySubmitter(relay)
+	}
 	return &RemoteRelayAggregator{
-		relays: append(relays, secondary...),
+		relays:     relays,
+		submitters: submitters,
+	}
+}
+
+// RelayStatus reports the status of every aggregated relay, primary first
+func (r *RemoteRelayAggregator) RelayStatus() []RelayStatus {
+	var status []RelayStatus
+	for _, relay := range r.relays {
+		if s, ok := relay.(IRelayStatus); ok {
+			status = append(status, s.RelayStatus()...)
+		} else {
+			status = append(status, RelayStatus{Endpoint: relay.Config().Endpoint})
+		}
 	}
+	return status
 }
 
 func (r *RemoteRelayAggregator) Start() error {
@@ -37,48 +72,244 @@ func (r *RemoteRelayAggregator) Start() error {
 
 func (r *RemoteRelayAggregator) Stop() {
 	for _, relay := range r.relays {
+		r.submitters[relay].stop()
 		relay.Stop()
 	}
 }
 
-func (r *RemoteRelayAggregator) SubmitBlock(msg *bellatrix.SubmitBlockRequest, registration ValidatorData) error {
-	r.registrationsCacheLock.RLock()
-	defer r.registrationsCacheLock.RUnlock()
+// SubmitBlock submits the block to every relay the proposer is registered with and waits for all of them
+func (r *RemoteRelayAggregator) SubmitBlock(ctx context.Context, msg *builderapi.VersionedSubmitBlockRequest, registration ValidatorData) error {
+	done := make(chan []builderapi.RelaySubmissionResult, 1)
+	if err := r.SubmitBlockToRelays(ctx, msg, registration, func(results []builderapi.RelaySubmissionResult) { done <- results }); err != nil {
+		return err
+	}
+	return relaySubmissionsError(<-done)
+}
 
+// SubmitBlockToRelays hands the block to the submitter of every relay the proposer is registered with and returns
+// without waiting for the relays. Every relay is sent its newest pending block as soon as it is done with the previous
+// one and its rate limit allows, so a slow relay does not delay the others. onDone is called with the outcome for
+// every relay, in order of relay precedence, once all of them completed or were superseded by a newer block of the
+// same slot and parent.
+func (r *RemoteRelayAggregator) SubmitBlockToRelays(ctx context.Context, msg *builderapi.VersionedSubmitBlockRequest, registration ValidatorData, onDone func([]builderapi.RelaySubmissionResult)) error {
+	bidTrace, err := msg.BidTrace()
+	if err != nil {
+		return err
+	} else if bidTrace == nil {
+		return errors.New("no bid trace in submission")
+	}
+	job := relaySubmissionJob{slot: bidTrace.Slot, parentHash: bidTrace.ParentHash}
+
+	r.registrationsCacheLock.RLock()
 	relays, found := r.registrationsCache[registration]
+	r.registrationsCacheLock.RUnlock()
 	if !found {
 		return fmt.Errorf("no relays for registration %s", registration.Pubkey)
 	}
-	for _, relay := range relays {
-		go func(relay IRelay) {
-			err := relay.SubmitBlock(msg, registration)
-			if err != nil {
-				log.Error("could not submit block", "err", err)
-			}
-		}(relay)
-	}
 
+	fanOut := &relayFanOut{
+		results:   make([]builderapi.RelaySubmissionResult, len(relays)),
+		remaining: len(relays),
+		onDone:    onDone,
+	}
+	for i, relay := range relays {
+		fanOut.results[i].Relay = relay.Config().Endpoint
+		r.submitters[relay].submit(&relaySubmission{
+			ctx:          ctx,
+			job:          job,
+			msg:          msg,
+			registration: registration,
+			fanOut:       fanOut,
+			index:        i,
+		})
+	}
 	return nil
 }
 
-func (r *RemoteRelayAggregator) SubmitBlockCapella(msg *capella.SubmitBlockRequest, registration ValidatorData) error {
-	r.registrationsCacheLock.RLock()
-	defer r.registrationsCacheLock.RUnlock()
+// relaySubmissionsError returns nil if any relay accepted the block, otherwise an error wrapping the error of the
+// most important relay that was sent the block
+func relaySubmissionsError(results []builderapi.RelaySubmissionResult) error {
+	var firstErr error
+	for _, result := range results {
+		if result.Err == nil {
+			return nil
+		}
+		if firstErr == nil && !errors.Is(result.Err, errRelaySubmissionSuperseded) {
+			firstErr = result.Err
+		}
+	}
+	if firstErr == nil {
+		firstErr = errRelaySubmissionSuperseded
+	}
+	return fmt.Errorf("no relay accepted the block: %w", firstErr)
+}
 
-	relays, found := r.registrationsCache[registration]
+// relayFanOut collects the outcome of submitting a block to several relays
+type relayFanOut struct {
+	mu        sync.Mutex
+	results   []builderapi.RelaySubmissionResult
+	remaining int
+	onDone    func([]builderapi.RelaySubmissionResult)
+}
+
+func (f *relayFanOut) done(index int, latency time.Duration, err error) {
+	f.mu.Lock()
+	f.results[index].Latency = latency
+	f.results[index].Err = err
+	f.remaining--
+	remaining := f.remaining
+	f.mu.Unlock()
+
+	if remaining == 0 && f.onDone != nil {
+		f.onDone(f.results)
+	}
+}
+
+// relaySubmissionJob identifies the building job of a block, the blocks of a job replace each other
+type relaySubmissionJob struct {
+	slot       uint64
+	parentHash phase0.Hash32
+}
+
+// relaySubmission is a block waiting to be sent to a single relay
+type relaySubmission struct {
+	ctx          context.Context
+	job          relaySubmissionJob
+	msg          *builderapi.VersionedSubmitBlockRequest
+	registration ValidatorData
+	fanOut       *relayFanOut
+	index        int
+}
+
+// relaySubmitter sends blocks to a single relay one at a time. Only the newest block of every job waiting for the
+// relay is kept, the jobs take turns in the order they started waiting. A block is sent once the previous submission
+// completed, the rate limit interval elapsed and the backoff after a rate limited submission expired.
+type relaySubmitter struct {
+	relay    IRelay
+	interval time.Duration
+	stopCh   chan struct{}
+
+	mu      sync.Mutex
+	pending map[relaySubmissionJob]*relaySubmission
+	queue   []relaySubmissionJob
+	running bool
+	stopped bool
+	next    time.Time
+	backoff time.Duration
+}
+
+func newRelaySubmitter(relay IRelay) *relaySubmitter {
+	return &relaySubmitter{
+		relay:    relay,
+		interval: relay.Config().RateLimitInterval,
+		stopCh:   make(chan struct{}),
+		pending:  make(map[relaySubmissionJob]*relaySubmission),
+	}
+}
+
+// submit makes the submission the one sent next to the relay for its job, superseding the block of the job waiting
+func (s *relaySubmitter) submit(submission *relaySubmission) {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		submission.fanOut.done(submission.index, 0, errRelaySubmitterStopped)
+		return
+	}
+	superseded, found := s.pending[submission.job]
 	if !found {
-		return fmt.Errorf("no relays for registration %s", registration.Pubkey)
+		s.queue = append(s.queue, submission.job)
 	}
-	for _, relay := range relays {
-		go func(relay IRelay) {
-			err := relay.SubmitBlockCapella(msg, registration)
-			if err != nil {
-				log.Error("could not submit block", "err", err)
+	s.pending[submission.job] = submission
+	if !s.running {
+		s.running = true
+		go s.run()
+	}
+	s.mu.Unlock()
+
+	if superseded != nil {
+		superseded.fanOut.done(superseded.index, 0, errRelaySubmissionSuperseded)
+	}
+}
+
+// stop fails the submissions waiting for the relay and the ones submitted later
+func (s *relaySubmitter) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.stopCh)
+	}
+}
+
+func (s *relaySubmitter) run() {
+	for {
+		s.mu.Lock()
+		if s.stopped {
+			aborted := s.pending
+			s.pending = make(map[relaySubmissionJob]*relaySubmission)
+			s.queue = nil
+			s.running = false
+			s.mu.Unlock()
+			for _, submission := range aborted {
+				submission.fanOut.done(submission.index, 0, errRelaySubmitterStopped)
 			}
-		}(relay)
+			return
+		}
+		if len(s.queue) == 0 {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+
+		job := s.queue[0]
+		submission := s.pending[job]
+		if wait := time.Until(s.next); wait > 0 && submission.ctx.Err() == nil {
+			s.mu.Unlock()
+			// the submission may be superseded while waiting, the newest block of the job is taken afterwards
+			s.wait(submission.ctx, wait)
+			continue
+		}
+		delete(s.pending, job)
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		if err := submission.ctx.Err(); err != nil {
+			submission.fanOut.done(submission.index, 0, err)
+			continue
+		}
+
+		start := time.Now()
+		err := s.relay.SubmitBlock(submission.ctx, submission.msg, submission.registration)
+		latency := time.Since(start)
+
+		s.mu.Lock()
+		s.next = start.Add(s.interval)
+		if errors.Is(err, ErrRelayRateLimited) {
+			if s.backoff == 0 {
+				s.backoff = rateLimitedBackoffInitial
+			} else if s.backoff < rateLimitedBackoffMax {
+				s.backoff *= 2
+			}
+			s.next = time.Now().Add(s.backoff)
+			log.Warn("relay rate limited submission, backing off", "relay", s.relay.Config().Endpoint, "backoff", s.backoff)
+		} else {
+			s.backoff = 0
+		}
+		s.mu.Unlock()
+
+		submission.fanOut.done(submission.index, latency, err)
 	}
+}
 
-	return nil
+// wait waits for d, it returns early when the context is done or the relay is stopped
+func (s *relaySubmitter) wait(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	case <-s.stopCh:
+	}
 }
 
 type RelayValidatorRegistration struct {
@@ -161,6 +392,7 @@ func (r *RemoteRelayAggregator) updateRelayRegistrations(nextSlot uint64, regist
 		r.registrationsCacheSlot = nextSlot
 	}
 
+	sort.Slice(registrations, func(i, j int) bool { return registrations[i].relayI < registrations[j].relayI })
 	for _, relayRegistration := range registrations {
 		r.registrationsCache[relayRegistration.vd] = append(r.registrationsCache[relayRegistration.vd], r.relays[relayRegistration.relayI])
 	}
@@ -1,31 +1,36 @@
 package builder
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/attestantio/go-builder-client/api/bellatrix"
-	"github.com/attestantio/go-builder-client/api/capella"
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
 	"github.com/stretchr/testify/require"
 )
 
 /*
 	validator     ValidatorData
 	requestedSlot uint64
-	submittedMsg  *bellatrix.SubmitBlockRequest
+	submittedMsg  *builderapi.VersionedSubmitBlockRequest
 */
 
 type testRelay struct {
 	sbError error
+	sbDelay time.Duration
+	config  RelayConfig
 	gvsVd   ValidatorData
 	gvsErr  error
 
-	requestedSlot         uint64
-	submittedMsg          *bellatrix.SubmitBlockRequest
-	submittedMsgCh        chan *bellatrix.SubmitBlockRequest
-	submittedMsgCapella   *capella.SubmitBlockRequest
-	submittedMsgChCapella chan *capella.SubmitBlockRequest
+	requestedSlot  uint64
+	submittedMsg   *builderapi.VersionedSubmitBlockRequest
+	submittedMsgCh chan *builderapi.VersionedSubmitBlockRequest
 }
 
 type testRelayAggBackend struct {
@@ -46,7 +51,8 @@ func newTestRelayAggBackend(numRelay int) *testRelayAggBackend {
 	return &testRelayAggBackend{testRelays, ragg}
 }
 
-func (r *testRelay) SubmitBlock(msg *bellatrix.SubmitBlockRequest, registration ValidatorData) error {
+func (r *testRelay) SubmitBlock(_ context.Context, msg *builderapi.VersionedSubmitBlockRequest, registration ValidatorData) error {
+	time.Sleep(r.sbDelay)
 	if r.submittedMsgCh != nil {
 		select {
 		case r.submittedMsgCh <- msg:
@@ -57,17 +63,6 @@ func (r *testRelay) SubmitBlock(msg *bellatrix.SubmitBlockRequest, registration
 	return r.sbError
 }
 
-func (r *testRelay) SubmitBlockCapella(msg *capella.SubmitBlockRequest, registration ValidatorData) error {
-	if r.submittedMsgCh != nil {
-		select {
-		case r.submittedMsgChCapella <- msg:
-		default:
-		}
-	}
-	r.submittedMsgCapella = msg
-	return r.sbError
-}
-
 func (r *testRelay) GetValidatorForSlot(nextSlot uint64) (ValidatorData, error) {
 	r.requestedSlot = nextSlot
 	return r.gvsVd, r.gvsErr
@@ -80,7 +75,14 @@ func (r *testRelay) Start() error {
 func (r *testRelay) Stop() {}
 
 func (r *testRelay) Config() RelayConfig {
-	return RelayConfig{}
+	return r.config
+}
+
+func newTestSubmitBlockRequest(slot uint64, parentHash phase0.Hash32) *builderapi.VersionedSubmitBlockRequest {
+	return &builderapi.VersionedSubmitBlockRequest{
+		Version:   consensusspec.DataVersionBellatrix,
+		Bellatrix: &bellatrix.SubmitBlockRequest{Message: &apiv1.BidTrace{Slot: slot, ParentHash: parentHash}},
+	}
 }
 
 func TestRemoteRelayAggregator(t *testing.T) {
@@ -145,8 +147,8 @@ func TestRemoteRelayAggregator(t *testing.T) {
 		time.Sleep(10 * time.Millisecond)
 
 		// if submitting for unseen VD should error out
-		msg := &bellatrix.SubmitBlockRequest{}
-		err = backend.ragg.SubmitBlock(msg, ValidatorData{GasLimit: 40})
+		msg := newTestSubmitBlockRequest(10, phase0.Hash32{0x01})
+		err = backend.ragg.SubmitBlock(context.Background(), msg, ValidatorData{GasLimit: 40})
 		require.Error(t, err)
 	})
 
@@ -166,13 +168,13 @@ func TestRemoteRelayAggregator(t *testing.T) {
 		time.Sleep(10 * time.Millisecond)
 
 		// if submitting for unseen VD should error out
-		msg := &bellatrix.SubmitBlockRequest{}
-		err = backend.ragg.SubmitBlock(msg, ValidatorData{GasLimit: 40})
+		msg := newTestSubmitBlockRequest(11, phase0.Hash32{0x01})
+		err = backend.ragg.SubmitBlock(context.Background(), msg, ValidatorData{GasLimit: 40})
 		require.Error(t, err)
 
 		// should submit to the single pirmary if its the only one matching
-		backend.relays[0].submittedMsgCh = make(chan *bellatrix.SubmitBlockRequest, 1)
-		err = backend.ragg.SubmitBlock(msg, ValidatorData{GasLimit: 10})
+		backend.relays[0].submittedMsgCh = make(chan *builderapi.VersionedSubmitBlockRequest, 1)
+		err = backend.ragg.SubmitBlock(context.Background(), msg, ValidatorData{GasLimit: 10})
 		require.NoError(t, err)
 		select {
 		case rsMsg := <-backend.relays[0].submittedMsgCh:
@@ -212,13 +214,13 @@ func TestRemoteRelayAggregator(t *testing.T) {
 		time.Sleep(10 * time.Millisecond)
 
 		// should submit to multiple matching relays
-		backend.relays[0].submittedMsgCh = make(chan *bellatrix.SubmitBlockRequest, 1)
-		backend.relays[2].submittedMsgCh = make(chan *bellatrix.SubmitBlockRequest, 1)
-		msg := &bellatrix.SubmitBlockRequest{}
-		err = backend.ragg.SubmitBlock(msg, ValidatorData{GasLimit: 10})
+		backend.relays[0].submittedMsgCh = make(chan *builderapi.VersionedSubmitBlockRequest, 1)
+		backend.relays[2].submittedMsgCh = make(chan *builderapi.VersionedSubmitBlockRequest, 1)
+		msg := newTestSubmitBlockRequest(12, phase0.Hash32{0x01})
+		err = backend.ragg.SubmitBlock(context.Background(), msg, ValidatorData{GasLimit: 10})
 		require.Error(t, err)
 
-		err = backend.ragg.SubmitBlock(msg, ValidatorData{GasLimit: 30})
+		err = backend.ragg.SubmitBlock(context.Background(), msg, ValidatorData{GasLimit: 30})
 		require.NoError(t, err)
 
 		select {
@@ -235,4 +237,174 @@ func TestRemoteRelayAggregator(t *testing.T) {
 			t.Fail()
 		}
 	})
+
+	t.Run("should submit to relays in the background and report the result of each", func(t *testing.T) {
+		backend := newTestRelayAggBackend(3)
+		for i, r := range backend.relays {
+			r.gvsVd.GasLimit = 10
+			r.config.Endpoint = fmt.Sprintf("http://relay%d", i)
+		}
+		backend.relays[0].sbDelay = 300 * time.Millisecond
+		backend.relays[1].sbError = ErrRelayBidTooLow
+
+		_, err := backend.ragg.GetValidatorForSlot(11)
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+
+		// the slow primary does not delay the return nor the submission to the other relays
+		backend.relays[2].submittedMsgCh = make(chan *builderapi.VersionedSubmitBlockRequest, 1)
+		msg := newTestSubmitBlockRequest(11, phase0.Hash32{0x01})
+		resultsCh := make(chan []builderapi.RelaySubmissionResult, 1)
+		start := time.Now()
+		err = backend.ragg.SubmitBlockToRelays(context.Background(), msg, ValidatorData{GasLimit: 10}, func(results []builderapi.RelaySubmissionResult) {
+			resultsCh <- results
+		})
+		require.NoError(t, err)
+		require.Less(t, time.Since(start), 100*time.Millisecond)
+
+		select {
+		case <-backend.relays[2].submittedMsgCh:
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("submission to fast relay was delayed")
+		}
+
+		results := <-resultsCh
+		require.Len(t, results, 3)
+		require.Equal(t, "http://relay0", results[0].Relay)
+		require.NoError(t, results[0].Err)
+		require.GreaterOrEqual(t, results[0].Latency, 300*time.Millisecond)
+		require.ErrorIs(t, results[1].Err, ErrRelayBidTooLow)
+		require.NoError(t, results[2].Err)
+
+		// fails only when no relay accepted the block, with the error of the most important relay
+		backend.relays[0].sbDelay = 0
+		backend.relays[0].sbError = ErrRelaySimulationFailed
+		backend.relays[2].sbError = ErrRelayBidTooLow
+		err = backend.ragg.SubmitBlock(context.Background(), msg, ValidatorData{GasLimit: 10})
+		require.ErrorIs(t, err, ErrRelaySimulationFailed)
+	})
+
+	t.Run("should send the newest pending block to relays over their rate limit", func(t *testing.T) {
+		testRelays := []*testRelay{{}, {config: RelayConfig{RateLimitInterval: 200 * time.Millisecond}}}
+		ragg := NewRemoteRelayAggregator(testRelays[0], []IRelay{testRelays[1]})
+		for _, r := range testRelays {
+			r.gvsVd.GasLimit = 10
+		}
+		testRelays[1].submittedMsgCh = make(chan *builderapi.VersionedSubmitBlockRequest, 3)
+
+		_, err := ragg.GetValidatorForSlot(11)
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+
+		msgs := make([]*builderapi.VersionedSubmitBlockRequest, 3)
+		resultsChs := make([]chan []builderapi.RelaySubmissionResult, 3)
+		for i := range msgs {
+			msgs[i] = newTestSubmitBlockRequest(11, phase0.Hash32{0x01})
+			resultsChs[i] = make(chan []builderapi.RelaySubmissionResult, 1)
+			resultsCh := resultsChs[i]
+			err = ragg.SubmitBlockToRelays(context.Background(), msgs[i], ValidatorData{GasLimit: 10}, func(results []builderapi.RelaySubmissionResult) {
+				resultsCh <- results
+			})
+			require.NoError(t, err)
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		// the first block is sent right away, the second one is replaced by the third one while waiting for the interval
+		start := time.Now()
+		require.Same(t, msgs[0], <-testRelays[1].submittedMsgCh)
+		require.Same(t, msgs[2], <-testRelays[1].submittedMsgCh)
+		require.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+
+		results := <-resultsChs[0]
+		require.NoError(t, results[0].Err)
+		require.NoError(t, results[1].Err)
+
+		results = <-resultsChs[1]
+		require.NoError(t, results[0].Err)
+		require.ErrorIs(t, results[1].Err, errRelaySubmissionSuperseded)
+
+		results = <-resultsChs[2]
+		require.NoError(t, results[0].Err)
+		require.NoError(t, results[1].Err)
+		require.Len(t, testRelays[1].submittedMsgCh, 0)
+	})
+
+	t.Run("should keep the newest pending block of every slot and parent", func(t *testing.T) {
+		relay := &testRelay{config: RelayConfig{RateLimitInterval: 200 * time.Millisecond}, gvsVd: ValidatorData{GasLimit: 10}}
+		ragg := NewRemoteRelayAggregator(relay, nil)
+		relay.submittedMsgCh = make(chan *builderapi.VersionedSubmitBlockRequest, 4)
+
+		_, err := ragg.GetValidatorForSlot(11)
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+
+		// the blocks of the second head do not supersede the waiting block of the first one
+		msgs := []*builderapi.VersionedSubmitBlockRequest{
+			newTestSubmitBlockRequest(11, phase0.Hash32{0x01}),
+			newTestSubmitBlockRequest(11, phase0.Hash32{0x01}),
+			newTestSubmitBlockRequest(11, phase0.Hash32{0x02}),
+			newTestSubmitBlockRequest(11, phase0.Hash32{0x02}),
+		}
+		resultsChs := make([]chan []builderapi.RelaySubmissionResult, len(msgs))
+		for i, msg := range msgs {
+			resultsCh := make(chan []builderapi.RelaySubmissionResult, 1)
+			resultsChs[i] = resultsCh
+			err = ragg.SubmitBlockToRelays(context.Background(), msg, ValidatorData{GasLimit: 10}, func(results []builderapi.RelaySubmissionResult) {
+				resultsCh <- results
+			})
+			require.NoError(t, err)
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		require.Same(t, msgs[0], <-relay.submittedMsgCh)
+		require.Same(t, msgs[1], <-relay.submittedMsgCh)
+		require.Same(t, msgs[3], <-relay.submittedMsgCh)
+		for i, expected := range []error{nil, nil, errRelaySubmissionSuperseded, nil} {
+			results := <-resultsChs[i]
+			if expected == nil {
+				require.NoError(t, results[0].Err, i)
+			} else {
+				require.ErrorIs(t, results[0].Err, expected, i)
+			}
+		}
+	})
+
+	t.Run("should not wait for the rate limit of a stopped relay or a done context", func(t *testing.T) {
+		relay := &testRelay{config: RelayConfig{RateLimitInterval: time.Hour}, gvsVd: ValidatorData{GasLimit: 10}}
+		ragg := NewRemoteRelayAggregator(relay, nil)
+
+		_, err := ragg.GetValidatorForSlot(11)
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+
+		submit := func(ctx context.Context, parentHash phase0.Hash32) chan []builderapi.RelaySubmissionResult {
+			resultsCh := make(chan []builderapi.RelaySubmissionResult, 1)
+			err := ragg.SubmitBlockToRelays(ctx, newTestSubmitBlockRequest(11, parentHash), ValidatorData{GasLimit: 10}, func(results []builderapi.RelaySubmissionResult) {
+				resultsCh <- results
+			})
+			require.NoError(t, err)
+			return resultsCh
+		}
+		awaitResult := func(resultsCh chan []builderapi.RelaySubmissionResult) error {
+			select {
+			case results := <-resultsCh:
+				return results[0].Err
+			case <-time.After(time.Second):
+				t.Fatal("submission is still waiting for the relay")
+				return nil
+			}
+		}
+
+		require.NoError(t, awaitResult(submit(context.Background(), phase0.Hash32{0x01})))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		resultsCh := submit(ctx, phase0.Hash32{0x02})
+		cancel()
+		require.ErrorIs(t, awaitResult(resultsCh), context.Canceled)
+
+		resultsCh = submit(context.Background(), phase0.Hash32{0x03})
+		ragg.Stop()
+		require.ErrorIs(t, awaitResult(resultsCh), errRelaySubmitterStopped)
+		require.ErrorIs(t, awaitResult(submit(context.Background(), phase0.Hash32{0x04})), errRelaySubmitterStopped)
+	})
 }
@@ -1,57 +1,79 @@
 package builder
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	bellatrixapi "github.com/attestantio/go-builder-client/api/bellatrix"
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
 	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/flashbots/go-boost-utils/ssz"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/require"
 )
 
+// relayValidatorsResponse returns a relay validators response with registrations signed by v for the given fee recipients per slot
+func relayValidatorsResponse(t *testing.T, v *ValidatorPrivateData, feeRecipients map[uint64]bellatrix.ExecutionAddress) []byte {
+	var pubkey phase0.BLSPubKey
+	copy(pubkey[:], v.Pk)
+
+	type entry struct {
+		Slot  uint64                             `json:"slot,string"`
+		Entry *apiv1.SignedValidatorRegistration `json:"entry"`
+	}
+	resp := []entry{}
+	for slot, feeRecipient := range feeRecipients {
+		msg := &apiv1.ValidatorRegistration{
+			FeeRecipient: feeRecipient,
+			GasLimit:     1,
+			Timestamp:    time.Unix(1, 0),
+			Pubkey:       pubkey,
+		}
+		signature, err := v.Sign(msg, ssz.DomainBuilder)
+		require.NoError(t, err)
+		resp = append(resp, entry{slot, &apiv1.SignedValidatorRegistration{Message: msg, Signature: signature}})
+	}
+
+	encoded, err := json.Marshal(resp)
+	require.NoError(t, err)
+	return encoded
+}
+
 func TestRemoteRelay(t *testing.T) {
+	validator := NewRandomValidator()
+
 	r := mux.NewRouter()
 	var validatorsHandler func(w http.ResponseWriter, r *http.Request)
 	r.HandleFunc("/relay/v1/builder/validators", func(w http.ResponseWriter, r *http.Request) { validatorsHandler(w, r) })
 
 	validatorsHandler = func(w http.ResponseWriter, r *http.Request) {
-		resp := `[{
-  "slot": "123",
-  "entry": {
-    "message": {
-      "fee_recipient": "0xabcf8e0d4e9587369b2301d0790347320302cc09",
-      "gas_limit": "1",
-      "timestamp": "1",
-      "pubkey": "0x93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74a"
-    },
-    "signature": "0x1b66ac1fb663c9bc59509846d6ec05345bd908eda73e670af888da41af171505cc411d61252fb6cb3fa0017b679f8bb2305b26a285fa2737f175668d0dff91cc1b66ac1fb663c9bc59509846d6ec05345bd908eda73e670af888da41af171505"
-  }}, {
-  "slot": "155",
-  "entry": {
-    "message": {
-      "fee_recipient": "0xabcf8e0d4e9587369b2301d0790347320302cc10",
-      "gas_limit": "1",
-      "timestamp": "1",
-      "pubkey": "0x93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74a"
-    },
-    "signature": "0x1b66ac1fb663c9bc59509846d6ec05345bd908eda73e670af888da41af171505cc411d61252fb6cb3fa0017b679f8bb2305b26a285fa2737f175668d0dff91cc1b66ac1fb663c9bc59509846d6ec05345bd908eda73e670af888da41af171505"
-  }
-}]`
+		resp := relayValidatorsResponse(t, validator, map[uint64]bellatrix.ExecutionAddress{
+			123: {0xab, 0xcf, 0x8e, 0xd, 0x4e, 0x95, 0x87, 0x36, 0x9b, 0x23, 0x1, 0xd0, 0x79, 0x3, 0x47, 0x32, 0x3, 0x2, 0xcc, 0x9},
+			155: {0xab, 0xcf, 0x8e, 0xd, 0x4e, 0x95, 0x87, 0x36, 0x9b, 0x23, 0x1, 0xd0, 0x79, 0x3, 0x47, 0x32, 0x3, 0x2, 0xcc, 0x10},
+		})
 
 		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(resp))
+		w.Write(resp)
 	}
 
 	srv := httptest.NewServer(r)
-	relay := NewRemoteRelay(RelayConfig{Endpoint: srv.URL, SszEnabled: false, GzipEnabled: false}, nil, false)
+	relay := NewRemoteRelay(RelayConfig{Endpoint: srv.URL, SszEnabled: false, GzipEnabled: false}, nil, false, ssz.DomainBuilder, nil, 32)
 	relay.validatorsLock.RLock()
 	vd, found := relay.validatorSlotMap[123]
 	relay.validatorsLock.RUnlock()
 	require.True(t, found)
 	expectedValidator_123 := ValidatorData{
-		Pubkey:       "0x93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74a",
+		Pubkey:       PubkeyHex(hexutil.Encode(validator.Pk)),
 		FeeRecipient: bellatrix.ExecutionAddress{0xab, 0xcf, 0x8e, 0xd, 0x4e, 0x95, 0x87, 0x36, 0x9b, 0x23, 0x1, 0xd0, 0x79, 0x3, 0x47, 0x32, 0x3, 0x2, 0xcc, 0x9},
 		GasLimit:     uint64(1),
 	}
@@ -65,59 +87,45 @@ func TestRemoteRelay(t *testing.T) {
 	require.Error(t, err)
 	require.Equal(t, vd, ValidatorData{})
 
-	validatorsRequested := make(chan struct{})
+	validatorsRequested := make(chan struct{}, 1)
 	validatorsHandler = func(w http.ResponseWriter, r *http.Request) {
-		resp := `[{
-  "slot": "155",
-  "entry": {
-    "message": {
-      "fee_recipient": "0xabcf8e0d4e9587369b2301d0790347320302cc10",
-      "gas_limit": "1",
-      "timestamp": "1",
-      "pubkey": "0x93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74a"
-    },
-    "signature": "0x1b66ac1fb663c9bc59509846d6ec05345bd908eda73e670af888da41af171505cc411d61252fb6cb3fa0017b679f8bb2305b26a285fa2737f175668d0dff91cc1b66ac1fb663c9bc59509846d6ec05345bd908eda73e670af888da41af171505"
-  }}, {
-  "slot": "156",
-  "entry": {
-    "message": {
-      "fee_recipient": "0xabcf8e0d4e9587369b2301d0790347320302cc11",
-      "gas_limit": "1",
-      "timestamp": "1",
-      "pubkey": "0x93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74a"
-    },
-    "signature": "0x1b66ac1fb663c9bc59509846d6ec05345bd908eda73e670af888da41af171505cc411d61252fb6cb3fa0017b679f8bb2305b26a285fa2737f175668d0dff91cc1b66ac1fb663c9bc59509846d6ec05345bd908eda73e670af888da41af171505"
-  }
-}]`
+		resp := relayValidatorsResponse(t, validator, map[uint64]bellatrix.ExecutionAddress{
+			155: {0xab, 0xcf, 0x8e, 0xd, 0x4e, 0x95, 0x87, 0x36, 0x9b, 0x23, 0x1, 0xd0, 0x79, 0x3, 0x47, 0x32, 0x3, 0x2, 0xcc, 0x10},
+			156: {0xab, 0xcf, 0x8e, 0xd, 0x4e, 0x95, 0x87, 0x36, 0x9b, 0x23, 0x1, 0xd0, 0x79, 0x3, 0x47, 0x32, 0x3, 0x2, 0xcc, 0x11},
+		})
 
 		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(resp))
+		w.Write(resp)
 		validatorsRequested <- struct{}{}
 	}
 
 	expectedValidator_155 := ValidatorData{
-		Pubkey:       "0x93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74a",
+		Pubkey:       PubkeyHex(hexutil.Encode(validator.Pk)),
 		FeeRecipient: bellatrix.ExecutionAddress{0xab, 0xcf, 0x8e, 0xd, 0x4e, 0x95, 0x87, 0x36, 0x9b, 0x23, 0x1, 0xd0, 0x79, 0x3, 0x47, 0x32, 0x3, 0x2, 0xcc, 0x10},
 		GasLimit:     uint64(1),
 	}
 
 	expectedValidator_156 := ValidatorData{
-		Pubkey:       "0x93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74a",
+		Pubkey:       PubkeyHex(hexutil.Encode(validator.Pk)),
 		FeeRecipient: bellatrix.ExecutionAddress{0xab, 0xcf, 0x8e, 0xd, 0x4e, 0x95, 0x87, 0x36, 0x9b, 0x23, 0x1, 0xd0, 0x79, 0x3, 0x47, 0x32, 0x3, 0x2, 0xcc, 0x11},
 		GasLimit:     uint64(1),
 	}
 
+	// lookups never wait for the relay, the registrations are refreshed in the background once started
 	vd, err = relay.GetValidatorForSlot(155)
 	require.NoError(t, err)
 	require.Equal(t, expectedValidator_155, vd)
 
+	require.NoError(t, relay.Start())
+	defer relay.Stop()
+
 	select {
 	case <-validatorsRequested:
-		relay.validatorsLock.RLock()
-		for i := 0; i < 10 && relay.lastRequestedSlot != 155; i++ {
-			time.Sleep(time.Millisecond)
-		}
-		relay.validatorsLock.RUnlock()
+		require.Eventually(t, func() bool {
+			relay.validatorsLock.RLock()
+			defer relay.validatorsLock.RUnlock()
+			return relay.lastRequestedSlot == 155
+		}, time.Second, time.Millisecond)
 	case <-time.After(time.Second):
 		t.Error("timeout waiting for validator registration request")
 	}
@@ -126,3 +134,194 @@ func TestRemoteRelay(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, expectedValidator_156, vd)
 }
+
+func TestRemoteRelayValidatorsTimeout(t *testing.T) {
+	var (
+		hang = make(chan struct{})
+		hung atomic.Bool
+	)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/relay/v1/builder/validators", func(w http.ResponseWriter, r *http.Request) {
+		if hung.Load() {
+			<-hang
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("[]"))
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	defer close(hang)
+
+	relay := NewRemoteRelay(RelayConfig{Endpoint: srv.URL, ValidatorsTimeout: 50 * time.Millisecond}, nil, false, ssz.DomainBuilder, nil, 32)
+
+	// a hung relay does not keep the validator sync ongoing
+	hung.Store(true)
+	start := time.Now()
+	require.Error(t, relay.updateValidatorsMap(32, 0))
+	require.Less(t, time.Since(start), time.Second)
+
+	hung.Store(false)
+	require.NoError(t, relay.updateValidatorsMap(32, 0))
+}
+
+func TestValidatorsRefreshDue(t *testing.T) {
+	require.True(t, validatorsRefreshDue(0, 5, 32))
+	require.False(t, validatorsRefreshDue(32, 47, 32))
+	require.True(t, validatorsRefreshDue(32, 48, 32))
+	require.False(t, validatorsRefreshDue(48, 63, 32))
+	require.True(t, validatorsRefreshDue(63, 64, 32))
+	require.True(t, validatorsRefreshDue(1, 2, 1))
+}
+
+func TestRemoteRelaySubmitBlockErrors(t *testing.T) {
+	r := mux.NewRouter()
+	var blocksHandler func(w http.ResponseWriter, r *http.Request)
+	r.HandleFunc("/relay/v1/builder/validators", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("[]")) })
+	r.HandleFunc("/relay/v1/builder/blocks", func(w http.ResponseWriter, r *http.Request) { blocksHandler(w, r) })
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	relay := NewRemoteRelay(RelayConfig{Endpoint: srv.URL}, nil, false, ssz.DomainBuilder, nil, 32)
+
+	msg := &builderapi.VersionedSubmitBlockRequest{Version: consensusspec.DataVersionBellatrix, Bellatrix: &bellatrixapi.SubmitBlockRequest{}}
+
+	testCases := []struct {
+		statusCode int
+		body       string
+		expected   error
+	}{
+		{http.StatusBadRequest, `{"code":400,"message":"payload for this slot was already delivered"}`, ErrRelayPayloadAlreadyDelivered},
+		{http.StatusBadRequest, `{"code":400,"message":"simulation failed: unknown ancestor"}`, ErrRelaySimulationFailed},
+		{http.StatusBadRequest, `{"code":400,"message":"bid value too low"}`, ErrRelayBidTooLow},
+		{http.StatusTooManyRequests, `too many requests`, ErrRelayRateLimited},
+	}
+	for _, tc := range testCases {
+		blocksHandler = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.statusCode)
+			w.Write([]byte(tc.body))
+		}
+
+		err := relay.SubmitBlock(context.Background(), msg, ValidatorData{})
+		require.ErrorIs(t, err, tc.expected)
+		require.ErrorIs(t, err, errHTTPErrorResponse)
+	}
+
+	blocksHandler = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"code":500,"message":"internal error"}`))
+	}
+	err := relay.SubmitBlock(context.Background(), msg, ValidatorData{})
+	var errResp *HTTPErrorResponse
+	require.ErrorAs(t, err, &errResp)
+	require.Equal(t, http.StatusInternalServerError, errResp.StatusCode)
+	require.Equal(t, "internal error", errResp.Message)
+	require.NotErrorIs(t, err, ErrRelayRateLimited)
+
+	blocksHandler = func(w http.ResponseWriter, r *http.Request) {}
+	require.NoError(t, relay.SubmitBlock(context.Background(), msg, ValidatorData{}))
+}
+
+func TestRemoteRelaySubmitBlockRetries(t *testing.T) {
+	r := mux.NewRouter()
+	var (
+		attempts      atomic.Int32
+		blocksHandler func(w http.ResponseWriter, r *http.Request)
+	)
+	r.HandleFunc("/relay/v1/builder/validators", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("[]")) })
+	r.HandleFunc("/relay/v1/builder/blocks", func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		blocksHandler(w, r)
+	})
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	relay := NewRemoteRelay(RelayConfig{Endpoint: srv.URL, Timeout: 100 * time.Millisecond, Retries: 2, RetryBackoff: time.Millisecond}, nil, false, ssz.DomainBuilder, nil, 32)
+
+	msg := &builderapi.VersionedSubmitBlockRequest{Version: consensusspec.DataVersionBellatrix, Bellatrix: &bellatrixapi.SubmitBlockRequest{}}
+
+	// server errors are retried
+	blocksHandler = func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Load() < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}
+	require.NoError(t, relay.SubmitBlock(context.Background(), msg, ValidatorData{}))
+	require.Equal(t, int32(3), attempts.Load())
+
+	// timed out attempts are retried up to the configured number of retries
+	attempts.Store(0)
+	blocksHandler = func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}
+	require.ErrorIs(t, relay.SubmitBlock(context.Background(), msg, ValidatorData{}), context.DeadlineExceeded)
+	require.Equal(t, int32(3), attempts.Load())
+
+	// relay rejections are final
+	attempts.Store(0)
+	blocksHandler = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":400,"message":"simulation failed: unknown ancestor"}`))
+	}
+	require.ErrorIs(t, relay.SubmitBlock(context.Background(), msg, ValidatorData{}), ErrRelaySimulationFailed)
+	require.Equal(t, int32(1), attempts.Load())
+
+	// submissions for abandoned slots are not sent
+	attempts.Store(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, relay.SubmitBlock(ctx, msg, ValidatorData{}), context.Canceled)
+	require.Equal(t, int32(0), attempts.Load())
+}
+
+func TestRemoteRelayValidatorRegistrationChecks(t *testing.T) {
+	validator := NewRandomValidator()
+	proposer := NewRandomValidator()
+
+	r := mux.NewRouter()
+	var validatorsResponse []byte
+	r.HandleFunc("/relay/v1/builder/validators", func(w http.ResponseWriter, r *http.Request) { w.Write(validatorsResponse) })
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	validatorsResponse = relayValidatorsResponse(t, validator, map[uint64]bellatrix.ExecutionAddress{1: {0x01}, 2: {0x02}})
+
+	// registrations signed for another domain are rejected
+	relay := NewRemoteRelay(RelayConfig{Endpoint: srv.URL}, nil, false, phase0.Domain{0x01}, nil, 32)
+	validators, err := relay.getSlotValidatorMapFromRelay()
+	require.NoError(t, err)
+	require.Empty(t, validators)
+
+	// tampered registrations are rejected
+	relay = NewRemoteRelay(RelayConfig{Endpoint: srv.URL}, nil, false, ssz.DomainBuilder, nil, 32)
+	validatorsResponse = bytes.Replace(validatorsResponse, []byte(`"gas_limit":"1"`), []byte(`"gas_limit":"2"`), 1)
+	validators, err = relay.getSlotValidatorMapFromRelay()
+	require.NoError(t, err)
+	require.Len(t, validators, 1)
+
+	// registrations of validators other than the proposer are rejected
+	relay = NewRemoteRelay(RelayConfig{Endpoint: srv.URL}, nil, false, ssz.DomainBuilder, &testBeaconClient{validator: proposer}, 32)
+	validatorsResponse = relayValidatorsResponse(t, validator, map[uint64]bellatrix.ExecutionAddress{1: {0x01}})
+	validators, err = relay.getSlotValidatorMapFromRelay()
+	require.NoError(t, err)
+	require.Empty(t, validators)
+
+	validatorsResponse = relayValidatorsResponse(t, proposer, map[uint64]bellatrix.ExecutionAddress{1: {0x01}})
+	validators, err = relay.getSlotValidatorMapFromRelay()
+	require.NoError(t, err)
+	require.Equal(t, ValidatorData{
+		Pubkey:       PubkeyHex(hexutil.Encode(proposer.Pk)),
+		FeeRecipient: bellatrix.ExecutionAddress{0x01},
+		GasLimit:     1,
+	}, validators[1])
+
+	// registrations for slots whose duties are not known yet are kept
+	beaconClient := NewBeaconClient("http://node", 32, 12, false)
+	beaconClient.slotProposerMap[1] = proposerDuty{Pubkey: PubkeyHex(hexutil.Encode(proposer.Pk))}
+	relay = NewRemoteRelay(RelayConfig{Endpoint: srv.URL}, nil, false, ssz.DomainBuilder, beaconClient, 32)
+	validatorsResponse = relayValidatorsResponse(t, validator, map[uint64]bellatrix.ExecutionAddress{1: {0x01}, 2: {0x02}})
+	validators, err = relay.getSlotValidatorMapFromRelay()
+	require.NoError(t, err)
+	require.Len(t, validators, 1)
+	require.Equal(t, bellatrix.ExecutionAddress{0x02}, validators[2].FeeRecipient)
+}
@@ -8,74 +8,139 @@ import (
 	"golang.org/x/time/rate"
 )
 
-// runResubmitLoop checks for update signal and calls submit respecting provided rate limiter and context
-func runResubmitLoop(ctx context.Context, limiter *rate.Limiter, updateSignal <-chan struct{}, submit func(), submitTime time.Time) {
-	if submitTime.IsZero() {
-		log.Warn("skipping resubmit loop - zero submit time found")
+// runResubmitLoop checks for update signal and calls submit following the submission schedule of the slot and
+// respecting provided rate limiter and context.
+func runResubmitLoop(ctx context.Context, limiter *rate.Limiter, updateSignal <-chan struct{}, submit func(), schedule SubmissionSchedule, slotTime time.Time) {
+	if slotTime.IsZero() {
+		log.Warn("skipping resubmit loop - zero slot time found")
 		return
 	}
 
-	waitUntilSubmitTime := func(waitUntil time.Time) (ok bool, err error) {
-		now := time.Now().UTC()
-		if waitUntil.UTC().Before(now) {
-			waitUntil = now
-		}
-		sleepTime := waitUntil.UTC().Sub(now.UTC())
-		select {
-		case <-ctx.Done():
-			ok = false
-		case <-time.After(sleepTime):
-			ok = true
+	// waitUntil returns false if the context is done before the given time
+	waitUntil := func(t time.Time) bool {
+		if d := t.Sub(schedule.now()); d > 0 {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-schedule.after(d):
+			}
 		}
-		return ok && ctx.Err() == nil, ctx.Err()
+		return ctx.Err() == nil
 	}
 
-	if canContinue, err := waitUntilSubmitTime(submitTime); !canContinue {
-		log.Warn("skipping resubmit loop - cannot continue", "error", err)
+	if !waitUntil(schedule.startTime(slotTime)) {
+		log.Warn("skipping resubmit loop - cannot continue", "error", ctx.Err())
 		return
 	}
 
-	var res *rate.Reservation
+	cutoff, cutoffEnabled := schedule.cutoffTime(slotTime)
+	pastCutoff := func() bool {
+		return cutoffEnabled && !schedule.now().Before(cutoff)
+	}
+
+	var cutoffC, lastLookC <-chan time.Time
+	if cutoffEnabled {
+		cutoffC = schedule.after(cutoff.Sub(schedule.now()))
+	}
+	if lastLook, ok := schedule.lastLookTime(slotTime); ok && lastLook.After(schedule.now()) {
+		lastLookC = schedule.after(lastLook.Sub(schedule.now()))
+	}
+
+	var (
+		res            *rate.Reservation
+		lastSubmission time.Time
+	)
+
+	// submitBeforeCutoff submits unless the cutoff passed, returns false if nothing can be submitted anymore
+	submitBeforeCutoff := func() bool {
+		if pastCutoff() {
+			return false
+		}
+		submit()
+		lastSubmission = schedule.now()
+		return true
+	}
+
 	for {
+		// runBuildingJob is example caller that uses updateSignal channel via block hook that sends signal to
+		// represent submissions that increase block profit
 		select {
 		case <-ctx.Done():
 			return
-		case <-updateSignal:
-			// runBuildingJob is example caller that uses updateSignal channel via block hook that sends signal to
-			// represent submissions that increase block profit
-
-			res = limiter.Reserve()
-			if !res.OK() {
-				log.Warn("resubmit loop failed to make limiter reservation")
+		case <-cutoffC:
+			log.Debug("submission cutoff reached", "slotTime", slotTime)
+			return
+		case <-lastLookC:
+			// the last look submission of the best block is not held back by the cadence of the schedule
+			lastLookC = nil
+			if !submitBeforeCutoff() {
 				return
 			}
+			continue
+		case <-updateSignal:
+		}
+
+		// wait out the minimum interval between submissions of the schedule
+		if !lastSubmission.IsZero() && !waitUntil(lastSubmission.Add(schedule.interval(slotTime, schedule.now()))) {
+			return
+		}
+
+		now := schedule.now()
+		res = limiter.ReserveN(now, 1)
+		if !res.OK() {
+			log.Warn("resubmit loop failed to make limiter reservation")
+			return
+		}
 
-			// check if we could make submission before context ctxDeadline
-			if ctxDeadline, ok := ctx.Deadline(); ok {
-				delayDeadline := time.Now().Add(res.Delay())
-				if delayDeadline.After(ctxDeadline) {
-					res.Cancel()
-					return
-				}
+		// check if we could make submission before context ctxDeadline
+		delay := res.DelayFrom(now)
+		if ctxDeadline, ok := ctx.Deadline(); ok {
+			delayDeadline := now.Add(delay)
+			if delayDeadline.After(ctxDeadline) {
+				res.CancelAt(now)
+				return
 			}
+		}
 
-			delay := res.Delay()
-			if delay == 0 {
-				submit()
-				continue
+		if delay == 0 {
+			if !submitBeforeCutoff() {
+				return
 			}
+			continue
+		}
 
-			t := time.NewTimer(delay)
-			select {
-			case <-t.C:
-				submit()
-				continue
-			case <-ctx.Done():
-				res.Cancel()
-				t.Stop()
+		select {
+		case <-schedule.after(delay):
+			if !submitBeforeCutoff() {
 				return
 			}
+		case <-ctx.Done():
+			res.CancelAt(schedule.now())
+			return
+		}
+	}
+}
+
+// runBuildLoop calls build when changed or bidChanged is signalled, waiting at least interval between two builds and respecting
+// context cancellation
+func runBuildLoop(ctx context.Context, interval time.Duration, changed, bidChanged <-chan struct{}, build func()) {
+	t := time.NewTimer(0)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		case <-bidChanged:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
 		}
+		build()
+		t.Reset(interval)
 	}
 }
 
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/require"
 	"golang.org/x/time/rate"
 )
 
@@ -43,7 +44,7 @@ func TestResubmitUtils(t *testing.T) {
 			subAll = append(subAll, submission{time.Now(), subBest})
 			subLast = subBest
 		}
-	}, time.Now())
+	}, SubmissionSchedule{}, time.Now())
 
 	runRetryLoop(ctx, resubmitInterval, func() {
 		subMu.Lock()
@@ -74,3 +75,39 @@ func TestResubmitUtils(t *testing.T) {
 		}
 	}
 }
+
+func TestRunBuildLoop(t *testing.T) {
+	const interval = 100 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changed := make(chan struct{}, 1)
+	builds := make(chan time.Time, 10)
+	go runBuildLoop(ctx, interval, changed, nil, func() {
+		builds <- time.Now()
+	})
+
+	// nothing is built until the orders change
+	select {
+	case <-builds:
+		t.Fatal("built without changes")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	changed <- struct{}{}
+	first := <-builds
+
+	// changes right after a build are picked up once the interval passed
+	changed <- struct{}{}
+	second := <-builds
+	require.GreaterOrEqual(t, second.Sub(first), interval)
+
+	cancel()
+	changed <- struct{}{}
+	select {
+	case <-builds:
+		t.Fatal("built after cancellation")
+	case <-time.After(2 * interval):
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/attestantio/go-eth2-client/spec/phase0"
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -21,6 +22,7 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/flashbots/go-boost-utils/bls"
 	"github.com/flashbots/go-boost-utils/ssz"
+	"github.com/flashbots/go-boost-utils/utils"
 	"github.com/flashbots/go-utils/httplogger"
 	"github.com/gorilla/mux"
 	"golang.org/x/time/rate"
@@ -34,8 +36,10 @@ const (
 )
 
 type Service struct {
-	srv     *http.Server
-	builder IBuilder
+	srv          *http.Server
+	builder      IBuilder
+	beaconClient IBeaconClient
+	bidObserver  *BidObserver
 }
 
 func (s *Service) Start() error {
@@ -61,6 +65,70 @@ func (s *Service) PayloadAttributes(payloadAttributes *types.BuilderPayloadAttri
 	return s.builder.OnPayloadAttribute(payloadAttributes)
 }
 
+// BeaconNodes returns the health of the beacon nodes followed by the builder
+func (s *Service) BeaconNodes() ([]BeaconNodeStatus, error) {
+	m, ok := s.beaconClient.(*MultiBeaconClient)
+	if !ok {
+		return nil, errors.New("no beacon nodes configured")
+	}
+	return m.NodesStatus(), nil
+}
+
+// GetSlotBids returns the competing bids observed on the relays for one of the recent slots
+func (s *Service) GetSlotBids(slot uint64) ([]builderapi.ObservedBid, error) {
+	if s.bidObserver == nil {
+		return nil, errors.New("bid observer is not enabled")
+	}
+	return s.bidObserver.SlotBids(slot), nil
+}
+
+var errBuilderStatusUnsupported = errors.New("builder does not report its status")
+
+// IBuilderStatus is implemented by builders reporting their state and the blocks sealed for the recent slots
+type IBuilderStatus interface {
+	Status() BuilderStatus
+	SlotHistory(slot uint64) []SealedBlockRecord
+	BestBlock() *SealedBlockRecord
+	Payload(blockHash common.Hash) *CachedPayload
+}
+
+// Status returns the current slot, the heads being built on and the health of the relays
+func (s *Service) Status() (*BuilderStatus, error) {
+	b, ok := s.builder.(IBuilderStatus)
+	if !ok {
+		return nil, errBuilderStatusUnsupported
+	}
+	status := b.Status()
+	return &status, nil
+}
+
+// GetSlotHistory returns the blocks sealed for one of the recent slots and the outcome of their submissions
+func (s *Service) GetSlotHistory(slot uint64) ([]SealedBlockRecord, error) {
+	b, ok := s.builder.(IBuilderStatus)
+	if !ok {
+		return nil, errBuilderStatusUnsupported
+	}
+	return b.SlotHistory(slot), nil
+}
+
+// GetBestBlock returns the most valuable block sealed for the latest slot, null if none was sealed
+func (s *Service) GetBestBlock() (*SealedBlockRecord, error) {
+	b, ok := s.builder.(IBuilderStatus)
+	if !ok {
+		return nil, errBuilderStatusUnsupported
+	}
+	return b.BestBlock(), nil
+}
+
+// GetPayload returns the payload of one of the blocks sealed for the recent slots, null if it is not cached
+func (s *Service) GetPayload(blockHash common.Hash) (*CachedPayload, error) {
+	b, ok := s.builder.(IBuilderStatus)
+	if !ok {
+		return nil, errBuilderStatusUnsupported
+	}
+	return b.Payload(blockHash), nil
+}
+
 func getRouter(localRelay *LocalRelay) http.Handler {
 	router := mux.NewRouter()
 
@@ -82,32 +150,47 @@ func getRelayConfig(endpoint string) (RelayConfig, error) {
 		return RelayConfig{}, fmt.Errorf("empty relay endpoint %s", endpoint)
 	}
 	relayUrl := configs[0]
-	// relay endpoint is configurated in the format URL;ssz=<value>;gzip=<value>
-	// if any of them are missing, we default the config value to false
-	var sszEnabled, gzipEnabled bool
+	// relay endpoint is configurated in the format
+	// URL;ssz=<value>;gzip=<value>;timeout=<value>;validators_timeout=<value>;retries=<value>;backoff=<value>;max_idle_conns=<value>;http2=<value>;rate_limit=<value>
+	// if any of them are missing, ssz and gzip default to false, http2 to true and the rest to the relay defaults
+	relayConfig := RelayConfig{Endpoint: relayUrl}
 	var err error
 
-	for _, config := range configs {
-		if strings.HasPrefix(config, "ssz=") {
-			sszEnabled, err = strconv.ParseBool(config[4:])
-			if err != nil {
-				log.Info("invalid ssz config for relay", "endpoint", endpoint, "err", err)
-			}
-		} else if strings.HasPrefix(config, "gzip=") {
-			gzipEnabled, err = strconv.ParseBool(config[5:])
-			if err != nil {
-				log.Info("invalid gzip config for relay", "endpoint", endpoint, "err", err)
-			}
+	for _, config := range configs[1:] {
+		key, value, _ := strings.Cut(config, "=")
+		switch key {
+		case "ssz":
+			relayConfig.SszEnabled, err = strconv.ParseBool(value)
+		case "gzip":
+			relayConfig.GzipEnabled, err = strconv.ParseBool(value)
+		case "timeout":
+			relayConfig.Timeout, err = time.ParseDuration(value)
+		case "validators_timeout":
+			relayConfig.ValidatorsTimeout, err = time.ParseDuration(value)
+		case "retries":
+			relayConfig.Retries, err = strconv.Atoi(value)
+		case "backoff":
+			relayConfig.RetryBackoff, err = time.ParseDuration(value)
+		case "rate_limit":
+			relayConfig.RateLimitInterval, err = time.ParseDuration(value)
+		case "max_idle_conns":
+			relayConfig.MaxIdleConns, err = strconv.Atoi(value)
+		case "http2":
+			var http2Enabled bool
+			http2Enabled, err = strconv.ParseBool(value)
+			relayConfig.DisableHTTP2 = err == nil && !http2Enabled
+		default:
+			log.Info("unknown config for relay", "endpoint", endpoint, "config", config)
+			continue
+		}
+		if err != nil {
+			log.Info("invalid config for relay", "endpoint", endpoint, "config", key, "err", err)
 		}
 	}
-	return RelayConfig{
-		Endpoint:    relayUrl,
-		SszEnabled:  sszEnabled,
-		GzipEnabled: gzipEnabled,
-	}, nil
+	return relayConfig.withDefaults(), nil
 }
 
-func NewService(listenAddr string, localRelay *LocalRelay, builder IBuilder) *Service {
+func NewService(listenAddr string, localRelay *LocalRelay, builder IBuilder, beaconClient IBeaconClient, bidObserver *BidObserver) *Service {
 	var srv *http.Server
 	if localRelay != nil {
 		srv = &http.Server{
@@ -123,8 +206,10 @@ func NewService(listenAddr string, localRelay *LocalRelay, builder IBuilder) *Se
 	}
 
 	return &Service{
-		srv:     srv,
-		builder: builder,
+		srv:          srv,
+		builder:      builder,
+		beaconClient: beaconClient,
+		bidObserver:  bidObserver,
 	}
 }
 
@@ -143,23 +228,26 @@ func Register(stack *node.Node, backend *eth.Ethereum, cfg *Config) error {
 	copy(genesisForkVersion[:], genesisForkVersionBytes[:4])
 	builderSigningDomain := ssz.ComputeDomain(ssz.DomainTypeAppBuilder, genesisForkVersion, phase0.Root{})
 
-	genesisValidatorsRoot := phase0.Root(common.HexToHash(cfg.GenesisValidatorsRoot))
-	bellatrixForkVersionBytes, err := hexutil.Decode(cfg.BellatrixForkVersion)
+	forkData := ForkData{cfg.GenesisForkVersion, cfg.BellatrixForkVersion, cfg.CapellaForkVersion, cfg.GenesisValidatorsRoot}
+	proposerSigningDomains, err := NewProposerSigningDomains(forkData)
 	if err != nil {
-		return fmt.Errorf("invalid bellatrixForkVersion: %w", err)
+		return err
 	}
 
-	var bellatrixForkVersion [4]byte
-	copy(bellatrixForkVersion[:], bellatrixForkVersionBytes[:4])
-	proposerSigningDomain := ssz.ComputeDomain(ssz.DomainTypeBeaconProposer, bellatrixForkVersion, genesisValidatorsRoot)
-
+	// a single beacon node is followed through the MultiBeaconClient too for the deduplication and health tracking,
+	// the active validators are only needed by the local relay to check registrations
 	var beaconClient IBeaconClient
 	if len(cfg.BeaconEndpoints) == 0 {
 		beaconClient = &NilBeaconClient{}
-	} else if len(cfg.BeaconEndpoints) == 1 {
-		beaconClient = NewBeaconClient(cfg.BeaconEndpoints[0], cfg.SlotsInEpoch, cfg.SecondsInSlot)
 	} else {
-		beaconClient = NewMultiBeaconClient(cfg.BeaconEndpoints, cfg.SlotsInEpoch, cfg.SecondsInSlot)
+		trackValidators := cfg.EnableLocalRelay && cfg.EnableValidatorChecks
+		beaconClient = NewMultiBeaconClient(cfg.BeaconEndpoints, cfg.SlotsInEpoch, cfg.SecondsInSlot, cfg.BeaconQuorum, trackValidators)
+	}
+
+	// registrations served by remote relays are only checked against the proposer duties with validator checks enabled
+	var dutiesClient IBeaconClient
+	if cfg.EnableValidatorChecks {
+		dutiesClient = beaconClient
 	}
 
 	var localRelay *LocalRelay
@@ -174,34 +262,42 @@ func Register(stack *node.Node, backend *eth.Ethereum, cfg *Config) error {
 			return errors.New("incorrect builder API secret key provided")
 		}
 
-		localRelay, err = NewLocalRelay(relaySk, beaconClient, builderSigningDomain, proposerSigningDomain, ForkData{cfg.GenesisForkVersion, cfg.BellatrixForkVersion, cfg.GenesisValidatorsRoot}, cfg.EnableValidatorChecks)
+		localRelay, err = NewLocalRelay(relaySk, beaconClient, builderSigningDomain, proposerSigningDomains, forkData, cfg.EnableValidatorChecks)
 		if err != nil {
 			return fmt.Errorf("failed to create local relay: %w", err)
 		}
 	}
 
+	// remote relays are the ones polled for competing bids
+	var remoteRelays []RelayConfig
 	var relay IRelay
 	if cfg.RemoteRelayEndpoint != "" {
 		relayConfig, err := getRelayConfig(cfg.RemoteRelayEndpoint)
 		if err != nil {
 			return fmt.Errorf("invalid remote relay endpoint: %w", err)
 		}
-		relay = NewRemoteRelay(relayConfig, localRelay, cfg.EnableCancellations)
+		remoteRelays = append(remoteRelays, relayConfig)
+		relay = NewRemoteRelay(relayConfig, localRelay, cfg.EnableCancellations, builderSigningDomain, dutiesClient, cfg.SlotsInEpoch)
 	} else if localRelay != nil {
 		relay = localRelay
 	} else {
 		return errors.New("neither local nor remote relay specified")
 	}
 
+	// remote relays are submitted to through the aggregator, it rate limits and backs off every relay on its own
+	var secondaryRelays []IRelay
 	if len(cfg.SecondaryRemoteRelayEndpoints) > 0 && !(len(cfg.SecondaryRemoteRelayEndpoints) == 1 && cfg.SecondaryRemoteRelayEndpoints[0] == "") {
-		secondaryRelays := make([]IRelay, len(cfg.SecondaryRemoteRelayEndpoints))
+		secondaryRelays = make([]IRelay, len(cfg.SecondaryRemoteRelayEndpoints))
 		for i, endpoint := range cfg.SecondaryRemoteRelayEndpoints {
 			relayConfig, err := getRelayConfig(endpoint)
 			if err != nil {
 				return fmt.Errorf("invalid secondary remote relay endpoint: %w", err)
 			}
-			secondaryRelays[i] = NewRemoteRelay(relayConfig, nil, cfg.EnableCancellations)
+			remoteRelays = append(remoteRelays, relayConfig)
+			secondaryRelays[i] = NewRemoteRelay(relayConfig, nil, cfg.EnableCancellations, builderSigningDomain, dutiesClient, cfg.SlotsInEpoch)
 		}
+	}
+	if len(remoteRelays) > 0 {
 		relay = NewRemoteRelayAggregator(relay, secondaryRelays)
 	}
 
@@ -217,6 +313,14 @@ func Register(stack *node.Node, backend *eth.Ethereum, cfg *Config) error {
 		validator = blockvalidation.NewBlockValidationAPI(backend, accessVerifier, cfg.ValidationUseCoinbaseDiff)
 	}
 
+	var shadowLog *ShadowLog
+	if cfg.DryRun && cfg.DryRunLog != "" {
+		shadowLog, err = OpenShadowLog(cfg.DryRunLog)
+		if err != nil {
+			return fmt.Errorf("failed to open dry run log %w", err)
+		}
+	}
+
 	// Set up builder rate limiter based on environment variables or CLI flags.
 	// Builder rate limit parameters are flags.BuilderRateLimitDuration and flags.BuilderRateLimitMaxBurst
 	duration, err := time.ParseDuration(cfg.BuilderRateLimitDuration)
@@ -238,16 +342,25 @@ func Register(stack *node.Node, backend *eth.Ethereum, cfg *Config) error {
 		builderRateLimitInterval = RateLimitIntervalDefault
 	}
 
-	var submissionOffset time.Duration
+	// unset phases of the slot timing are derived from the slot duration
+	var slotTiming SlotTiming
 	if offset := cfg.BuilderSubmissionOffset; offset != 0 {
 		if offset < 0 {
 			return fmt.Errorf("builder submission offset must be positive")
 		} else if uint64(offset.Seconds()) > cfg.SecondsInSlot {
 			return fmt.Errorf("builder submission offset must be less than seconds in slot")
 		}
-		submissionOffset = offset
-	} else {
-		submissionOffset = SubmissionOffsetFromEndOfSlotSecondsDefault
+		slotTiming.SubmissionOffset = offset
+	}
+	slotTiming, err = parseSlotTiming(slotTiming, cfg.SlotTiming)
+	if err != nil {
+		return err
+	}
+	slotTiming = slotTiming.withDefaults(cfg.SecondsInSlot)
+
+	submissionSchedule, err := parseSubmissionSchedule(SubmissionSchedule{Start: slotTiming.SubmissionOffset}, cfg.BuilderSubmissionSchedule)
+	if err != nil {
+		return err
 	}
 
 	// TODO: move to proper flags
@@ -273,34 +386,62 @@ func Register(stack *node.Node, backend *eth.Ethereum, cfg *Config) error {
 		go bundleFetcher.Run()
 	}
 
-	ethereumService := NewEthereumService(backend)
+	ethereumService := NewEthereumService(backend, slotTiming.BuildTimeout)
 
 	builderSk, err := bls.SecretKeyFromBytes(envBuilderSkBytes[:])
 	if err != nil {
 		return errors.New("incorrect builder API secret key provided")
 	}
 
+	var bidObserver *BidObserver
+	if cfg.ObserveBids {
+		if len(remoteRelays) == 0 {
+			return errors.New("observing bids requires remote relays")
+		}
+		builderPk, err := bls.PublicKeyFromSecretKey(builderSk)
+		if err != nil {
+			return err
+		}
+		builderPubkey, err := utils.BlsPublicKeyToPublicKey(builderPk)
+		if err != nil {
+			return err
+		}
+		bidObserver = NewBidObserver(remoteRelays, builderPubkey, ds, BidObserverPollIntervalDefault)
+	}
+
+	bidStrategy, err := parseBidStrategy(cfg.BidStrategy)
+	if err != nil {
+		return err
+	}
+
 	builderArgs := BuilderArgs{
-		sk:                            builderSk,
-		ds:                            ds,
-		dryRun:                        cfg.DryRun,
-		eth:                           ethereumService,
-		relay:                         relay,
-		builderSigningDomain:          builderSigningDomain,
-		builderBlockResubmitInterval:  builderRateLimitInterval,
-		submissionOffsetFromEndOfSlot: submissionOffset,
-		discardRevertibleTxOnErr:      cfg.DiscardRevertibleTxOnErr,
-		ignoreLatePayloadAttributes:   cfg.IgnoreLatePayloadAttributes,
-		validator:                     validator,
-		beaconClient:                  beaconClient,
-		limiter:                       limiter,
+		sk:                           builderSk,
+		ds:                           ds,
+		dryRun:                       cfg.DryRun,
+		eth:                          ethereumService,
+		relay:                        relay,
+		builderSigningDomain:         builderSigningDomain,
+		builderBlockResubmitInterval: builderRateLimitInterval,
+		builderBlockRebuildInterval:  cfg.BuilderRebuildInterval,
+		slotTiming:                   slotTiming,
+		submissionSchedule:           submissionSchedule,
+		maxHeads:                     cfg.MaxHeads,
+		secondsInSlot:                cfg.SecondsInSlot,
+		bidStrategy:                  bidStrategy,
+		bidObserver:                  bidObserver,
+		discardRevertibleTxOnErr:     cfg.DiscardRevertibleTxOnErr,
+		ignoreLatePayloadAttributes:  cfg.IgnoreLatePayloadAttributes,
+		validator:                    validator,
+		shadowLog:                    shadowLog,
+		beaconClient:                 beaconClient,
+		limiter:                      limiter,
 	}
 
 	builderBackend, err := NewBuilder(builderArgs)
 	if err != nil {
 		return fmt.Errorf("failed to create builder backend: %w", err)
 	}
-	builderService := NewService(cfg.ListenAddr, localRelay, builderBackend)
+	builderService := NewService(cfg.ListenAddr, localRelay, builderBackend, beaconClient, bidObserver)
 
 	stack.RegisterAPIs([]rpc.API{
 		{
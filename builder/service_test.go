@@ -0,0 +1,42 @@
+package builder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRelayConfig(t *testing.T) {
+	config, err := getRelayConfig("http://relay.example")
+	require.NoError(t, err)
+	require.Equal(t, RelayConfig{
+		Endpoint:          "http://relay.example",
+		Timeout:           RelayTimeoutDefault,
+		ValidatorsTimeout: RelayValidatorsTimeoutDefault,
+		RetryBackoff:      RelayRetryBackoffDefault,
+		MaxIdleConns:      RelayMaxIdleConnsDefault,
+	}, config)
+
+	config, err = getRelayConfig("http://relay.example;ssz=true;gzip=true;timeout=500ms;validators_timeout=5s;retries=3;backoff=50ms;max_idle_conns=32;http2=false;rate_limit=250ms")
+	require.NoError(t, err)
+	require.Equal(t, RelayConfig{
+		Endpoint:          "http://relay.example",
+		SszEnabled:        true,
+		GzipEnabled:       true,
+		Timeout:           500 * time.Millisecond,
+		ValidatorsTimeout: 5 * time.Second,
+		Retries:           3,
+		RetryBackoff:      50 * time.Millisecond,
+		MaxIdleConns:      32,
+		DisableHTTP2:      true,
+		RateLimitInterval: 250 * time.Millisecond,
+	}, config)
+
+	// invalid values fall back to the defaults
+	config, err = getRelayConfig("http://relay.example;timeout=soon;retries=-1;http2=maybe;unknown=1")
+	require.NoError(t, err)
+	require.Equal(t, RelayTimeoutDefault, config.Timeout)
+	require.Equal(t, 0, config.Retries)
+	require.False(t, config.DisableHTTP2)
+}
@@ -0,0 +1,158 @@
+package builder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	bellatrixapi "github.com/attestantio/go-builder-client/api/bellatrix"
+	capellaapi "github.com/attestantio/go-builder-client/api/capella"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ShadowSubmission is a block submission the builder would have sent to the relays in dry run mode, along with the
+// outcome of its local validation
+type ShadowSubmission struct {
+	Slot    uint64 `json:"slot"`
+	Version string `json:"version"`
+	// Submission is the signed bid and the payload in the relay API format of the version
+	Submission         json.RawMessage `json:"submission"`
+	RegisteredGasLimit uint64          `json:"registeredGasLimit"`
+	// ProposerPayment is the proposer payment simulated while building the block
+	ProposerPayment *big.Int `json:"proposerPayment"`
+	ValidationError string   `json:"validationError,omitempty"`
+
+	OrdersClosedAt    time.Time     `json:"ordersClosedAt"`
+	SealedAt          time.Time     `json:"sealedAt"`
+	ValidatedAt       time.Time     `json:"validatedAt"`
+	ValidationLatency time.Duration `json:"validationLatency"`
+}
+
+func newShadowSubmission(req *builderapi.VersionedSubmitBlockRequest, slot, registeredGasLimit uint64, proposerPayment *big.Int) (*ShadowSubmission, error) {
+	if req.Version != consensusspec.DataVersionBellatrix && req.Version != consensusspec.DataVersionCapella {
+		// the validation API has no method for deneb submissions, they could not be replayed
+		return nil, fmt.Errorf("%w: %s submissions are not recorded", builderapi.ErrUnsupportedVersion, req.Version)
+	}
+	submission, err := req.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return &ShadowSubmission{
+		Slot:               slot,
+		Version:            req.Version.String(),
+		Submission:         submission,
+		RegisteredGasLimit: registeredGasLimit,
+		ProposerPayment:    new(big.Int).Set(proposerPayment),
+	}, nil
+}
+
+// Request decodes the recorded submission
+func (s *ShadowSubmission) Request() (*builderapi.VersionedSubmitBlockRequest, error) {
+	var (
+		req = new(builderapi.VersionedSubmitBlockRequest)
+		err error
+	)
+	switch s.Version {
+	case consensusspec.DataVersionBellatrix.String():
+		req.Version, req.Bellatrix = consensusspec.DataVersionBellatrix, new(bellatrixapi.SubmitBlockRequest)
+		err = json.Unmarshal(s.Submission, req.Bellatrix)
+	case consensusspec.DataVersionCapella.String():
+		req.Version, req.Capella = consensusspec.DataVersionCapella, new(capellaapi.SubmitBlockRequest)
+		err = json.Unmarshal(s.Submission, req.Capella)
+	default:
+		return nil, fmt.Errorf("%w: %s", builderapi.ErrUnsupportedVersion, s.Version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s submission: %w", s.Version, err)
+	}
+	return req, nil
+}
+
+// ShadowLog appends the dry run submissions to a file, one JSON encoded ShadowSubmission per line
+type ShadowLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenShadowLog opens the shadow log at path, submissions are appended to the ones already recorded
+func OpenShadowLog(path string) (*ShadowLog, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &ShadowLog{file: file}, nil
+}
+
+func (l *ShadowLog) Append(s *ShadowSubmission) error {
+	line, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.file.Write(line)
+	return err
+}
+
+func (l *ShadowLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// ReadShadowLog calls fn for every submission recorded in the shadow log until fn returns an error
+func ReadShadowLog(r io.Reader, fn func(*ShadowSubmission) error) error {
+	decoder := json.NewDecoder(r)
+	for {
+		s := new(ShadowSubmission)
+		if err := decoder.Decode(s); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+}
+
+// ValidateShadowSubmission validates the recorded submission with the flashbots block validation API of the node
+func ValidateShadowSubmission(ctx context.Context, client *rpc.Client, s *ShadowSubmission) error {
+	req, err := s.Request()
+	if err != nil {
+		return err
+	}
+
+	// The validation requests are the relay API submissions extended with the validation fields
+	params := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(s.Submission, &params); err != nil {
+		return err
+	}
+	params["registered_gas_limit"], _ = json.Marshal(fmt.Sprint(s.RegisteredGasLimit))
+
+	var method string
+	switch req.Version {
+	case consensusspec.DataVersionBellatrix:
+		method = "flashbots_validateBuilderSubmissionV1"
+	case consensusspec.DataVersionCapella:
+		block, err := req.Block()
+		if err != nil {
+			return err
+		}
+		params["withdrawals_root"], _ = json.Marshal(block.Header().WithdrawalsHash)
+		method = "flashbots_validateBuilderSubmissionV2"
+	default:
+		return fmt.Errorf("%w: no validation method for %s", builderapi.ErrUnsupportedVersion, req.Version)
+	}
+	return client.CallContext(ctx, nil, method, params)
+}
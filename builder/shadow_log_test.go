@@ -0,0 +1,117 @@
+package builder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	capellaapi "github.com/attestantio/go-builder-client/api/capella"
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
+	"github.com/ethereum/go-ethereum/beacon/engine"
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+// testValidationAPI records the validation requests and rejects the ones registered with another gas limit
+type testValidationAPI struct {
+	requests []map[string]json.RawMessage
+}
+
+func (api *testValidationAPI) ValidateBuilderSubmissionV2(params map[string]json.RawMessage) error {
+	api.requests = append(api.requests, params)
+	if string(params["registered_gas_limit"]) != `"30000000"` {
+		return errors.New("incorrect gas limit")
+	}
+	return nil
+}
+
+func testCapellaSubmission(t *testing.T) *builderapi.VersionedSubmitBlockRequest {
+	withdrawals := types.Withdrawals{{Index: 1, Validator: 2, Address: common.Address{0x03}, Amount: 4}}
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10), GasLimit: 30_000_000, BaseFee: big.NewInt(7), Extra: []byte{}}).WithWithdrawals(withdrawals)
+	payload, err := executableDataToCapellaExecutionPayload(engine.BlockToExecutableData(block, big.NewInt(10)).ExecutionPayload)
+	require.NoError(t, err)
+	return &builderapi.VersionedSubmitBlockRequest{
+		Version: consensusspec.DataVersionCapella,
+		Capella: &capellaapi.SubmitBlockRequest{
+			Message:          &apiv1.BidTrace{Slot: 1, GasLimit: 30_000_000, Value: uint256.NewInt(10)},
+			ExecutionPayload: payload,
+		},
+	}
+}
+
+func TestShadowLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dry_run.jsonl")
+	req := testCapellaSubmission(t)
+
+	shadowLog, err := OpenShadowLog(path)
+	require.NoError(t, err)
+	for i, validationError := range []string{"", "incorrect gas limit"} {
+		submission, err := newShadowSubmission(req, 1, uint64(30_000_000+i), big.NewInt(10))
+		require.NoError(t, err)
+		submission.ValidationError = validationError
+		submission.SealedAt = time.Unix(1, 0).UTC()
+		require.NoError(t, shadowLog.Append(submission))
+	}
+	require.NoError(t, shadowLog.Close())
+
+	// submissions are appended to the ones already recorded
+	shadowLog, err = OpenShadowLog(path)
+	require.NoError(t, err)
+	submission, err := newShadowSubmission(req, 2, 30_000_000, big.NewInt(10))
+	require.NoError(t, err)
+	require.NoError(t, shadowLog.Append(submission))
+	require.NoError(t, shadowLog.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var submissions []*ShadowSubmission
+	require.NoError(t, ReadShadowLog(file, func(s *ShadowSubmission) error {
+		submissions = append(submissions, s)
+		return nil
+	}))
+	require.Len(t, submissions, 3)
+	require.Equal(t, "capella", submissions[0].Version)
+	require.Equal(t, big.NewInt(10), submissions[0].ProposerPayment)
+	require.Equal(t, time.Unix(1, 0).UTC(), submissions[0].SealedAt)
+	require.Equal(t, "incorrect gas limit", submissions[1].ValidationError)
+	require.Equal(t, uint64(2), submissions[2].Slot)
+
+	decoded, err := submissions[0].Request()
+	require.NoError(t, err)
+	require.Equal(t, req, decoded)
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	api := &testValidationAPI{}
+	require.NoError(t, server.RegisterName("flashbots", api))
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	require.NoError(t, ValidateShadowSubmission(context.Background(), client, submissions[0]))
+	require.ErrorContains(t, ValidateShadowSubmission(context.Background(), client, submissions[1]), "incorrect gas limit")
+	require.Len(t, api.requests, 2)
+	block, err := req.Block()
+	require.NoError(t, err)
+	withdrawalsRoot, err := json.Marshal(block.Header().WithdrawalsHash)
+	require.NoError(t, err)
+	require.JSONEq(t, string(withdrawalsRoot), string(api.requests[0]["withdrawals_root"]))
+	require.Contains(t, api.requests[0], "execution_payload")
+
+	// deneb submissions have no validation method yet, they are not recorded
+	_, err = newShadowSubmission(&builderapi.VersionedSubmitBlockRequest{Version: consensusspec.DataVersionDeneb}, 3, 30, big.NewInt(10))
+	require.ErrorIs(t, err, builderapi.ErrUnsupportedVersion)
+	submissions[0].Version = "deneb"
+	require.ErrorIs(t, ValidateShadowSubmission(context.Background(), client, submissions[0]), builderapi.ErrUnsupportedVersion)
+}
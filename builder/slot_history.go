@@ -0,0 +1,266 @@
+package builder
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// slotHistorySlotsKept is the number of slots for which the sealed blocks are kept in memory
+const slotHistorySlotsKept = 64
+
+// SealedBlockRecord is a block sealed by a building job and the outcome of its submissions to the relays
+type SealedBlockRecord struct {
+	Slot           uint64             `json:"slot"`
+	ParentHash     common.Hash        `json:"parentHash"`
+	BlockHash      common.Hash        `json:"blockHash"`
+	BlockNumber    uint64             `json:"blockNumber"`
+	Value          *big.Int           `json:"value"`
+	Txs            int                `json:"txs"`
+	GasUsed        uint64             `json:"gasUsed"`
+	Bundles        []common.Hash      `json:"bundles"`
+	SBundles       []common.Hash      `json:"sbundles"`
+	OrdersClosedAt time.Time          `json:"ordersClosedAt"`
+	SealedAt       time.Time          `json:"sealedAt"`
+	Submissions    []SubmissionRecord `json:"submissions"`
+}
+
+// SubmissionRecord is the outcome of the submission of a block to a relay
+type SubmissionRecord struct {
+	Relay       string        `json:"relay"`
+	SubmittedAt time.Time     `json:"submittedAt"`
+	Latency     time.Duration `json:"latency"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// RelayStatus is the health of a relay as seen by the builder
+type RelayStatus struct {
+	Endpoint string `json:"endpoint"`
+	// ValidatorsSlot and ValidatorsUpdatedAt are the slot and time of the last refresh of the validator registrations
+	// served by the relay, unset for relays not serving registrations
+	ValidatorsSlot      uint64    `json:"validatorsSlot"`
+	ValidatorsUpdatedAt time.Time `json:"validatorsUpdatedAt"`
+	Validators          int       `json:"validators"`
+
+	Submissions     uint64        `json:"submissions"`
+	Failures        uint64        `json:"failures"`
+	LastSubmittedAt time.Time     `json:"lastSubmittedAt"`
+	LastLatency     time.Duration `json:"lastLatency"`
+	LastError       string        `json:"lastError,omitempty"`
+}
+
+// BuilderStatus is the state of the builder returned by the builder_status RPC method
+type BuilderStatus struct {
+	Slot uint64 `json:"slot"`
+	// Heads are the parents of the running building jobs of the slot
+	Heads  []common.Hash `json:"heads"`
+	Synced bool          `json:"synced"`
+	DryRun bool          `json:"dryRun"`
+	Relays []RelayStatus `json:"relays"`
+}
+
+// IRelayStatus is implemented by relays reporting the freshness of the validator registrations they serve
+type IRelayStatus interface {
+	RelayStatus() []RelayStatus
+}
+
+type slotHistoryEntry struct {
+	slot   uint64
+	blocks []*SealedBlockRecord
+	byHash map[common.Hash]*SealedBlockRecord
+}
+
+// SlotHistory keeps the blocks sealed for the recent slots in a ring buffer indexed by slot, and the submission
+// statistics of every relay
+type SlotHistory struct {
+	mu      sync.Mutex
+	entries []slotHistoryEntry
+	relays  map[string]*RelayStatus
+}
+
+func newSlotHistory(slotsKept int) *SlotHistory {
+	if slotsKept <= 0 {
+		slotsKept = slotHistorySlotsKept
+	}
+	return &SlotHistory{
+		entries: make([]slotHistoryEntry, slotsKept),
+		relays:  make(map[string]*RelayStatus),
+	}
+}
+
+// entry returns the entry of the slot, recycling the entry of the slot it replaces in the ring buffer. It returns nil
+// for slots older than the one kept in their place. Must be called with the lock held.
+func (h *SlotHistory) entry(slot uint64, create bool) *slotHistoryEntry {
+	e := &h.entries[slot%uint64(len(h.entries))]
+	switch {
+	case e.slot == slot && e.byHash != nil:
+		return e
+	case !create || (e.byHash != nil && e.slot > slot):
+		return nil
+	}
+	*e = slotHistoryEntry{slot: slot, byHash: make(map[common.Hash]*SealedBlockRecord)}
+	return e
+}
+
+func (h *SlotHistory) recordSealedBlock(attrs *types.BuilderPayloadAttributes, block *types.Block, blockValue *big.Int, ordersClosedAt, sealedAt time.Time,
+	commitedBundles []types.SimulatedBundle, usedSbundles []types.UsedSBundle,
+) {
+	record := &SealedBlockRecord{
+		Slot:           attrs.Slot,
+		ParentHash:     block.ParentHash(),
+		BlockHash:      block.Hash(),
+		BlockNumber:    block.NumberU64(),
+		Value:          new(big.Int).Set(blockValue),
+		Txs:            len(block.Transactions()),
+		GasUsed:        block.GasUsed(),
+		Bundles:        make([]common.Hash, 0, len(commitedBundles)),
+		SBundles:       make([]common.Hash, 0, len(usedSbundles)),
+		OrdersClosedAt: ordersClosedAt,
+		SealedAt:       sealedAt,
+	}
+	for _, bundle := range commitedBundles {
+		record.Bundles = append(record.Bundles, bundle.OriginalBundle.Hash)
+	}
+	for _, sbundle := range usedSbundles {
+		if sbundle.Success {
+			record.SBundles = append(record.SBundles, sbundle.Bundle.Hash())
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	e := h.entry(attrs.Slot, true)
+	if e == nil {
+		return
+	}
+	if _, found := e.byHash[record.BlockHash]; found {
+		return
+	}
+	e.blocks = append(e.blocks, record)
+	e.byHash[record.BlockHash] = record
+}
+
+func (h *SlotHistory) recordSubmissions(slot uint64, blockHash common.Hash, submittedAt time.Time, results []builderapi.RelaySubmissionResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var record *SealedBlockRecord
+	if e := h.entry(slot, false); e != nil {
+		record = e.byHash[blockHash]
+	}
+
+	for _, result := range results {
+		submission := SubmissionRecord{Relay: result.Relay, SubmittedAt: submittedAt, Latency: result.Latency}
+		if result.Err != nil {
+			submission.Error = result.Err.Error()
+		}
+		if record != nil {
+			record.Submissions = append(record.Submissions, submission)
+		}
+		if errors.Is(result.Err, errRelaySubmissionSuperseded) {
+			// the block was never sent to the relay
+			continue
+		}
+
+		relay, found := h.relays[result.Relay]
+		if !found {
+			relay = &RelayStatus{Endpoint: result.Relay}
+			h.relays[result.Relay] = relay
+		}
+		relay.Submissions++
+		if result.Err != nil {
+			relay.Failures++
+		}
+		relay.LastSubmittedAt = submittedAt
+		relay.LastLatency = result.Latency
+		relay.LastError = submission.Error
+	}
+}
+
+// Slot returns the blocks sealed for the slot in the order they were sealed
+func (h *SlotHistory) Slot(slot uint64) []SealedBlockRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	e := h.entry(slot, false)
+	if e == nil {
+		return []SealedBlockRecord{}
+	}
+	records := make([]SealedBlockRecord, len(e.blocks))
+	for i, record := range e.blocks {
+		records[i] = copySealedBlockRecord(record)
+	}
+	return records
+}
+
+// BestBlock returns the most valuable block sealed for the latest slot with sealed blocks, nil if there is none
+func (h *SlotHistory) BestBlock() *SealedBlockRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var latest *slotHistoryEntry
+	for i := range h.entries {
+		e := &h.entries[i]
+		if len(e.blocks) > 0 && (latest == nil || e.slot > latest.slot) {
+			latest = e
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+
+	best := latest.blocks[0]
+	for _, record := range latest.blocks[1:] {
+		if record.Value.Cmp(best.Value) > 0 {
+			best = record
+		}
+	}
+	record := copySealedBlockRecord(best)
+	return &record
+}
+
+// relayStatus merges the submission statistics of the relays into their status, relays the builder only submitted
+// to are appended in the order of their endpoints
+func (h *SlotHistory) relayStatus(status []RelayStatus) []RelayStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	known := make(map[string]struct{}, len(status))
+	for i := range status {
+		known[status[i].Endpoint] = struct{}{}
+		if relay, found := h.relays[status[i].Endpoint]; found {
+			status[i].Submissions = relay.Submissions
+			status[i].Failures = relay.Failures
+			status[i].LastSubmittedAt = relay.LastSubmittedAt
+			status[i].LastLatency = relay.LastLatency
+			status[i].LastError = relay.LastError
+		}
+	}
+
+	var submittedOnly []RelayStatus
+	for endpoint, relay := range h.relays {
+		if _, found := known[endpoint]; !found {
+			submittedOnly = append(submittedOnly, *relay)
+		}
+	}
+	sort.Slice(submittedOnly, func(i, j int) bool {
+		return submittedOnly[i].Endpoint < submittedOnly[j].Endpoint
+	})
+	return append(status, submittedOnly...)
+}
+
+func copySealedBlockRecord(record *SealedBlockRecord) SealedBlockRecord {
+	cpy := *record
+	cpy.Value = new(big.Int).Set(record.Value)
+	cpy.Bundles = append([]common.Hash{}, record.Bundles...)
+	cpy.SBundles = append([]common.Hash{}, record.SBundles...)
+	cpy.Submissions = append([]SubmissionRecord{}, record.Submissions...)
+	return cpy
+}
@@ -0,0 +1,71 @@
+package builder
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlotHistory(t *testing.T) {
+	history := newSlotHistory(4)
+	require.Nil(t, history.BestBlock())
+	require.Empty(t, history.Slot(1))
+
+	sealBlock := func(slot uint64, number int64, value int64) *types.Block {
+		block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number), ParentHash: common.Hash{0x01}})
+		bundles := []types.SimulatedBundle{{OriginalBundle: types.MevBundle{Hash: common.Hash{byte(number)}}}}
+		history.recordSealedBlock(&types.BuilderPayloadAttributes{Slot: slot}, block, big.NewInt(value), time.Unix(1, 0), time.Unix(2, 0), bundles, nil)
+		return block
+	}
+
+	low := sealBlock(1, 10, 100)
+	high := sealBlock(1, 11, 200)
+	// blocks sealed again are recorded once
+	sealBlock(1, 10, 100)
+
+	submittedAt := time.Unix(3, 0)
+	history.recordSubmissions(1, high.Hash(), submittedAt, []builderapi.RelaySubmissionResult{
+		{Relay: "http://relay-a", Latency: time.Millisecond},
+		{Relay: "http://relay-b", Latency: 2 * time.Millisecond, Err: errors.New("bid too low")},
+	})
+
+	records := history.Slot(1)
+	require.Len(t, records, 2)
+	require.Equal(t, low.Hash(), records[0].BlockHash)
+	require.Equal(t, []common.Hash{{10}}, records[0].Bundles)
+	require.Empty(t, records[0].Submissions)
+	require.Equal(t, []SubmissionRecord{
+		{Relay: "http://relay-a", SubmittedAt: submittedAt, Latency: time.Millisecond},
+		{Relay: "http://relay-b", SubmittedAt: submittedAt, Latency: 2 * time.Millisecond, Error: "bid too low"},
+	}, records[1].Submissions)
+
+	// returned records are copies
+	records[1].Value.SetInt64(0)
+	best := history.BestBlock()
+	require.Equal(t, high.Hash(), best.BlockHash)
+	require.Equal(t, big.NewInt(200), best.Value)
+
+	// the best block is taken from the latest slot
+	sealBlock(2, 12, 50)
+	require.Equal(t, big.NewInt(50), history.BestBlock().Value)
+
+	// slots are overwritten once the ring buffer wraps around, older slots are not recorded in place of newer ones
+	sealBlock(5, 13, 10)
+	require.Empty(t, history.Slot(1))
+	require.Len(t, history.Slot(5), 1)
+	sealBlock(1, 14, 10)
+	require.Empty(t, history.Slot(1))
+
+	status := history.relayStatus([]RelayStatus{{Endpoint: "http://relay-b", Validators: 3}, {Endpoint: "http://relay-c"}})
+	require.Equal(t, []RelayStatus{
+		{Endpoint: "http://relay-b", Validators: 3, Submissions: 1, Failures: 1, LastSubmittedAt: submittedAt, LastLatency: 2 * time.Millisecond, LastError: "bid too low"},
+		{Endpoint: "http://relay-c"},
+		{Endpoint: "http://relay-a", Submissions: 1, LastSubmittedAt: submittedAt, LastLatency: time.Millisecond},
+	}, status)
+}
@@ -0,0 +1,71 @@
+package builder
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SlotTiming is the timing of the building jobs of a slot, offsets are relative to the slot time, the timestamp of the
+// payload being built.
+type SlotTiming struct {
+	// SlotDeadline is how long after the slot time the building jobs of the slot are stopped
+	SlotDeadline time.Duration
+	// BuildTimeout is how long the miner is given to build a block
+	BuildTimeout time.Duration
+	// SubmissionOffset is how long before the slot time the submissions start
+	SubmissionOffset time.Duration
+	// PayloadAttributesGrace is how long after the slot time payload attributes are still built on
+	PayloadAttributesGrace time.Duration
+}
+
+// withDefaults returns the timing with unset phases derived from the slot duration. For 12 second slots the jobs run
+// until 4 seconds after the slot time, blocks are built within 4 seconds, submissions start 3 seconds before the slot
+// time and payload attributes are built on until 1 second after the slot time.
+func (t SlotTiming) withDefaults(secondsInSlot uint64) SlotTiming {
+	slotDuration := time.Duration(secondsInSlot) * time.Second
+	if t.SlotDeadline <= 0 {
+		t.SlotDeadline = slotDuration / 3
+	}
+	if t.BuildTimeout <= 0 {
+		t.BuildTimeout = slotDuration / 3
+	}
+	if t.SubmissionOffset <= 0 {
+		t.SubmissionOffset = slotDuration / 4
+	}
+	if t.PayloadAttributesGrace <= 0 {
+		t.PayloadAttributesGrace = slotDuration / 12
+	}
+	return t
+}
+
+// parseSlotTiming overrides the timing with the options of the flag value in the format
+// deadline=<duration>,build_timeout=<duration>,payload_attributes_grace=<duration>
+func parseSlotTiming(timing SlotTiming, options string) (SlotTiming, error) {
+	if options == "" {
+		return timing, nil
+	}
+
+	for _, option := range strings.Split(options, ",") {
+		key, value, _ := strings.Cut(option, "=")
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return timing, fmt.Errorf("invalid slot timing option %q: %w", option, err)
+		}
+		if d < 0 {
+			return timing, fmt.Errorf("slot timing option %q must not be negative", option)
+		}
+
+		switch key {
+		case "deadline":
+			timing.SlotDeadline = d
+		case "build_timeout":
+			timing.BuildTimeout = d
+		case "payload_attributes_grace":
+			timing.PayloadAttributesGrace = d
+		default:
+			return timing, fmt.Errorf("unknown slot timing option %q", key)
+		}
+	}
+	return timing, nil
+}
@@ -0,0 +1,99 @@
+package builder
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/flashbotsextra"
+	"github.com/flashbots/go-boost-utils/bls"
+	"github.com/stretchr/testify/require"
+)
+
+// slotClock is the system clock shifted so that the slot time is now
+type slotClock struct {
+	offset time.Duration
+}
+
+func newSlotClock(timestamp uint64) slotClock {
+	return slotClock{offset: time.Until(time.Unix(int64(timestamp), 0))}
+}
+
+func (c slotClock) Now() time.Time {
+	return time.Now().Add(c.offset)
+}
+
+func (c slotClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func TestSlotTimingDefaults(t *testing.T) {
+	require.Equal(t, SlotTiming{
+		SlotDeadline:           4 * time.Second,
+		BuildTimeout:           4 * time.Second,
+		SubmissionOffset:       3 * time.Second,
+		PayloadAttributesGrace: time.Second,
+	}, SlotTiming{}.withDefaults(12))
+
+	require.Equal(t, SlotTiming{
+		SlotDeadline:           2 * time.Second,
+		BuildTimeout:           2 * time.Second / 3,
+		SubmissionOffset:       time.Second / 2,
+		PayloadAttributesGrace: time.Second / 6,
+	}, SlotTiming{SlotDeadline: 2 * time.Second}.withDefaults(2))
+}
+
+func TestParseSlotTiming(t *testing.T) {
+	timing, err := parseSlotTiming(SlotTiming{SubmissionOffset: time.Second}, "")
+	require.NoError(t, err)
+	require.Equal(t, SlotTiming{SubmissionOffset: time.Second}, timing)
+
+	timing, err = parseSlotTiming(SlotTiming{SubmissionOffset: time.Second}, "deadline=6s,build_timeout=1500ms,payload_attributes_grace=500ms")
+	require.NoError(t, err)
+	require.Equal(t, SlotTiming{
+		SlotDeadline:           6 * time.Second,
+		BuildTimeout:           1500 * time.Millisecond,
+		SubmissionOffset:       time.Second,
+		PayloadAttributesGrace: 500 * time.Millisecond,
+	}, timing)
+
+	for _, options := range []string{"deadline", "deadline=abc", "deadline=-1s", "unknown=1s"} {
+		_, err = parseSlotTiming(SlotTiming{}, options)
+		require.Error(t, err, options)
+	}
+}
+
+func TestOnPayloadAttributesLate(t *testing.T) {
+	validator := NewRandomValidator()
+	testRelay := testRelay{
+		gvsVd: ValidatorData{
+			Pubkey:       PubkeyHex(validator.Pk.String()),
+			FeeRecipient: bellatrix.ExecutionAddress{0x02},
+			GasLimit:     30_000_000,
+		},
+	}
+	sk, err := bls.GenerateRandomSecretKey()
+	require.NoError(t, err)
+	testBlock := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10), GasLimit: 30_000_000})
+	testEthService := &testEthereumService{synced: true, testBlock: testBlock, testBlockValue: big.NewInt(10)}
+
+	// payload attributes are built on until the grace period after the slot time has passed
+	builder, err := NewBuilder(BuilderArgs{
+		sk:            sk,
+		ds:            flashbotsextra.NilDbService{},
+		relay:         &testRelay,
+		eth:           testEthService,
+		beaconClient:  &testBeaconClient{validator: validator},
+		secondsInSlot: 2,
+		clock:         slotClock{offset: time.Until(time.Unix(12, 0)) + 200*time.Millisecond},
+	})
+	require.NoError(t, err)
+	defer builder.Stop()
+
+	err = builder.OnPayloadAttribute(&types.BuilderPayloadAttributes{Timestamp: hexutil.Uint64(12), Random: common.Hash{0x01}, Slot: 1})
+	require.ErrorIs(t, err, errPayloadAttrsLate)
+}
@@ -0,0 +1,115 @@
+package builder
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Clock is the source of time of the submission schedule, the system clock is used when nil
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// SubmissionSchedule is the timeline of the submissions of every building job of a slot, offsets are relative to the
+// slot time, the timestamp of the payload being built.
+//
+// Submissions begin Start before the slot time. The minimum interval between two submissions of a job ramps down
+// linearly from StartInterval at the start to MinInterval at RampEnd before the slot time. A last look submission of
+// the best block is made LastLook before the slot time, and nothing is submitted after Cutoff past the slot time.
+// Submissions of all jobs are additionally rate limited by the builder limiter.
+type SubmissionSchedule struct {
+	Start         time.Duration
+	StartInterval time.Duration
+	RampEnd       time.Duration
+	MinInterval   time.Duration
+	// LastLook is disabled when zero
+	LastLook time.Duration
+	// Cutoff is disabled when zero, negative values stop the submissions before the slot time
+	Cutoff time.Duration
+
+	clock Clock
+}
+
+func (s SubmissionSchedule) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
+
+func (s SubmissionSchedule) after(d time.Duration) <-chan time.Time {
+	if s.clock == nil {
+		return time.After(d)
+	}
+	return s.clock.After(d)
+}
+
+func (s SubmissionSchedule) startTime(slotTime time.Time) time.Time {
+	return slotTime.Add(-s.Start)
+}
+
+func (s SubmissionSchedule) lastLookTime(slotTime time.Time) (time.Time, bool) {
+	return slotTime.Add(-s.LastLook), s.LastLook > 0
+}
+
+func (s SubmissionSchedule) cutoffTime(slotTime time.Time) (time.Time, bool) {
+	return slotTime.Add(s.Cutoff), s.Cutoff != 0
+}
+
+// interval returns the minimum interval between two submissions of a job at the given time
+func (s SubmissionSchedule) interval(slotTime, now time.Time) time.Duration {
+	remaining := slotTime.Sub(now)
+	switch {
+	case remaining <= s.RampEnd:
+		return s.MinInterval
+	case remaining >= s.Start:
+		return s.StartInterval
+	}
+
+	// linear ramp from StartInterval at Start to MinInterval at RampEnd
+	progress := float64(s.Start-remaining) / float64(s.Start-s.RampEnd)
+	return s.StartInterval - time.Duration(progress*float64(s.StartInterval-s.MinInterval))
+}
+
+// parseSubmissionSchedule overrides the schedule with the options of the flag value in the format
+// start=<duration>,interval=<duration>,ramp_end=<duration>,min_interval=<duration>,last_look=<duration>,cutoff=<duration>
+func parseSubmissionSchedule(schedule SubmissionSchedule, options string) (SubmissionSchedule, error) {
+	if options == "" {
+		return schedule, nil
+	}
+
+	for _, option := range strings.Split(options, ",") {
+		key, value, _ := strings.Cut(option, "=")
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return schedule, fmt.Errorf("invalid submission schedule option %q: %w", option, err)
+		}
+
+		switch key {
+		case "start":
+			schedule.Start = d
+		case "interval":
+			schedule.StartInterval = d
+		case "ramp_end":
+			schedule.RampEnd = d
+		case "min_interval":
+			schedule.MinInterval = d
+		case "last_look":
+			schedule.LastLook = d
+		case "cutoff":
+			schedule.Cutoff = d
+		default:
+			return schedule, fmt.Errorf("unknown submission schedule option %q", key)
+		}
+	}
+
+	if schedule.Start <= 0 {
+		return schedule, fmt.Errorf("submission schedule start must be positive")
+	}
+	if schedule.StartInterval < 0 || schedule.MinInterval < 0 || schedule.RampEnd < 0 || schedule.LastLook < 0 {
+		return schedule, fmt.Errorf("submission schedule intervals and offsets must not be negative")
+	}
+	return schedule, nil
+}
@@ -0,0 +1,177 @@
+package builder
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+type testClockWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// testClock is a manually advanced clock
+type testClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []testClockWaiter
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, testClockWaiter{at: c.now.Add(d), c: ch})
+	return ch
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiters = append(waiters, w)
+			continue
+		}
+		w.c <- c.now
+	}
+	c.waiters = waiters
+}
+
+func (c *testClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+func TestSubmissionScheduleInterval(t *testing.T) {
+	schedule := SubmissionSchedule{Start: 4 * time.Second, StartInterval: time.Second, RampEnd: time.Second, MinInterval: 100 * time.Millisecond}
+	slotTime := time.Unix(100, 0)
+
+	require.Equal(t, time.Second, schedule.interval(slotTime, slotTime.Add(-5*time.Second)))
+	require.Equal(t, time.Second, schedule.interval(slotTime, slotTime.Add(-4*time.Second)))
+	require.Equal(t, 550*time.Millisecond, schedule.interval(slotTime, slotTime.Add(-2500*time.Millisecond)))
+	require.Equal(t, 100*time.Millisecond, schedule.interval(slotTime, slotTime.Add(-time.Second)))
+	require.Equal(t, 100*time.Millisecond, schedule.interval(slotTime, slotTime.Add(time.Second)))
+}
+
+func TestParseSubmissionSchedule(t *testing.T) {
+	base := SubmissionSchedule{Start: 3 * time.Second}
+
+	schedule, err := parseSubmissionSchedule(base, "")
+	require.NoError(t, err)
+	require.Equal(t, base, schedule)
+
+	schedule, err = parseSubmissionSchedule(base, "start=4s,interval=500ms,ramp_end=500ms,min_interval=50ms,last_look=100ms,cutoff=1s")
+	require.NoError(t, err)
+	require.Equal(t, SubmissionSchedule{
+		Start:         4 * time.Second,
+		StartInterval: 500 * time.Millisecond,
+		RampEnd:       500 * time.Millisecond,
+		MinInterval:   50 * time.Millisecond,
+		LastLook:      100 * time.Millisecond,
+		Cutoff:        time.Second,
+	}, schedule)
+
+	for _, options := range []string{"start", "start=abc", "unknown=1s", "start=0s", "interval=-1s"} {
+		_, err = parseSubmissionSchedule(base, options)
+		require.Error(t, err, options)
+	}
+}
+
+func TestResubmitLoopSchedule(t *testing.T) {
+	clock := &testClock{now: time.Unix(1000, 0)}
+	slotTime := clock.Now().Add(10 * time.Second)
+	schedule := SubmissionSchedule{
+		Start:         4 * time.Second,
+		StartInterval: time.Second,
+		RampEnd:       time.Second,
+		MinInterval:   100 * time.Millisecond,
+		LastLook:      200 * time.Millisecond,
+		Cutoff:        500 * time.Millisecond,
+		clock:         clock,
+	}
+
+	var (
+		signal      = make(chan struct{}, 1)
+		submissions = make(chan time.Time, 10)
+		done        = make(chan struct{})
+	)
+	go func() {
+		runResubmitLoop(context.Background(), rate.NewLimiter(rate.Inf, 1), signal, func() {
+			submissions <- clock.Now()
+		}, schedule, slotTime)
+		close(done)
+	}()
+
+	expectSubmission := func(at time.Time) {
+		select {
+		case submitted := <-submissions:
+			require.Equal(t, at, submitted)
+		case <-time.After(time.Second):
+			t.Fatalf("expected submission at %s", at)
+		}
+	}
+	expectNoSubmission := func() {
+		select {
+		case submitted := <-submissions:
+			t.Fatalf("unexpected submission at %s", submitted)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	waitForWaiters := func(n int) {
+		require.Eventually(t, func() bool { return clock.Waiters() == n }, time.Second, time.Millisecond)
+	}
+
+	// nothing is submitted before the start of the schedule
+	signal <- struct{}{}
+	waitForWaiters(1)
+	expectNoSubmission()
+	clock.Advance(6 * time.Second)
+	expectSubmission(slotTime.Add(-4 * time.Second))
+
+	// submissions are held back by the interval at the start
+	signal <- struct{}{}
+	waitForWaiters(3)
+	expectNoSubmission()
+	clock.Advance(time.Second)
+	expectSubmission(slotTime.Add(-3 * time.Second))
+
+	// the interval is ramped down near the slot time
+	clock.Advance(2700 * time.Millisecond)
+	signal <- struct{}{}
+	expectSubmission(slotTime.Add(-300 * time.Millisecond))
+
+	// last look submission right before the slot time
+	clock.Advance(100 * time.Millisecond)
+	expectSubmission(slotTime.Add(-200 * time.Millisecond))
+
+	// nothing is submitted after the cutoff
+	clock.Advance(700 * time.Millisecond)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("resubmit loop did not stop at the cutoff")
+	}
+	signal <- struct{}{}
+	expectNoSubmission()
+}
@@ -9,10 +9,41 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 )
 
 var errHTTPErrorResponse = errors.New("HTTP error response")
 
+// HTTPErrorResponse is a non-ok response of a relay, the message is taken from the relay error JSON
+// or is the raw body if the relay did not respond with one
+type HTTPErrorResponse struct {
+	StatusCode int    `json:"-"`
+	Code       int    `json:"code"`
+	Message    string `json:"message"`
+}
+
+func newHTTPErrorResponse(statusCode int, body []byte) *HTTPErrorResponse {
+	resp := &HTTPErrorResponse{StatusCode: statusCode}
+	if err := json.Unmarshal(body, resp); err != nil || resp.Message == "" {
+		resp.Code = 0
+		resp.Message = strings.TrimSpace(string(body))
+	}
+	return resp
+}
+
+func (e *HTTPErrorResponse) Error() string {
+	return fmt.Sprintf("%s: %d / %s", errHTTPErrorResponse, e.StatusCode, e.Message)
+}
+
+// Is reports whether the response is a well-known relay rejection such as ErrRelayRateLimited
+func (e *HTTPErrorResponse) Is(target error) bool {
+	if target == errHTTPErrorResponse {
+		return true
+	}
+	kind := classifyRelayError(e.StatusCode, e.Message)
+	return kind != nil && kind == target
+}
+
 // SendSSZRequest is a request to send SSZ data to a remote relay.
 func SendSSZRequest(ctx context.Context, client http.Client, method, url string, payload []byte, useGzip bool) (code int, err error) {
 	var req *http.Request
@@ -36,20 +67,20 @@ func SendSSZRequest(ctx context.Context, client http.Client, method, url string,
 			return 0, fmt.Errorf("error closing gzip writer: %w", err)
 		}
 
-		req, err = http.NewRequest(http.MethodPost, url, &buf)
+		req, err = http.NewRequestWithContext(ctx, method, url, &buf)
 		if err != nil {
 			return 0, fmt.Errorf("error creating request: %w", err)
 		}
 		req.Header.Add("Content-Encoding", "gzip")
 	} else {
-		req, err = http.NewRequest(http.MethodPost, url, reader)
+		req, err = http.NewRequestWithContext(ctx, method, url, reader)
 		if err != nil {
 			return 0, fmt.Errorf("error creating request: %w", err)
 		}
 	}
 
 	req.Header.Add("Content-Type", "application/octet-stream")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return 0, fmt.Errorf("error sending request: %w", err)
 	}
@@ -60,7 +91,7 @@ func SendSSZRequest(ctx context.Context, client http.Client, method, url string,
 		if err != nil {
 			return resp.StatusCode, fmt.Errorf("could not read error response body for status code %d: %w", resp.StatusCode, err)
 		}
-		return resp.StatusCode, fmt.Errorf("HTTP error response: %d / %s", resp.StatusCode, string(bodyBytes))
+		return resp.StatusCode, newHTTPErrorResponse(resp.StatusCode, bodyBytes)
 	}
 	return resp.StatusCode, nil
 }
@@ -101,7 +132,7 @@ func SendHTTPRequest(ctx context.Context, client http.Client, method, url string
 		if err != nil {
 			return resp.StatusCode, fmt.Errorf("could not read error response body for status code %d: %w", resp.StatusCode, err)
 		}
-		return resp.StatusCode, fmt.Errorf("%w: %d / %s", errHTTPErrorResponse, resp.StatusCode, string(bodyBytes))
+		return resp.StatusCode, newHTTPErrorResponse(resp.StatusCode, bodyBytes)
 	}
 
 	if dst != nil {
@@ -0,0 +1,93 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/builder"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	builderReplayRPCFlag = &cli.StringFlag{
+		Name:  "rpc",
+		Usage: "RPC endpoint of the node serving the flashbots block validation API",
+		Value: "http://127.0.0.1:8545",
+	}
+	builderCommand = &cli.Command{
+		Name:  "builder",
+		Usage: "A set of commands for the block builder",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "replay",
+				Usage:     "Re-validate the submissions recorded in a dry run log",
+				ArgsUsage: "<file>",
+				Action:    replayDryRunLog,
+				Flags:     []cli.Flag{builderReplayRPCFlag},
+				Description: `
+geth builder replay --rpc <endpoint> <file>
+validates every submission recorded with --builder.dry_run_log against the node at
+<endpoint> and reports the submissions whose validation outcome differs from the
+recorded one.
+`,
+			},
+		},
+	}
+)
+
+func replayDryRunLog(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("expected the dry run log as the only argument")
+	}
+	file, err := os.Open(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	client, err := rpc.DialContext(ctx.Context, ctx.String(builderReplayRPCFlag.Name))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var total, invalid, mismatched int
+	err = builder.ReadShadowLog(file, func(s *builder.ShadowSubmission) error {
+		total++
+		var validationError string
+		if err := builder.ValidateShadowSubmission(ctx.Context, client, s); err != nil {
+			validationError = err.Error()
+			invalid++
+		}
+		if (validationError == "") != (s.ValidationError == "") {
+			mismatched++
+			log.Warn("Validation outcome changed", "slot", s.Slot, "version", s.Version, "proposerPayment", s.ProposerPayment,
+				"recorded", s.ValidationError, "replayed", validationError)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not read dry run log: %w", err)
+	}
+	log.Info("Replayed dry run log", "submissions", total, "invalid", invalid, "mismatched", mismatched)
+	return nil
+}
@@ -159,6 +159,7 @@ var (
 	builderApiFlags = []cli.Flag{
 		utils.BuilderEnabled,
 		utils.BuilderAlgoTypeFlag,
+		utils.BuilderAlgoOptionsFlag,
 		utils.BuilderPriceCutoffPercentFlag,
 		utils.BuilderEnableValidatorChecks,
 		utils.BuilderBlockValidationBlacklistSourceFilePath,
@@ -168,22 +169,31 @@ var (
 		utils.BuilderSlotsInEpoch,
 		utils.BuilderDisableBundleFetcher,
 		utils.BuilderDryRun,
+		utils.BuilderDryRunLog,
 		utils.BuilderIgnoreLatePayloadAttributes,
 		utils.BuilderSecretKey,
 		utils.BuilderRelaySecretKey,
 		utils.BuilderListenAddr,
 		utils.BuilderGenesisForkVersion,
 		utils.BuilderBellatrixForkVersion,
+		utils.BuilderCapellaForkVersion,
 		utils.BuilderGenesisValidatorsRoot,
 		utils.BuilderBeaconEndpoints,
+		utils.BuilderBeaconQuorum,
 		utils.BuilderRemoteRelayEndpoint,
 		utils.BuilderSecondaryRemoteRelayEndpoints,
 		utils.BuilderRateLimitDuration,
 		utils.BuilderRateLimitMaxBurst,
 		utils.BuilderBlockResubmitInterval,
+		utils.BuilderBlockRebuildInterval,
 		utils.BuilderSubmissionOffset,
+		utils.BuilderSubmissionSchedule,
+		utils.BuilderSlotTiming,
 		utils.BuilderDiscardRevertibleTxOnErr,
 		utils.BuilderEnableCancellations,
+		utils.BuilderMaxHeads,
+		utils.BuilderBidStrategy,
+		utils.BuilderObserveBids,
 	}
 
 	rpcFlags = []cli.Flag{
@@ -274,6 +284,8 @@ func init() {
 		snapshotCommand,
 		// See verkle.go
 		verkleCommand,
+		// See buildercmd.go
+		builderCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
@@ -703,7 +703,16 @@ var (
 	// see setMiner in cmd/utils/flags.go
 	BuilderAlgoTypeFlag = &cli.StringFlag{
 		Name:     "builder.algotype",
-		Usage:    "Block building algorithm to use [=mev-geth] (mev-geth, greedy, greedy-buckets)",
+		Usage:    "Block building algorithm to use [=mev-geth] (mev-geth, greedy, greedy-buckets, greedy-multi-snap, greedy-buckets-multi-snap), a comma separated list runs the algorithms in parallel and uses the most valuable block",
+		Category: flags.BuilderCategory,
+	}
+
+	BuilderAlgoOptionsFlag = &cli.StringFlag{
+		Name: "builder.algo_options",
+		Usage: "Per algorithm options as <algo>:<key>=<value>,...;<algo>:... on top of the defaults of the algorithm, " +
+			"keys are drop_revertible_tx_on_err, enforce_profit, profit_threshold_percent, price_cutoff_percent, retry_limit, " +
+			"local_search_budget, speculative_orders and record_conflicts",
+		EnvVars:  []string{"FLASHBOTS_BUILDER_ALGO_OPTIONS"},
 		Category: flags.BuilderCategory,
 	}
 
@@ -768,6 +777,14 @@ var (
 		Usage:    "Builder only validates blocks without submission to the relay",
 		Category: flags.BuilderCategory,
 	}
+	BuilderDryRunLog = &cli.StringFlag{
+		Name: "builder.dry_run_log",
+		Usage: "Append every block validated in dry run mode to this file, one JSON submission per line, " +
+			"to be replayed with the builder replay command",
+		EnvVars:  []string{"FLASHBOTS_BUILDER_DRY_RUN_LOG"},
+		Value:    builder.DefaultConfig.DryRunLog,
+		Category: flags.BuilderCategory,
+	}
 	BuilderIgnoreLatePayloadAttributes = &cli.BoolFlag{
 		Name:     "builder.ignore_late_payload_attributes",
 		Usage:    "Builder will ignore all but the first payload attributes. Use if your CL sends non-canonical head updates.",
@@ -808,6 +825,13 @@ var (
 		Value:    "0x02000000",
 		Category: flags.BuilderCategory,
 	}
+	BuilderCapellaForkVersion = &cli.StringFlag{
+		Name:     "builder.capella_fork_version",
+		Usage:    "Capella fork version.",
+		EnvVars:  []string{"BUILDER_CAPELLA_FORK_VERSION"},
+		Value:    "0x03000000",
+		Category: flags.BuilderCategory,
+	}
 	BuilderGenesisValidatorsRoot = &cli.StringFlag{
 		Name:     "builder.genesis_validators_root",
 		Usage:    "Genesis validators root of the network.",
@@ -822,6 +846,14 @@ var (
 		Value:    "http://127.0.0.1:5052",
 		Category: flags.BuilderCategory,
 	}
+	BuilderBeaconQuorum = &cli.IntFlag{
+		Name: "builder.beacon_quorum",
+		Usage: "Number of beacon nodes which must announce the same payload attributes before the builder starts building " +
+			"on them. Must not be larger than the number of beacon endpoints.",
+		EnvVars:  []string{"BUILDER_BEACON_QUORUM"},
+		Value:    builder.DefaultConfig.BeaconQuorum,
+		Category: flags.BuilderCategory,
+	}
 	BuilderRemoteRelayEndpoint = &cli.StringFlag{
 		Name:     "builder.remote_relay_endpoint",
 		Usage:    "Relay endpoint to connect to for validator registration data, if not provided will expose validator registration locally",
@@ -868,12 +900,44 @@ var (
 		Category: flags.BuilderCategory,
 	}
 
+	BuilderBlockRebuildInterval = &cli.DurationFlag{
+		Name: "builder.block_rebuild_interval",
+		Usage: "Determines the minimum interval between two builds of the block of a building job. " +
+			"Blocks are only rebuilt when transactions or bundles entered the pool since the last build",
+		EnvVars:  []string{"FLASHBOTS_BUILDER_BLOCK_REBUILD_INTERVAL"},
+		Value:    builder.BlockRebuildIntervalDefault,
+		Category: flags.BuilderCategory,
+	}
+
 	BuilderSubmissionOffset = &cli.DurationFlag{
 		Name: "builder.submission_offset",
 		Usage: "Determines the offset from the end of slot time that the builder will submit blocks. " +
-			"For example, if a slot is 12 seconds long, and the offset is 2 seconds, the builder will submit blocks at 10 seconds into the slot.",
+			"For example, if a slot is 12 seconds long, and the offset is 2 seconds, the builder will submit blocks at 10 seconds into the slot. " +
+			"Defaults to a quarter of the slot.",
 		EnvVars:  []string{"FLASHBOTS_BUILDER_SUBMISSION_OFFSET"},
-		Value:    builder.SubmissionOffsetFromEndOfSlotSecondsDefault,
+		Value:    builder.DefaultConfig.BuilderSubmissionOffset,
+		Category: flags.BuilderCategory,
+	}
+
+	BuilderSlotTiming = &cli.StringFlag{
+		Name: "builder.slot_timing",
+		Usage: "Comma separated slot timing options relative to the slot time: building jobs are stopped deadline=<duration> after the slot time, " +
+			"blocks are built within build_timeout=<duration>, and payload attributes received more than payload_attributes_grace=<duration> " +
+			"after the slot time are ignored. Unset options are derived from the seconds in slot, for 12 second slots " +
+			"\"deadline=4s,build_timeout=4s,payload_attributes_grace=1s\"",
+		EnvVars:  []string{"FLASHBOTS_BUILDER_SLOT_TIMING"},
+		Value:    builder.DefaultConfig.SlotTiming,
+		Category: flags.BuilderCategory,
+	}
+
+	BuilderSubmissionSchedule = &cli.StringFlag{
+		Name: "builder.submission_schedule",
+		Usage: "Comma separated submission schedule options relative to the slot time: start=<duration> overrides the submission offset, " +
+			"the minimum interval between submissions of a job ramps down from interval=<duration> at the start to min_interval=<duration> " +
+			"at ramp_end=<duration> before the slot time, last_look=<duration> makes a final submission of the best block before the slot time, " +
+			"and nothing is submitted after cutoff=<duration> past the slot time. For example \"start=4s,interval=500ms,ramp_end=500ms,min_interval=50ms,last_look=100ms,cutoff=1s\"",
+		EnvVars:  []string{"FLASHBOTS_BUILDER_SUBMISSION_SCHEDULE"},
+		Value:    builder.DefaultConfig.BuilderSubmissionSchedule,
 		Category: flags.BuilderCategory,
 	}
 
@@ -893,6 +957,35 @@ var (
 		Category: flags.BuilderCategory,
 	}
 
+	BuilderMaxHeads = &cli.IntFlag{
+		Name: "builder.max_heads",
+		Usage: "Maximum number of heads the builder builds on concurrently within a slot. " +
+			"When a new head is announced and the limit is reached, the job of the earliest announced head is stopped.",
+		EnvVars:  []string{"FLASHBOTS_BUILDER_MAX_HEADS"},
+		Value:    builder.DefaultConfig.MaxHeads,
+		Category: flags.BuilderCategory,
+	}
+
+	BuilderBidStrategy = &cli.StringFlag{
+		Name: "builder.bid_strategy",
+		Usage: "Strategy deciding how much of the block value is paid to the proposer: \"full-value\", " +
+			"\"fixed-margin:<wei>\" keeping a fixed margin, or \"match-top-bid:<wei>[:<duration>]\" outbidding the top " +
+			"competing bid by the given amount, and paying the full value after the given time into the slot. " +
+			"Only used when the builder pays the proposer with a payment transaction.",
+		EnvVars:  []string{"FLASHBOTS_BUILDER_BID_STRATEGY"},
+		Value:    builder.DefaultConfig.BidStrategy,
+		Category: flags.BuilderCategory,
+	}
+
+	BuilderObserveBids = &cli.BoolFlag{
+		Name: "builder.observe_bids",
+		Usage: "Poll the data API of the remote relays for the bids of other builders in the current slot. " +
+			"The top bid is used by the bid strategy and observed bids are returned by the builder_getSlotBids RPC method.",
+		EnvVars:  []string{"FLASHBOTS_BUILDER_OBSERVE_BIDS"},
+		Value:    builder.DefaultConfig.ObserveBids,
+		Category: flags.BuilderCategory,
+	}
+
 	// RPC settings
 	IPCDisabledFlag = &cli.BoolFlag{
 		Name:     "ipcdisable",
@@ -1698,14 +1791,17 @@ func SetBuilderConfig(ctx *cli.Context, cfg *builder.Config) {
 	cfg.SecondsInSlot = ctx.Uint64(BuilderSecondsInSlot.Name)
 	cfg.DisableBundleFetcher = ctx.IsSet(BuilderDisableBundleFetcher.Name)
 	cfg.DryRun = ctx.IsSet(BuilderDryRun.Name)
+	cfg.DryRunLog = ctx.String(BuilderDryRunLog.Name)
 	cfg.IgnoreLatePayloadAttributes = ctx.IsSet(BuilderIgnoreLatePayloadAttributes.Name)
 	cfg.BuilderSecretKey = ctx.String(BuilderSecretKey.Name)
 	cfg.RelaySecretKey = ctx.String(BuilderRelaySecretKey.Name)
 	cfg.ListenAddr = ctx.String(BuilderListenAddr.Name)
 	cfg.GenesisForkVersion = ctx.String(BuilderGenesisForkVersion.Name)
 	cfg.BellatrixForkVersion = ctx.String(BuilderBellatrixForkVersion.Name)
+	cfg.CapellaForkVersion = ctx.String(BuilderCapellaForkVersion.Name)
 	cfg.GenesisValidatorsRoot = ctx.String(BuilderGenesisValidatorsRoot.Name)
 	cfg.BeaconEndpoints = strings.Split(ctx.String(BuilderBeaconEndpoints.Name), ",")
+	cfg.BeaconQuorum = ctx.Int(BuilderBeaconQuorum.Name)
 	cfg.RemoteRelayEndpoint = ctx.String(BuilderRemoteRelayEndpoint.Name)
 	cfg.SecondaryRemoteRelayEndpoints = strings.Split(ctx.String(BuilderSecondaryRemoteRelayEndpoints.Name), ",")
 	// NOTE: This flag is deprecated and will be removed in the future in favor of BuilderBlockValidationBlacklistSourceFilePath
@@ -1721,9 +1817,15 @@ func SetBuilderConfig(ctx *cli.Context, cfg *builder.Config) {
 	cfg.BuilderRateLimitDuration = ctx.String(BuilderRateLimitDuration.Name)
 	cfg.BuilderRateLimitMaxBurst = ctx.Int(BuilderRateLimitMaxBurst.Name)
 	cfg.BuilderSubmissionOffset = ctx.Duration(BuilderSubmissionOffset.Name)
+	cfg.BuilderSubmissionSchedule = ctx.String(BuilderSubmissionSchedule.Name)
+	cfg.SlotTiming = ctx.String(BuilderSlotTiming.Name)
 	cfg.DiscardRevertibleTxOnErr = ctx.Bool(BuilderDiscardRevertibleTxOnErr.Name)
 	cfg.EnableCancellations = ctx.IsSet(BuilderEnableCancellations.Name)
+	cfg.MaxHeads = ctx.Int(BuilderMaxHeads.Name)
+	cfg.BidStrategy = ctx.String(BuilderBidStrategy.Name)
+	cfg.ObserveBids = ctx.Bool(BuilderObserveBids.Name)
 	cfg.BuilderRateLimitResubmitInterval = ctx.String(BuilderBlockResubmitInterval.Name)
+	cfg.BuilderRebuildInterval = ctx.Duration(BuilderBlockRebuildInterval.Name)
 }
 
 // SetNodeConfig applies node-related command line flags to the config.
@@ -1921,19 +2023,19 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	}
 	// NOTE: This flag is deprecated and will be removed in the future.
 	if ctx.IsSet(MinerAlgoTypeFlag.Name) {
-		algoType, err := miner.AlgoTypeFlagToEnum(ctx.String(BuilderAlgoTypeFlag.Name))
+		algoTypes, err := miner.ParseAlgoTypes(ctx.String(BuilderAlgoTypeFlag.Name))
 		if err != nil {
 			Fatalf("Invalid algo in --miner.algotype: %s", ctx.String(BuilderAlgoTypeFlag.Name))
 		}
-		cfg.AlgoType = algoType
+		cfg.AlgoType, cfg.AlgoTypes = algoTypes[0], algoTypes
 	}
 	// NOTE: BuilderAlgoTypeFlag takes precedence and will overwrite value set by MinerAlgoTypeFlag.
 	if ctx.IsSet(BuilderAlgoTypeFlag.Name) {
-		algoType, err := miner.AlgoTypeFlagToEnum(ctx.String(BuilderAlgoTypeFlag.Name))
+		algoTypes, err := miner.ParseAlgoTypes(ctx.String(BuilderAlgoTypeFlag.Name))
 		if err != nil {
 			Fatalf("Invalid algo in --builder.algotype: %s", ctx.String(BuilderAlgoTypeFlag.Name))
 		}
-		cfg.AlgoType = algoType
+		cfg.AlgoType, cfg.AlgoTypes = algoTypes[0], algoTypes
 	}
 	if ctx.IsSet(MinerRecommitIntervalFlag.Name) {
 		cfg.Recommit = ctx.Duration(MinerRecommitIntervalFlag.Name)
@@ -1973,6 +2075,12 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 
 	cfg.DiscardRevertibleTxOnErr = ctx.Bool(BuilderDiscardRevertibleTxOnErr.Name)
 	cfg.PriceCutoffPercent = ctx.Int(BuilderPriceCutoffPercentFlag.Name)
+
+	algoOptions, err := miner.ParseAlgorithmOptions(ctx.String(BuilderAlgoOptionsFlag.Name), cfg)
+	if err != nil {
+		Fatalf("Invalid --builder.algo_options: %v", err)
+	}
+	cfg.AlgoOptions = algoOptions
 }
 
 func setRequiredBlocks(ctx *cli.Context, cfg *ethconfig.Config) {
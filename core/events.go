@@ -24,6 +24,13 @@ import (
 // NewTxsEvent is posted when a batch of transactions enter the transaction pool.
 type NewTxsEvent struct{ Txs []*types.Transaction }
 
+// NewBundlesEvent is posted when bundles enter the transaction pool or sbundles are cancelled.
+type NewBundlesEvent struct {
+	Bundles           []types.MevBundle
+	SBundles          []*types.SBundle
+	CancelledSBundles []common.Hash
+}
+
 // NewMinedBlockEvent is posted when a block has been imported.
 type NewMinedBlockEvent struct{ Block *types.Block }
 
@@ -254,6 +254,7 @@ type TxPool struct {
 	chain       blockChain
 	gasPrice    *big.Int
 	txFeed      event.Feed
+	bundleFeed  event.Feed
 	scope       event.SubscriptionScope
 	signer      types.Signer
 	mu          sync.RWMutex
@@ -475,6 +476,12 @@ func (pool *TxPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subsc
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeNewBundlesEvent registers a subscription of NewBundlesEvent and
+// starts sending event to the given channel.
+func (pool *TxPool) SubscribeNewBundlesEvent(ch chan<- core.NewBundlesEvent) event.Subscription {
+	return pool.scope.Track(pool.bundleFeed.Subscribe(ch))
+}
+
 // GasPrice returns the current gas price enforced by the transaction pool.
 func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
@@ -739,9 +746,10 @@ func (pool *TxPool) MevBundles(blockNumber *big.Int, blockTimestamp uint64) ([]t
 // AddMevBundles adds a mev bundles to the pool
 func (pool *TxPool) AddMevBundles(mevBundles []types.MevBundle) error {
 	pool.mu.Lock()
-	defer pool.mu.Unlock()
-
 	pool.mevBundles = append(pool.mevBundles, mevBundles...)
+	pool.mu.Unlock()
+
+	pool.bundleFeed.Send(core.NewBundlesEvent{Bundles: mevBundles})
 	return nil
 }
 
@@ -755,10 +763,7 @@ func (pool *TxPool) AddMevBundle(txs types.Transactions, blockNumber *big.Int, r
 	}
 	bundleHash := common.BytesToHash(bundleHasher.Sum(nil))
 
-	pool.mu.Lock()
-	defer pool.mu.Unlock()
-
-	pool.mevBundles = append(pool.mevBundles, types.MevBundle{
+	bundle := types.MevBundle{
 		Txs:               txs,
 		BlockNumber:       blockNumber,
 		Uuid:              replacementUuid,
@@ -767,17 +772,27 @@ func (pool *TxPool) AddMevBundle(txs types.Transactions, blockNumber *big.Int, r
 		MaxTimestamp:      maxTimestamp,
 		RevertingTxHashes: revertingTxHashes,
 		Hash:              bundleHash,
-	})
-	println("length of mev bundles: ", len(pool.mevBundles))
+	}
+
+	pool.mu.Lock()
+	pool.mevBundles = append(pool.mevBundles, bundle)
+	pool.mu.Unlock()
+
+	pool.bundleFeed.Send(core.NewBundlesEvent{Bundles: []types.MevBundle{bundle}})
 	return nil
 }
 
 func (pool *TxPool) AddSBundle(bundle *types.SBundle) error {
-	return pool.sbundles.Add(bundle)
+	if err := pool.sbundles.Add(bundle); err != nil {
+		return err
+	}
+	pool.bundleFeed.Send(core.NewBundlesEvent{SBundles: []*types.SBundle{bundle}})
+	return nil
 }
 
 func (pool *TxPool) CancelSBundles(hashes []common.Hash) {
 	pool.sbundles.Cancel(hashes)
+	pool.bundleFeed.Send(core.NewBundlesEvent{CancelledSBundles: hashes})
 }
 
 func (pool *TxPool) GetSBundles(block *big.Int) []*types.SBundle {
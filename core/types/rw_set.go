@@ -0,0 +1,84 @@
+package types
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// StorageSlot is a storage slot of an account
+type StorageSlot struct {
+	Address common.Address
+	Key     common.Hash
+}
+
+// RWSet is the set of accounts and storage slots read and written by transactions.
+// Account reads are the accounts whose balance, nonce or code is checked, the accounts with code called by the
+// transactions are read as their code is executed. Account writes are balance, nonce and code changes. Balance
+// increments commute, so the coinbase and the accounts without code whose balance is only increased are written but
+// not read, whereas an account whose balance is decreased is also read.
+type RWSet struct {
+	AccountReads  map[common.Address]struct{}
+	AccountWrites map[common.Address]struct{}
+	StorageReads  map[StorageSlot]struct{}
+	StorageWrites map[StorageSlot]struct{}
+}
+
+func NewRWSet() *RWSet {
+	return &RWSet{
+		AccountReads:  make(map[common.Address]struct{}),
+		AccountWrites: make(map[common.Address]struct{}),
+		StorageReads:  make(map[StorageSlot]struct{}),
+		StorageWrites: make(map[StorageSlot]struct{}),
+	}
+}
+
+func (s *RWSet) ReadAccount(address common.Address) {
+	s.AccountReads[address] = struct{}{}
+}
+
+func (s *RWSet) WriteAccount(address common.Address) {
+	s.AccountWrites[address] = struct{}{}
+}
+
+func (s *RWSet) ReadStorage(address common.Address, key common.Hash) {
+	s.StorageReads[StorageSlot{address, key}] = struct{}{}
+}
+
+func (s *RWSet) WriteStorage(address common.Address, key common.Hash) {
+	s.StorageWrites[StorageSlot{address, key}] = struct{}{}
+}
+
+// Merge adds the accounts and storage slots of other to the set
+func (s *RWSet) Merge(other *RWSet) {
+	for address := range other.AccountReads {
+		s.AccountReads[address] = struct{}{}
+	}
+	for address := range other.AccountWrites {
+		s.AccountWrites[address] = struct{}{}
+	}
+	for slot := range other.StorageReads {
+		s.StorageReads[slot] = struct{}{}
+	}
+	for slot := range other.StorageWrites {
+		s.StorageWrites[slot] = struct{}{}
+	}
+}
+
+// Conflicts reports whether the outcome of the transactions of s and other depends on their order, that is if one of
+// them reads an account or a storage slot written by the other or if both write the same storage slot
+func (s *RWSet) Conflicts(other *RWSet) bool {
+	return intersects(s.AccountWrites, other.AccountReads) || intersects(other.AccountWrites, s.AccountReads) ||
+		intersects(s.StorageWrites, other.StorageReads) || intersects(other.StorageWrites, s.StorageReads) ||
+		intersects(s.StorageWrites, other.StorageWrites)
+}
+
+func intersects[K comparable](a, b map[K]struct{}) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	for key := range a {
+		if _, ok := b[key]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestRWSetConflicts(t *testing.T) {
+	var (
+		sender   = common.Address{0x01}
+		receiver = common.Address{0x02}
+		contract = common.Address{0x03}
+		slot     = common.Hash{0x04}
+	)
+	transfer := func(from common.Address) *RWSet {
+		s := NewRWSet()
+		s.ReadAccount(from)
+		s.WriteAccount(from)
+		s.WriteAccount(receiver)
+		return s
+	}
+	storage := func(read, write bool) *RWSet {
+		s := NewRWSet()
+		if read {
+			s.ReadStorage(contract, slot)
+		}
+		if write {
+			s.WriteStorage(contract, slot)
+		}
+		return s
+	}
+	balanceCheck := NewRWSet()
+	balanceCheck.ReadAccount(receiver)
+
+	tests := []struct {
+		name      string
+		a, b      *RWSet
+		conflicts bool
+	}{
+		{"same sender", transfer(sender), transfer(sender), true},
+		{"same receiver", transfer(sender), transfer(common.Address{0x05}), false},
+		{"balance read", transfer(sender), balanceCheck, true},
+		{"storage reads", storage(true, false), storage(true, false), false},
+		{"storage read and write", storage(true, false), storage(false, true), true},
+		{"storage writes", storage(false, true), storage(false, true), true},
+		{"disjoint", transfer(sender), storage(true, true), false},
+	}
+	for _, test := range tests {
+		if got := test.a.Conflicts(test.b); got != test.conflicts {
+			t.Errorf("%s: conflicts %v, want %v", test.name, got, test.conflicts)
+		}
+		if got := test.b.Conflicts(test.a); got != test.conflicts {
+			t.Errorf("%s: reversed conflicts %v, want %v", test.name, got, test.conflicts)
+		}
+	}
+
+	merged := storage(true, false)
+	merged.Merge(transfer(sender))
+	if !merged.Conflicts(storage(false, true)) || !merged.Conflicts(transfer(sender)) {
+		t.Errorf("merged set does not conflict with the sets merged")
+	}
+}
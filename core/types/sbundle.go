@@ -86,6 +86,8 @@ type SimSBundle struct {
 	// MevGasPrice = (total coinbase profit) / (gas used)
 	MevGasPrice *big.Int
 	Profit      *big.Int
+	// RWSet is the set of accounts and storage slots accessed by the sbundle in simulation, nil if unknown
+	RWSet *RWSet
 }
 
 func GetRefundConfig(body *BundleBody, signer Signer) ([]RefundConfig, error) {
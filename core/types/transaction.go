@@ -835,4 +835,6 @@ type SimulatedBundle struct {
 	EthSentToCoinbase *big.Int
 	TotalGasUsed      uint64
 	OriginalBundle    MevBundle
+	// RWSet is the set of accounts and storage slots accessed by the bundle in simulation, nil if unknown
+	RWSet *RWSet
 }
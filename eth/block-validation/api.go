@@ -4,13 +4,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math/big"
 	"os"
 
 	bellatrixapi "github.com/attestantio/go-builder-client/api/bellatrix"
 	capellaapi "github.com/attestantio/go-builder-client/api/capella"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
-	"github.com/ethereum/go-ethereum/beacon/engine"
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -135,70 +135,10 @@ type BuilderBlockValidationRequest struct {
 }
 
 func (api *BlockValidationAPI) ValidateBuilderSubmissionV1(params *BuilderBlockValidationRequest) error {
-	// TODO: fuzztest, make sure the validation is sound
-	// TODO: handle context!
-
-	if params.ExecutionPayload == nil {
-		return errors.New("nil execution payload")
-	}
-	payload := params.ExecutionPayload
-	block, err := engine.ExecutionPayloadToBlock(payload)
-	if err != nil {
-		return err
-	}
-
-	if params.Message.ParentHash != phase0.Hash32(block.ParentHash()) {
-		return fmt.Errorf("incorrect ParentHash %s, expected %s", params.Message.ParentHash.String(), block.ParentHash().String())
-	}
-
-	if params.Message.BlockHash != phase0.Hash32(block.Hash()) {
-		return fmt.Errorf("incorrect BlockHash %s, expected %s", params.Message.BlockHash.String(), block.Hash().String())
-	}
-
-	if params.Message.GasLimit != block.GasLimit() {
-		return fmt.Errorf("incorrect GasLimit %d, expected %d", params.Message.GasLimit, block.GasLimit())
-	}
-
-	if params.Message.GasUsed != block.GasUsed() {
-		return fmt.Errorf("incorrect GasUsed %d, expected %d", params.Message.GasUsed, block.GasUsed())
-	}
-
-	feeRecipient := common.BytesToAddress(params.Message.ProposerFeeRecipient[:])
-	expectedProfit := params.Message.Value.ToBig()
-
-	var vmconfig vm.Config
-	var tracer *logger.AccessListTracer = nil
-	if api.accessVerifier != nil {
-		if err := api.accessVerifier.isBlacklisted(block.Coinbase()); err != nil {
-			return err
-		}
-		if err := api.accessVerifier.isBlacklisted(feeRecipient); err != nil {
-			return err
-		}
-		if err := api.accessVerifier.verifyTransactions(types.LatestSigner(api.eth.BlockChain().Config()), block.Transactions()); err != nil {
-			return err
-		}
-		isPostMerge := true // the call is PoS-native
-		timestamp := params.SubmitBlockRequest.ExecutionPayload.Timestamp
-		precompiles := vm.ActivePrecompiles(api.eth.APIBackend.ChainConfig().Rules(new(big.Int).SetUint64(params.ExecutionPayload.BlockNumber), isPostMerge, timestamp))
-		tracer = logger.NewAccessListTracer(nil, common.Address{}, common.Address{}, precompiles)
-		vmconfig = vm.Config{Tracer: tracer, Debug: true}
-	}
-
-	err = api.eth.BlockChain().ValidatePayload(block, feeRecipient, expectedProfit, params.RegisteredGasLimit, vmconfig, api.useBalanceDiffProfit)
-	if err != nil {
-		log.Error("invalid payload", "hash", payload.BlockHash.String(), "number", payload.BlockNumber, "parentHash", payload.ParentHash.String(), "err", err)
-		return err
-	}
-
-	if api.accessVerifier != nil && tracer != nil {
-		if err := api.accessVerifier.verifyTraces(tracer); err != nil {
-			return err
-		}
-	}
-
-	log.Info("validated block", "hash", block.Hash(), "number", block.NumberU64(), "parentHash", block.ParentHash())
-	return nil
+	return api.validateBuilderSubmission(&builderapi.VersionedSubmitBlockRequest{
+		Version:   consensusspec.DataVersionBellatrix,
+		Bellatrix: &params.SubmitBlockRequest,
+	}, params.RegisteredGasLimit)
 }
 
 type BuilderBlockValidationRequestV2 struct {
@@ -229,35 +169,50 @@ func (r *BuilderBlockValidationRequestV2) UnmarshalJSON(data []byte) error {
 }
 
 func (api *BlockValidationAPI) ValidateBuilderSubmissionV2(params *BuilderBlockValidationRequestV2) error {
+	return api.validateBuilderSubmission(&builderapi.VersionedSubmitBlockRequest{
+		Version: consensusspec.DataVersionCapella,
+		Capella: &params.SubmitBlockRequest,
+	}, params.RegisteredGasLimit)
+}
+
+// ValidateSubmission validates a block submission of any supported fork against the registered gas limit
+func (api *BlockValidationAPI) ValidateSubmission(msg *builderapi.VersionedSubmitBlockRequest, registeredGasLimit uint64) error {
+	return api.validateBuilderSubmission(msg, registeredGasLimit)
+}
+
+func (api *BlockValidationAPI) validateBuilderSubmission(msg *builderapi.VersionedSubmitBlockRequest, registeredGasLimit uint64) error {
 	// TODO: fuzztest, make sure the validation is sound
 	// TODO: handle context!
-	if params.ExecutionPayload == nil {
-		return errors.New("nil execution payload")
+	bidTrace, err := msg.BidTrace()
+	if err != nil {
+		return err
+	}
+	if bidTrace == nil {
+		return errors.New("nil bid trace")
 	}
-	payload := params.ExecutionPayload
-	block, err := engine.ExecutionPayloadV2ToBlock(payload)
+	block, err := msg.Block()
 	if err != nil {
 		return err
 	}
 
-	if params.Message.ParentHash != phase0.Hash32(block.ParentHash()) {
-		return fmt.Errorf("incorrect ParentHash %s, expected %s", params.Message.ParentHash.String(), block.ParentHash().String())
+	if bidTrace.ParentHash != phase0.Hash32(block.ParentHash()) {
+		return fmt.Errorf("incorrect ParentHash %s, expected %s", bidTrace.ParentHash.String(), block.ParentHash().String())
 	}
 
-	if params.Message.BlockHash != phase0.Hash32(block.Hash()) {
-		return fmt.Errorf("incorrect BlockHash %s, expected %s", params.Message.BlockHash.String(), block.Hash().String())
+	if bidTrace.BlockHash != phase0.Hash32(block.Hash()) {
+		return fmt.Errorf("incorrect BlockHash %s, expected %s", bidTrace.BlockHash.String(), block.Hash().String())
 	}
 
-	if params.Message.GasLimit != block.GasLimit() {
-		return fmt.Errorf("incorrect GasLimit %d, expected %d", params.Message.GasLimit, block.GasLimit())
+	if bidTrace.GasLimit != block.GasLimit() {
+		return fmt.Errorf("incorrect GasLimit %d, expected %d", bidTrace.GasLimit, block.GasLimit())
 	}
 
-	if params.Message.GasUsed != block.GasUsed() {
-		return fmt.Errorf("incorrect GasUsed %d, expected %d", params.Message.GasUsed, block.GasUsed())
+	if bidTrace.GasUsed != block.GasUsed() {
+		return fmt.Errorf("incorrect GasUsed %d, expected %d", bidTrace.GasUsed, block.GasUsed())
 	}
 
-	feeRecipient := common.BytesToAddress(params.Message.ProposerFeeRecipient[:])
-	expectedProfit := params.Message.Value.ToBig()
+	feeRecipient := common.BytesToAddress(bidTrace.ProposerFeeRecipient[:])
+	expectedProfit := bidTrace.Value.ToBig()
 
 	var vmconfig vm.Config
 	var tracer *logger.AccessListTracer = nil
@@ -272,14 +227,14 @@ func (api *BlockValidationAPI) ValidateBuilderSubmissionV2(params *BuilderBlockV
 			return err
 		}
 		isPostMerge := true // the call is PoS-native
-		precompiles := vm.ActivePrecompiles(api.eth.APIBackend.ChainConfig().Rules(new(big.Int).SetUint64(params.ExecutionPayload.BlockNumber), isPostMerge, params.ExecutionPayload.Timestamp))
+		precompiles := vm.ActivePrecompiles(api.eth.APIBackend.ChainConfig().Rules(block.Number(), isPostMerge, block.Time()))
 		tracer = logger.NewAccessListTracer(nil, common.Address{}, common.Address{}, precompiles)
 		vmconfig = vm.Config{Tracer: tracer, Debug: true}
 	}
 
-	err = api.eth.BlockChain().ValidatePayload(block, feeRecipient, expectedProfit, params.RegisteredGasLimit, vmconfig, api.useBalanceDiffProfit)
+	err = api.eth.BlockChain().ValidatePayload(block, feeRecipient, expectedProfit, registeredGasLimit, vmconfig, api.useBalanceDiffProfit)
 	if err != nil {
-		log.Error("invalid payload", "hash", payload.BlockHash.String(), "number", payload.BlockNumber, "parentHash", payload.ParentHash.String(), "err", err)
+		log.Error("invalid payload", "version", msg.Version, "hash", block.Hash(), "number", block.NumberU64(), "parentHash", block.ParentHash(), "err", err)
 		return err
 	}
 
@@ -289,6 +244,6 @@ func (api *BlockValidationAPI) ValidateBuilderSubmissionV2(params *BuilderBlockV
 		}
 	}
 
-	log.Info("validated block", "hash", block.Hash(), "number", block.NumberU64(), "parentHash", block.ParentHash())
+	log.Info("validated block", "version", msg.Version, "hash", block.Hash(), "number", block.NumberU64(), "parentHash", block.ParentHash())
 	return nil
 }
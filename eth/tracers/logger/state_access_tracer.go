@@ -0,0 +1,137 @@
+// Copyright 2023 flashbots
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package logger
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+type StateAccessTracer struct {
+	env     *vm.EVM
+	rwSet   *types.RWSet
+	touched map[common.Address]struct{}
+}
+
+// NewStateAccessTracer creates new StateAccessTracer
+// that collects the accounts and storage slots read and written
+// by all the txs traced with it, along with the addresses they touch
+// the way AccountTouchTracer does
+func NewStateAccessTracer() *StateAccessTracer {
+	return &StateAccessTracer{
+		rwSet:   types.NewRWSet(),
+		touched: make(map[common.Address]struct{}),
+	}
+}
+
+func (t *StateAccessTracer) RWSet() *types.RWSet {
+	return t.rwSet
+}
+
+func (t *StateAccessTracer) TouchedAddresses() []common.Address {
+	result := make([]common.Address, 0, len(t.touched))
+
+	for address := range t.touched {
+		result = append(result, address)
+	}
+	return result
+}
+
+func (t *StateAccessTracer) CaptureTxStart(uint64) {}
+
+func (t *StateAccessTracer) CaptureTxEnd(uint64) {}
+
+func (t *StateAccessTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, _ []byte, _ uint64, value *big.Int) {
+	t.env = env
+	t.touched[from] = struct{}{}
+	t.touched[to] = struct{}{}
+
+	// the sender pays for gas and increments its nonce
+	t.rwSet.ReadAccount(from)
+	t.rwSet.WriteAccount(from)
+	if create {
+		t.rwSet.ReadAccount(to)
+		t.rwSet.WriteAccount(to)
+	} else {
+		t.call(to, value)
+	}
+}
+
+func (t *StateAccessTracer) CaptureEnd([]byte, uint64, error) {}
+
+func (t *StateAccessTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, _ []byte, _ uint64, value *big.Int) {
+	t.touched[to] = struct{}{}
+
+	switch typ {
+	case vm.CREATE, vm.CREATE2:
+		t.rwSet.ReadAccount(from)
+		t.rwSet.WriteAccount(from)
+		t.rwSet.ReadAccount(to)
+		t.rwSet.WriteAccount(to)
+	case vm.SELFDESTRUCT:
+		t.rwSet.ReadAccount(from)
+		t.rwSet.WriteAccount(from)
+		t.rwSet.WriteAccount(to)
+	case vm.CALL:
+		if value != nil && value.Sign() > 0 {
+			t.rwSet.ReadAccount(from)
+			t.rwSet.WriteAccount(from)
+		}
+		t.call(to, value)
+	case vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		// to is the account whose code is executed, no value is transferred to it
+		t.call(to, nil)
+	}
+}
+
+// call records the call of to with value. Balance increments commute, so the coinbase and the accounts without code
+// are only written when value is transferred to them, whereas the accounts whose code is executed are also read.
+func (t *StateAccessTracer) call(to common.Address, value *big.Int) {
+	if value != nil && value.Sign() > 0 {
+		t.rwSet.WriteAccount(to)
+	}
+	if to != t.env.Context.Coinbase && t.env.StateDB.GetCodeSize(to) != 0 {
+		t.rwSet.ReadAccount(to)
+	}
+}
+
+func (t *StateAccessTracer) CaptureExit([]byte, uint64, error) {}
+
+func (t *StateAccessTracer) CaptureState(_ uint64, op vm.OpCode, _, _ uint64, scope *vm.ScopeContext, _ []byte, _ int, _ error) {
+	stackData := scope.Stack.Data()
+	stackLen := len(stackData)
+	switch {
+	case op == vm.SLOAD && stackLen >= 1:
+		t.rwSet.ReadStorage(scope.Contract.Address(), common.Hash(stackData[stackLen-1].Bytes32()))
+	case op == vm.SSTORE && stackLen >= 1:
+		t.rwSet.WriteStorage(scope.Contract.Address(), common.Hash(stackData[stackLen-1].Bytes32()))
+	case (op == vm.EXTCODECOPY || op == vm.EXTCODEHASH || op == vm.EXTCODESIZE || op == vm.BALANCE) && stackLen >= 1:
+		addr := common.Address(stackData[stackLen-1].Bytes20())
+		t.touched[addr] = struct{}{}
+		t.rwSet.ReadAccount(addr)
+	case op == vm.SELFDESTRUCT && stackLen >= 1:
+		t.touched[common.Address(stackData[stackLen-1].Bytes20())] = struct{}{}
+	case op == vm.SELFBALANCE:
+		t.rwSet.ReadAccount(scope.Contract.Address())
+	}
+}
+
+func (t *StateAccessTracer) CaptureFault(uint64, vm.OpCode, uint64, uint64, *vm.ScopeContext, int, error) {
+}
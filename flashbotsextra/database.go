@@ -7,6 +7,7 @@ import (
 	"time"
 
 	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
@@ -24,14 +25,15 @@ type IDatabaseService interface {
 	ConsumeBuiltBlock(block *types.Block, blockValue *big.Int, OrdersClosedAt time.Time, sealedAt time.Time,
 		commitedBundles []types.SimulatedBundle, allBundles []types.SimulatedBundle,
 		usedSbundles []types.UsedSBundle,
-		bidTrace *apiv1.BidTrace)
+		submission *builderapi.VersionedSubmitBlockRequest, relayResults []builderapi.RelaySubmissionResult)
 	GetPriorityBundles(ctx context.Context, blockNum int64, isHighPrio bool) ([]DbBundle, error)
 	GetLatestUuidBundles(ctx context.Context, blockNum int64) ([]types.LatestUuidBundle, error)
+	ConsumeObservedBids(bids []builderapi.ObservedBid)
 }
 
 type NilDbService struct{}
 
-func (NilDbService) ConsumeBuiltBlock(block *types.Block, _ *big.Int, _ time.Time, _ time.Time, _ []types.SimulatedBundle, _ []types.SimulatedBundle, _ []types.UsedSBundle, _ *apiv1.BidTrace) {
+func (NilDbService) ConsumeBuiltBlock(block *types.Block, _ *big.Int, _ time.Time, _ time.Time, _ []types.SimulatedBundle, _ []types.SimulatedBundle, _ []types.UsedSBundle, _ *builderapi.VersionedSubmitBlockRequest, _ []builderapi.RelaySubmissionResult) {
 }
 
 func (NilDbService) GetPriorityBundles(ctx context.Context, blockNum int64, isHighPrio bool) ([]DbBundle, error) {
@@ -42,6 +44,8 @@ func (NilDbService) GetLatestUuidBundles(ctx context.Context, blockNum int64) ([
 	return []types.LatestUuidBundle{}, nil
 }
 
+func (NilDbService) ConsumeObservedBids(_ []builderapi.ObservedBid) {}
+
 type DatabaseService struct {
 	db *sqlx.DB
 
@@ -256,6 +260,27 @@ func (ds *DatabaseService) insertUsedSBundleIds(tx *sqlx.Tx, ctx context.Context
 	return err
 }
 
+func (ds *DatabaseService) insertRelaySubmissions(ctx context.Context, blockId uint64, relayResults []builderapi.RelaySubmissionResult) error {
+	if len(relayResults) == 0 {
+		return nil
+	}
+
+	toInsert := make([]DbRelaySubmission, len(relayResults))
+	for i, result := range relayResults {
+		toInsert[i] = DbRelaySubmission{
+			BlockId:   blockId,
+			Relay:     result.Relay,
+			Success:   result.Err == nil,
+			LatencyMs: result.Latency.Milliseconds(),
+		}
+		if result.Err != nil {
+			toInsert[i].Error = result.Err.Error()
+		}
+	}
+	_, err := ds.db.NamedExecContext(ctx, "insert into built_blocks_relay_submissions (block_id, relay, success, error, latency_ms) values (:block_id, :relay, :success, :error, :latency_ms)", toInsert)
+	return err
+}
+
 type uuidBundle struct {
 	SimulatedBundle types.SimulatedBundle
 	UUID            uuid.UUID
@@ -264,7 +289,13 @@ type uuidBundle struct {
 func (ds *DatabaseService) ConsumeBuiltBlock(block *types.Block, blockValue *big.Int, ordersClosedAt time.Time, sealedAt time.Time,
 	commitedBundles []types.SimulatedBundle, allBundles []types.SimulatedBundle,
 	usedSbundles []types.UsedSBundle,
-	bidTrace *apiv1.BidTrace) {
+	submission *builderapi.VersionedSubmitBlockRequest, relayResults []builderapi.RelaySubmissionResult) {
+	bidTrace, err := submission.BidTrace()
+	if err != nil {
+		log.Error("could not get bid trace of built block", "err", err)
+		return
+	}
+
 	var allUUIDBundles = make([]uuidBundle, 0, len(allBundles))
 	for _, bundle := range allBundles {
 		allUUIDBundles = append(allUUIDBundles, uuidBundle{bundle, bundle.OriginalBundle.ComputeUUID()})
@@ -337,6 +368,42 @@ func (ds *DatabaseService) ConsumeBuiltBlock(block *types.Block, blockValue *big
 	err = tx.Commit()
 	if err != nil {
 		log.Error("could not commit DB trasnaction", "err", err)
+		return
+	}
+
+	// relay results are stored outside of the block transaction so that failing to store them does not lose the block
+	err = ds.insertRelaySubmissions(ctx, blockId, relayResults)
+	if err != nil {
+		log.Error("could not insert relay submissions", "err", err)
+	}
+}
+
+// ConsumeObservedBids stores the competing bids observed on the relays, bids already stored are ignored
+func (ds *DatabaseService) ConsumeObservedBids(bids []builderapi.ObservedBid) {
+	if len(bids) == 0 {
+		return
+	}
+
+	toInsert := make([]DbObservedBid, len(bids))
+	for i, bid := range bids {
+		toInsert[i] = DbObservedBid{
+			Relay:         bid.Relay,
+			Slot:          bid.Slot,
+			ParentHash:    bid.ParentHash.String(),
+			BlockHash:     bid.BlockHash.String(),
+			BuilderPubkey: bid.BuilderPubkey,
+			Value:         bid.Value.String(),
+			ReceivedAt:    bid.ReceivedAt,
+			ObservedAt:    bid.ObservedAt,
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
+	defer cancel()
+
+	_, err := ds.db.NamedExecContext(ctx, "insert into observed_bids (relay, slot, parent_hash, block_hash, builder_pubkey, value, received_at, observed_at) values (:relay, :slot, :parent_hash, :block_hash, :builder_pubkey, :value, :received_at, :observed_at) on conflict do nothing", toInsert)
+	if err != nil {
+		log.Error("could not insert observed bids", "err", err)
 	}
 }
 func (ds *DatabaseService) GetPriorityBundles(ctx context.Context, blockNum int64, isHighPrio bool) ([]DbBundle, error) {
@@ -6,7 +6,10 @@ import (
 	"testing"
 	"time"
 
+	bellatrixapi "github.com/attestantio/go-builder-client/api/bellatrix"
 	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
+	builderapi "github.com/ethereum/go-ethereum/builder/api"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/stretchr/testify/require"
@@ -119,13 +122,16 @@ func TestDatabaseBlockInsertion(t *testing.T) {
 		Success: true,
 	}
 
-	bidTrace := &apiv1.BidTrace{}
+	submission := &builderapi.VersionedSubmitBlockRequest{
+		Version:   consensusspec.DataVersionBellatrix,
+		Bellatrix: &bellatrixapi.SubmitBlockRequest{Message: &apiv1.BidTrace{}},
+	}
 
 	ocAt := time.Now().Add(-time.Hour).UTC()
 	sealedAt := time.Now().Add(-30 * time.Minute).UTC()
 	ds.ConsumeBuiltBlock(block, blockProfit, ocAt, sealedAt,
 		[]types.SimulatedBundle{simBundle1, simBundle2}, []types.SimulatedBundle{simBundle1, simBundle2, simBundle3, simBundle4},
-		[]types.UsedSBundle{usedSbundle}, bidTrace)
+		[]types.UsedSBundle{usedSbundle}, submission, nil)
 
 	var dbBlock BuiltBlock
 	require.NoError(t, ds.db.Get(&dbBlock, "select block_id, block_number, profit, slot, hash, gas_limit, gas_used, base_fee, parent_hash, timestamp, timestamp_datetime, orders_closed_at, sealed_at from built_blocks where hash = '0x9cc3ee47d091fea38c0187049cae56abe4e642eeb06c4832f06ec59f5dbce7ab'"))
@@ -30,6 +30,25 @@ type BuiltBlock struct {
 	SealedAt             time.Time `db:"sealed_at"`
 }
 
+type DbRelaySubmission struct {
+	BlockId   uint64 `db:"block_id"`
+	Relay     string `db:"relay"`
+	Success   bool   `db:"success"`
+	Error     string `db:"error"`
+	LatencyMs int64  `db:"latency_ms"`
+}
+
+type DbObservedBid struct {
+	Relay         string    `db:"relay"`
+	Slot          uint64    `db:"slot"`
+	ParentHash    string    `db:"parent_hash"`
+	BlockHash     string    `db:"block_hash"`
+	BuilderPubkey string    `db:"builder_pubkey"`
+	Value         string    `db:"value"`
+	ReceivedAt    time.Time `db:"received_at"`
+	ObservedAt    time.Time `db:"observed_at"`
+}
+
 type BuiltBlockBundle struct {
 	BlockId     uint64  `db:"block_id"`
 	BundleId    *uint64 `db:"bundle_id"`
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/big"
 	"sync/atomic"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
@@ -37,6 +38,7 @@ var (
 		ExpectedProfit:         nil,
 		ProfitThresholdPercent: defaultProfitThresholdPercent,
 		PriceCutoffPercent:     defaultPriceCutoffPercent,
+		RetryLimit:             defaultRetryLimit,
 	}
 )
 
@@ -81,6 +83,12 @@ type algorithmConfig struct {
 	// is 10 (i.e. 10%), then the minimum effective gas price included in the same bucket as the top transaction
 	// is (1000 * 10%) = 100 wei.
 	PriceCutoffPercent int
+	// RetryLimit is the number of times an order not committed due to low profit is retried by the bucket algorithms
+	RetryLimit int
+	// LocalSearchBudget is the time spent improving the block of the greedy algorithms by local search
+	LocalSearchBudget time.Duration
+	// SpeculativeOrders is the number of txs executed ahead in parallel by the greedy algorithm
+	SpeculativeOrders int
 }
 
 type chainData struct {
@@ -0,0 +1,188 @@
+package miner
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	errInvalidEnvironment = errors.New("environment not created by the miner")
+	errInvalidSnapshot    = errors.New("invalid environment snapshot")
+)
+
+// Environment is the block being built by a BlockBuildingAlgorithm. Orders are committed on top of the block one at a
+// time, an order failing to commit leaves the block unchanged. Snapshot and RevertToSnapshot undo the orders committed
+// since the snapshot, Copy returns an independent block to try alternative orderings on.
+type Environment interface {
+	// Header returns a copy of the header of the block being built
+	Header() *types.Header
+	// Profit returns the value of the block being built for the builder
+	Profit() *big.Int
+	// GasLeft returns the gas still available to the orders of the block
+	GasLeft() uint64
+	// Copy returns a deep copy of the block being built, its snapshots included
+	Copy() Environment
+
+	// CommitTx commits the transaction, failed transactions are not included
+	CommitTx(tx *types.Transaction) (*types.Receipt, error)
+	// CommitBundle commits every transaction of the simulated bundle or none of them
+	CommitBundle(bundle *types.SimulatedBundle) error
+	// CommitSBundle commits the simulated sbundle along with its refunds, the builder key must be set
+	CommitSBundle(sbundle *types.SimSBundle) error
+
+	// Snapshot returns the identifier of the current state of the block
+	Snapshot() (int, error)
+	// RevertToSnapshot undoes the orders committed since the snapshot, later snapshots are invalidated
+	RevertToSnapshot(id int) error
+}
+
+// algorithmEnvironment implements Environment on top of the worker environment, with the chain data and options of
+// the algorithm committing to it
+type algorithmEnvironment struct {
+	env      *environment
+	chData   chainData
+	algoConf algorithmConfig
+	key      *ecdsa.PrivateKey
+
+	snapshots []environmentSnapshot
+}
+
+// environmentSnapshot is the part of the environment restored on revert next to the state
+type environmentSnapshot struct {
+	gas     uint64
+	gasUsed uint64
+	profit  *big.Int
+	tcount  int
+	txs     int
+}
+
+func newAlgorithmEnvironment(env *environment, ctx *AlgorithmContext, opts AlgorithmOptions) *algorithmEnvironment {
+	return &algorithmEnvironment{
+		env:      env,
+		chData:   chainData{chainConfig: ctx.ChainConfig, chain: ctx.Chain, blacklist: ctx.Blacklist},
+		algoConf: *opts.algorithmConfig(),
+		key:      ctx.BuilderKey,
+	}
+}
+
+// wrap returns the Environment of env committing with the chain data and options of e
+func (e *algorithmEnvironment) wrap(env *environment) *algorithmEnvironment {
+	return &algorithmEnvironment{env: env, chData: e.chData, algoConf: e.algoConf, key: e.key}
+}
+
+// unwrapEnvironment returns the worker environment of the block built by an algorithm, the snapshots still taken on
+// its state are released
+func unwrapEnvironment(env Environment) (*environment, error) {
+	algoEnv, ok := env.(*algorithmEnvironment)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", errInvalidEnvironment, env)
+	}
+	for range algoEnv.snapshots {
+		if err := algoEnv.env.state.MultiTxSnapshotCommit(); err != nil {
+			return nil, err
+		}
+	}
+	algoEnv.snapshots = nil
+	return algoEnv.env, nil
+}
+
+func (e *algorithmEnvironment) Header() *types.Header {
+	return types.CopyHeader(e.env.header)
+}
+
+func (e *algorithmEnvironment) Profit() *big.Int {
+	return new(big.Int).Set(e.env.profit)
+}
+
+func (e *algorithmEnvironment) GasLeft() uint64 {
+	return e.env.gasPool.Gas()
+}
+
+func (e *algorithmEnvironment) Copy() Environment {
+	cpy := e.wrap(e.env.copy())
+	cpy.snapshots = make([]environmentSnapshot, len(e.snapshots))
+	for i, snapshot := range e.snapshots {
+		snapshot.profit = new(big.Int).Set(snapshot.profit)
+		cpy.snapshots[i] = snapshot
+	}
+	return cpy
+}
+
+func (e *algorithmEnvironment) CommitTx(tx *types.Transaction) (*types.Receipt, error) {
+	var receipt *types.Receipt
+	err := e.commit(func(changes *envChanges) (err error) {
+		receipt, _, err = changes.commitTx(tx, e.chData)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+func (e *algorithmEnvironment) CommitBundle(bundle *types.SimulatedBundle) error {
+	return e.commit(func(changes *envChanges) error {
+		return changes.commitBundle(bundle, e.chData, e.algoConf)
+	})
+}
+
+func (e *algorithmEnvironment) CommitSBundle(sbundle *types.SimSBundle) error {
+	return e.commit(func(changes *envChanges) error {
+		return changes.CommitSBundle(sbundle, e.chData, e.key, e.algoConf)
+	})
+}
+
+// commit applies the changes made by fn to the environment, or discards them if fn fails
+func (e *algorithmEnvironment) commit(fn func(changes *envChanges) error) error {
+	changes, err := newEnvChanges(e.env)
+	if err != nil {
+		return err
+	}
+	if err := fn(changes); err != nil {
+		if discardErr := changes.discard(); discardErr != nil {
+			log.Error("Failed to discard changes", "err", discardErr)
+		}
+		return err
+	}
+	return changes.apply()
+}
+
+func (e *algorithmEnvironment) Snapshot() (int, error) {
+	if err := e.env.state.NewMultiTxSnapshot(); err != nil {
+		return 0, err
+	}
+	e.snapshots = append(e.snapshots, environmentSnapshot{
+		gas:     e.env.gasPool.Gas(),
+		gasUsed: e.env.header.GasUsed,
+		profit:  new(big.Int).Set(e.env.profit),
+		tcount:  e.env.tcount,
+		txs:     len(e.env.txs),
+	})
+	return len(e.snapshots) - 1, nil
+}
+
+func (e *algorithmEnvironment) RevertToSnapshot(id int) error {
+	if id < 0 || id >= len(e.snapshots) {
+		return fmt.Errorf("%w: %d", errInvalidSnapshot, id)
+	}
+	snapshot := e.snapshots[id]
+	for len(e.snapshots) > id {
+		if err := e.env.state.MultiTxSnapshotRevert(); err != nil {
+			return err
+		}
+		e.snapshots = e.snapshots[:len(e.snapshots)-1]
+	}
+
+	e.env.gasPool.SetGas(snapshot.gas)
+	e.env.header.GasUsed = snapshot.gasUsed
+	e.env.profit.Set(snapshot.profit)
+	e.env.tcount = snapshot.tcount
+	e.env.txs = e.env.txs[:snapshot.txs]
+	e.env.receipts = e.env.receipts[:snapshot.txs]
+	return nil
+}
@@ -0,0 +1,149 @@
+package miner_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/miner"
+	"github.com/stretchr/testify/require"
+)
+
+var errGasLimitReached = errors.New("gas limit reached")
+
+// bestOfTwoAlgoType is an algorithm registered from outside the miner package, it builds the block with the bundles
+// first and with the txs first and keeps the most profitable one
+var bestOfTwoAlgoType, bestOfTwoAlgoErr = miner.RegisterAlgorithm("best-of-two", func(ctx *miner.AlgorithmContext, opts miner.AlgorithmOptions) miner.BlockBuildingAlgorithm {
+	return miner.AlgorithmFunc(buildBestOfTwo)
+}, miner.AlgorithmOptions{})
+
+func buildBestOfTwo(env miner.Environment, simBundles []types.SimulatedBundle, _ []*types.SimSBundle, transactions map[common.Address]types.Transactions) (miner.Environment, []types.SimulatedBundle, []types.UsedSBundle) {
+	bundlesFirst, txsFirst := env.Copy(), env.Copy()
+
+	bundles := commitBundles(bundlesFirst, simBundles)
+	commitAccounts(bundlesFirst, transactions)
+
+	commitAccounts(txsFirst, transactions)
+	txsFirstBundles := commitBundles(txsFirst, simBundles)
+
+	if txsFirst.Profit().Cmp(bundlesFirst.Profit()) > 0 {
+		return txsFirst, txsFirstBundles, nil
+	}
+	return bundlesFirst, bundles, nil
+}
+
+func commitBundles(env miner.Environment, simBundles []types.SimulatedBundle) []types.SimulatedBundle {
+	var committed []types.SimulatedBundle
+	for _, bundle := range simBundles {
+		if bundle.TotalGasUsed <= env.GasLeft() && env.CommitBundle(&bundle) == nil {
+			committed = append(committed, bundle)
+		}
+	}
+	return committed
+}
+
+// commitAccounts commits either every tx of an account or none of them
+func commitAccounts(env miner.Environment, transactions map[common.Address]types.Transactions) {
+	for _, txs := range transactions {
+		snapshot, err := env.Snapshot()
+		if err != nil {
+			return
+		}
+		for _, tx := range txs {
+			if tx.Gas() > env.GasLeft() {
+				err = errGasLimitReached
+			} else {
+				_, err = env.CommitTx(tx)
+			}
+			if err != nil {
+				if err := env.RevertToSnapshot(snapshot); err != nil {
+					return
+				}
+				break
+			}
+		}
+	}
+}
+
+func TestExternalAlgorithm(t *testing.T) {
+	require.NoError(t, bestOfTwoAlgoErr)
+	algoType, err := miner.AlgoTypeFlagToEnum("best-of-two")
+	require.NoError(t, err)
+	require.Equal(t, bestOfTwoAlgoType, algoType)
+
+	setup, err := miner.NewTestAlgorithmSetup(miner.AlgorithmOptions{}, 4, 3, 2)
+	require.NoError(t, err)
+	// the txs of an account are only committed if all of them are, the nonce of the last tx of the last signer is too high
+	last := setup.Transactions[setup.Signers[3]]
+	setup.Transactions[setup.Signers[3]] = types.Transactions{last[0], last[2]}
+
+	algo, err := miner.NewBlockBuildingAlgorithm(bestOfTwoAlgoType, setup.Context, miner.AlgorithmOptions{})
+	require.NoError(t, err)
+	result, bundles, usedSBundles := algo.BuildBlock(setup.Env, setup.Bundles, nil, setup.Transactions)
+
+	require.Equal(t, setup.Bundles, bundles)
+	require.Empty(t, usedSBundles)
+	require.Len(t, miner.EnvironmentTxs(result), 3*3+2*2)
+	require.Positive(t, result.Profit().Sign())
+	require.Less(t, result.GasLeft(), setup.Env.GasLeft())
+
+	// the environment the algorithm was given is untouched
+	require.Empty(t, miner.EnvironmentTxs(setup.Env))
+	require.Zero(t, setup.Env.Profit().Sign())
+	require.Equal(t, uint64(0), setup.Env.Header().GasUsed)
+}
+
+func TestEnvironmentSnapshots(t *testing.T) {
+	setup, err := miner.NewTestAlgorithmSetup(miner.AlgorithmOptions{}, 2, 2, 1)
+	require.NoError(t, err)
+	env := setup.Env
+	txs := setup.Transactions[setup.Signers[0]]
+
+	// failed commits leave the block untouched
+	_, err = env.CommitTx(txs[1])
+	require.Error(t, err)
+	require.Empty(t, miner.EnvironmentTxs(env))
+
+	receipt, err := env.CommitTx(txs[0])
+	require.NoError(t, err)
+	require.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+	var (
+		gasLeft = env.GasLeft()
+		profit  = env.Profit()
+	)
+
+	first, err := env.Snapshot()
+	require.NoError(t, err)
+	_, err = env.CommitTx(txs[1])
+	require.NoError(t, err)
+	second, err := env.Snapshot()
+	require.NoError(t, err)
+	require.NoError(t, env.CommitBundle(&setup.Bundles[0]))
+	require.Len(t, miner.EnvironmentTxs(env), 4)
+
+	// copies are independent of the block they are copied from, snapshots included
+	cpy := env.Copy()
+	require.NoError(t, cpy.RevertToSnapshot(second))
+	require.Len(t, miner.EnvironmentTxs(cpy), 2)
+	require.Len(t, miner.EnvironmentTxs(env), 4)
+
+	// reverting to a snapshot invalidates the later ones
+	require.NoError(t, env.RevertToSnapshot(first))
+	require.Equal(t, types.Transactions{txs[0]}, miner.EnvironmentTxs(env))
+	require.Equal(t, gasLeft, env.GasLeft())
+	require.Equal(t, profit, env.Profit())
+	require.Equal(t, receipt.GasUsed, env.Header().GasUsed)
+	require.Error(t, env.RevertToSnapshot(second))
+
+	// the txs reverted can be committed again
+	_, err = env.CommitTx(txs[1])
+	require.NoError(t, err)
+	require.NoError(t, env.CommitBundle(&setup.Bundles[0]))
+	require.Equal(t, miner.EnvironmentTxs(cpy), miner.EnvironmentTxs(env)[:2])
+	require.Len(t, miner.EnvironmentTxs(env), 4)
+
+	// sbundles need the builder key
+	require.Error(t, env.CommitSBundle(&types.SimSBundle{}))
+	require.Len(t, miner.EnvironmentTxs(env), 4)
+}
@@ -21,6 +21,10 @@ type greedyBuilder struct {
 	builderKey       *ecdsa.PrivateKey
 	interrupt        *int32
 	algoConf         algorithmConfig
+	// trace records the orders for the local search, nil if it is disabled
+	trace *orderTrace
+	// conflicts is the conflict graph of the bundles evaluated as alternatives, nil if it is disabled
+	conflicts *BundleConflictGraph
 }
 
 func newGreedyBuilder(
@@ -47,6 +51,8 @@ func (b *greedyBuilder) mergeOrdersIntoEnvDiff(
 	var (
 		usedBundles  []types.SimulatedBundle
 		usedSbundles []types.UsedSBundle
+
+		speculative = newSpeculativeExecutor(b.chainData, envDiff, b.algoConf.SpeculativeOrders)
 	)
 	for {
 		order := orders.Peek()
@@ -56,13 +62,14 @@ func (b *greedyBuilder) mergeOrdersIntoEnvDiff(
 
 		if tx := order.Tx(); tx != nil {
 			log.Info("tx", "hash", tx.Hash().String())
-			receipt, skip, err := envDiff.commitTx(tx, b.chainData)
+			receipt, skip, err := speculative.commitTx(envDiff, orders, tx, b.chainData)
 			switch skip {
 			case shiftTx:
 				orders.Shift()
 			case popTx:
 				orders.Pop()
 			}
+			b.trace.record(order, err != nil)
 
 			if err != nil {
 				log.Trace("could not apply tx", "hash", tx.Hash(), "err", err)
@@ -77,6 +84,7 @@ func (b *greedyBuilder) mergeOrdersIntoEnvDiff(
 			//log.Debug("buildBlock considering bundle", "egp", bundle.MevGasPrice.String(), "hash", bundle.OriginalBundle.Hash)
 			err := envDiff.commitBundle(bundle, b.chainData, b.interrupt, b.algoConf)
 			orders.Pop()
+			b.trace.record(order, err != nil)
 			if err != nil {
 				log.Trace("Could not apply bundle", "bundle", bundle.OriginalBundle.Hash, "err", err)
 				continue
@@ -90,6 +98,7 @@ func (b *greedyBuilder) mergeOrdersIntoEnvDiff(
 			}
 			err := envDiff.commitSBundle(sbundle, b.chainData, b.interrupt, b.builderKey, b.algoConf)
 			orders.Pop()
+			b.trace.record(order, err != nil)
 			if err != nil {
 				log.Trace("Could not apply sbundle", "bundle", sbundle.Bundle.Hash(), "err", err)
 				usedEntry.Success = false
@@ -102,10 +111,32 @@ func (b *greedyBuilder) mergeOrdersIntoEnvDiff(
 			usedSbundles = append(usedSbundles, usedEntry)
 		}
 	}
+	if speculative != nil {
+		log.Debug("Merged orders with speculative execution", "hits", speculative.hits, "misses", speculative.misses)
+	}
 	return usedBundles, usedSbundles
 }
 
 func (b *greedyBuilder) buildBlock(simBundles []types.SimulatedBundle, simSBundles []*types.SimSBundle, transactions map[common.Address]types.Transactions) (*environment, []types.SimulatedBundle, []types.UsedSBundle) {
+	if b.algoConf.LocalSearchBudget > 0 {
+		b.trace = new(orderTrace)
+	}
+	env, usedBundles, usedSbundles := b.merge(simBundles, simSBundles, copyTransactions(transactions))
+	if b.conflicts != nil {
+		env, usedBundles, usedSbundles = b.mergeAlternatives(simBundles, simSBundles, transactions, env, usedBundles, usedSbundles)
+	}
+
+	if b.trace != nil {
+		search := newLocalSearch(b.inputEnvironment.copy(), b.chainData, b.builderKey, b.interrupt, b.algoConf)
+		if env, bundles, sbundles, ok := search.improve(b.trace, env.profit); ok {
+			return env, bundles, sbundles
+		}
+	}
+	return env, usedBundles, usedSbundles
+}
+
+// merge commits the orders in price order on top of a copy of the input environment
+func (b *greedyBuilder) merge(simBundles []types.SimulatedBundle, simSBundles []*types.SimSBundle, transactions map[common.Address]types.Transactions) (*environment, []types.SimulatedBundle, []types.UsedSBundle) {
 	orders := types.NewTransactionsByPriceAndNonce(b.inputEnvironment.signer, transactions, simBundles, simSBundles, b.inputEnvironment.header.BaseFee)
 	envDiff := newEnvironmentDiff(b.inputEnvironment.copy())
 	usedBundles, usedSbundles := b.mergeOrdersIntoEnvDiff(envDiff, orders)
@@ -163,8 +163,6 @@ func (b *greedyBucketsBuilder) mergeOrdersIntoEnvDiff(
 		return nil, nil
 	}
 
-	const retryLimit = 1
-
 	var (
 		baseFee            = envDiff.baseEnvironment.header.BaseFee
 		retryMap           = make(map[*types.TxWithMinerFee]int)
@@ -186,7 +184,7 @@ func (b *greedyBucketsBuilder) mergeOrdersIntoEnvDiff(
 		if order == nil {
 			if len(transactions) != 0 {
 				SortInPlaceByProfit(baseFee, transactions, b.gasUsedMap)
-				bundles, sbundles := b.commit(envDiff, transactions, orders, b.gasUsedMap, retryMap, retryLimit)
+				bundles, sbundles := b.commit(envDiff, transactions, orders, b.gasUsedMap, retryMap, b.algoConf.RetryLimit)
 				usedBundles = append(usedBundles, bundles...)
 				usedSbundles = append(usedSbundles, sbundles...)
 				transactions = nil
@@ -203,7 +201,7 @@ func (b *greedyBucketsBuilder) mergeOrdersIntoEnvDiff(
 		} else {
 			if len(transactions) != 0 {
 				SortInPlaceByProfit(baseFee, transactions, b.gasUsedMap)
-				bundles, sbundles := b.commit(envDiff, transactions, orders, b.gasUsedMap, retryMap, retryLimit)
+				bundles, sbundles := b.commit(envDiff, transactions, orders, b.gasUsedMap, retryMap, b.algoConf.RetryLimit)
 				usedBundles = append(usedBundles, bundles...)
 				usedSbundles = append(usedSbundles, sbundles...)
 				transactions = nil
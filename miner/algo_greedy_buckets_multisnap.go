@@ -178,8 +178,6 @@ func (b *greedyBucketsMultiSnapBuilder) mergeOrdersAndApplyToEnv(
 		return b.inputEnvironment, nil, nil
 	}
 
-	const retryLimit = 1
-
 	var (
 		baseFee            = changes.env.header.BaseFee
 		retryMap           = make(map[*types.TxWithMinerFee]int)
@@ -201,7 +199,7 @@ func (b *greedyBucketsMultiSnapBuilder) mergeOrdersAndApplyToEnv(
 		if order == nil {
 			if len(transactions) != 0 {
 				SortInPlaceByProfit(baseFee, transactions, b.gasUsedMap)
-				bundles, sbundles := b.commit(changes, transactions, orders, b.gasUsedMap, retryMap, retryLimit)
+				bundles, sbundles := b.commit(changes, transactions, orders, b.gasUsedMap, retryMap, b.algoConf.RetryLimit)
 				usedBundles = append(usedBundles, bundles...)
 				usedSbundles = append(usedSbundles, sbundles...)
 				transactions = nil
@@ -218,7 +216,7 @@ func (b *greedyBucketsMultiSnapBuilder) mergeOrdersAndApplyToEnv(
 		} else {
 			if len(transactions) != 0 {
 				SortInPlaceByProfit(baseFee, transactions, b.gasUsedMap)
-				bundles, sbundles := b.commit(changes, transactions, orders, b.gasUsedMap, retryMap, retryLimit)
+				bundles, sbundles := b.commit(changes, transactions, orders, b.gasUsedMap, retryMap, b.algoConf.RetryLimit)
 				usedBundles = append(usedBundles, bundles...)
 				usedSbundles = append(usedSbundles, sbundles...)
 				transactions = nil
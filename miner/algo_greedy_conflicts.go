@@ -0,0 +1,92 @@
+package miner
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// maxConflictAlternatives is the maximum number of alternative blocks the greedy algorithm builds for the conflicting
+// bundles and sbundles of a block
+const maxConflictAlternatives = 8
+
+// useConflicts makes the greedy algorithm evaluate the conflicting bundles and sbundles of the graph as alternatives
+func (b *greedyBuilder) useConflicts(conflicts *BundleConflictGraph) {
+	b.conflicts = conflicts
+}
+
+// mergeAlternatives evaluates the conflicting bundles and sbundles left out of the greedy block as alternatives to the
+// bundles they conflict with: for each of them the block is merged again without the bundles it conflicts with. The
+// independent bundles are committed the same way in every block. The most profitable block is returned.
+func (b *greedyBuilder) mergeAlternatives(
+	simBundles []types.SimulatedBundle, simSBundles []*types.SimSBundle, transactions map[common.Address]types.Transactions,
+	env *environment, usedBundles []types.SimulatedBundle, usedSbundles []types.UsedSBundle,
+) (*environment, []types.SimulatedBundle, []types.UsedSBundle) {
+	committed := make(map[common.Hash]struct{}, len(usedBundles)+len(usedSbundles))
+	for _, bundle := range usedBundles {
+		committed[bundle.OriginalBundle.Hash] = struct{}{}
+	}
+	for _, sbundle := range usedSbundles {
+		if sbundle.Success {
+			committed[sbundle.Bundle.Hash()] = struct{}{}
+		}
+	}
+
+	// the local search starts from the greedy block, the alternatives are not traced
+	trace := b.trace
+	b.trace = nil
+	defer func() { b.trace = trace }()
+
+	var alternatives int
+	for _, group := range b.conflicts.Groups() {
+		for _, hash := range group {
+			if _, ok := committed[hash]; ok {
+				continue
+			}
+			if alternatives == maxConflictAlternatives || checkInterrupt(b.interrupt) {
+				return env, usedBundles, usedSbundles
+			}
+			alternatives++
+
+			excluded := make(map[common.Hash]struct{})
+			for _, other := range b.conflicts.Conflicting(hash) {
+				excluded[other] = struct{}{}
+			}
+			altEnv, altBundles, altSbundles := b.merge(excludeBundles(simBundles, excluded), excludeSBundles(simSBundles, excluded), copyTransactions(transactions))
+			if altEnv.profit.Cmp(env.profit) > 0 {
+				log.Trace("Conflicting bundle alternative improved the block", "bundle", hash, "profit", altEnv.profit)
+				env, usedBundles, usedSbundles = altEnv, altBundles, altSbundles
+			}
+		}
+	}
+	return env, usedBundles, usedSbundles
+}
+
+func excludeBundles(bundles []types.SimulatedBundle, excluded map[common.Hash]struct{}) []types.SimulatedBundle {
+	result := make([]types.SimulatedBundle, 0, len(bundles))
+	for _, bundle := range bundles {
+		if _, ok := excluded[bundle.OriginalBundle.Hash]; !ok {
+			result = append(result, bundle)
+		}
+	}
+	return result
+}
+
+func excludeSBundles(sbundles []*types.SimSBundle, excluded map[common.Hash]struct{}) []*types.SimSBundle {
+	result := make([]*types.SimSBundle, 0, len(sbundles))
+	for _, sbundle := range sbundles {
+		if _, ok := excluded[sbundle.Bundle.Hash()]; !ok {
+			result = append(result, sbundle)
+		}
+	}
+	return result
+}
+
+// copyTransactions copies transactions, the orders consume the txs they are created with
+func copyTransactions(transactions map[common.Address]types.Transactions) map[common.Address]types.Transactions {
+	cpy := make(map[common.Address]types.Transactions, len(transactions))
+	for from, txs := range transactions {
+		cpy[from] = txs
+	}
+	return cpy
+}
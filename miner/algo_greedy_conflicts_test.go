@@ -0,0 +1,43 @@
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGreedyConflictAlternatives(t *testing.T) {
+	statedb, chData, signers := genTestSetup(GasLimit)
+	env := newEnvironment(chData, statedb, signers.addresses[0], GasLimit, big.NewInt(1))
+
+	// both bundles spend the same nonce, the cheaper one per gas pays more to the coinbase
+	calldata := append(make([]byte, 12), signers.addresses[0].Bytes()...)
+	signers.nonces[1] = 0
+	transfer := signers.signTx(1, 21000, big.NewInt(100), big.NewInt(200), signers.addresses[2], big.NewInt(0), nil)
+	signers.nonces[1] = 0
+	payment := signers.signTx(1, 60000, big.NewInt(1), big.NewInt(200), payProxyAddress, big.NewInt(3_000_000), calldata)
+
+	var simBundles []types.SimulatedBundle
+	for _, tx := range []*types.Transaction{transfer, payment} {
+		simBundle, err := simulateBundle(env.copy(), types.MevBundle{Txs: types.Transactions{tx}, Hash: tx.Hash()}, chData, nil)
+		require.NoError(t, err)
+		simBundle.RWSet = types.NewRWSet()
+		simBundle.RWSet.ReadAccount(signers.addresses[1])
+		simBundle.RWSet.WriteAccount(signers.addresses[1])
+		simBundles = append(simBundles, simBundle)
+	}
+	require.Greater(t, simBundles[0].MevGasPrice.Cmp(simBundles[1].MevGasPrice), 0)
+
+	algoConf := defaultAlgorithmConfig
+	greedy, bundles, _ := newGreedyBuilder(chData.chain, chData.chainConfig, &algoConf, nil, env, nil, nil).buildBlock(simBundles, nil, nil)
+	require.Equal(t, []types.SimulatedBundle{simBundles[0]}, bundles)
+
+	builder := newGreedyBuilder(chData.chain, chData.chainConfig, &algoConf, nil, env, nil, nil)
+	builder.useConflicts(NewBundleConflictGraph(simBundles, nil))
+	result, bundles, _ := builder.buildBlock(simBundles, nil, nil)
+	require.Equal(t, []types.SimulatedBundle{simBundles[1]}, bundles)
+	require.Greater(t, result.profit.Cmp(greedy.profit), 0)
+	require.Equal(t, types.Transactions{payment}, types.Transactions(result.txs))
+}
@@ -47,6 +47,15 @@ func (b *greedyMultiSnapBuilder) buildBlock(simBundles []types.SimulatedBundle,
 		usedSbundles []types.UsedSBundle
 	)
 
+	// the local search starts from the input environment, which is modified in place
+	var (
+		trace     *orderTrace
+		searchEnv *environment
+	)
+	if b.algoConf.LocalSearchBudget > 0 {
+		trace, searchEnv = new(orderTrace), b.inputEnvironment.copy()
+	}
+
 	changes, err := newEnvChanges(b.inputEnvironment)
 	if err != nil {
 		log.Error("Failed to create new environment changes", "err", err)
@@ -112,6 +121,7 @@ func (b *greedyMultiSnapBuilder) buildBlock(simBundles []types.SimulatedBundle,
 			usedSbundles = append(usedSbundles, usedEntry)
 		}
 
+		trace.record(order, orderFailed)
 		if orderFailed {
 			if err := changes.env.state.MultiTxSnapshotRevert(); err != nil {
 				log.Error("Failed to revert snapshot", "err", err)
@@ -130,5 +140,12 @@ func (b *greedyMultiSnapBuilder) buildBlock(simBundles []types.SimulatedBundle,
 		return b.inputEnvironment, usedBundles, usedSbundles
 	}
 
+	if trace != nil {
+		search := newLocalSearch(searchEnv, b.chainData, b.builderKey, b.interrupt, b.algoConf)
+		if env, bundles, sbundles, ok := search.improve(trace, changes.env.profit); ok {
+			return env, bundles, sbundles
+		}
+	}
+
 	return changes.env, usedBundles, usedSbundles
 }
@@ -0,0 +1,317 @@
+package miner
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// speculativeAccount is the state of an account a speculatively executed tx depends on
+type speculativeAccount struct {
+	// strict accounts were read by the tx, their balance and nonce must not change, only the code and the emptiness
+	// of the accounts the tx touched or credited must not change
+	strict   bool
+	balance  *big.Int
+	nonce    uint64
+	codeHash common.Hash
+	empty    bool
+}
+
+// speculativeTx is a tx executed ahead of its turn on a copy of the state, along with the state it read and the
+// changes it made
+type speculativeTx struct {
+	receipt  *types.Receipt
+	logs     []*types.Log
+	accounts map[common.Address]*speculativeAccount
+	storage  map[types.StorageSlot]common.Hash
+
+	balanceChanges map[common.Address]*big.Int
+	nonceChanges   map[common.Address]uint64
+	storageChanges map[types.StorageSlot]common.Hash
+}
+
+// speculativeExecutor executes the next txs of the greedy merge in parallel on copies of the state. The result of a
+// tx is reused when it is its turn, provided the state it read has not changed since, instead of executing it again.
+// Only txs succeeding without creating or destroying contracts are reused, the others are executed again in turn.
+type speculativeExecutor struct {
+	chainData chainData
+	orders    int
+	// results are the speculatively executed txs by hash, nil for the txs that can not be reused
+	results map[common.Hash]*speculativeTx
+	// hits and misses count the txs committed with and without reusing their speculative execution
+	hits, misses int
+}
+
+// newSpeculativeExecutor creates a speculative executor executing orders txs ahead, it returns nil if speculative
+// execution is disabled or not supported, with a blacklist or before byzantium
+func newSpeculativeExecutor(chData chainData, envDiff *environmentDiff, orders int) *speculativeExecutor {
+	if orders <= 0 || len(chData.blacklist) != 0 || !chData.chainConfig.IsByzantium(envDiff.header.Number) {
+		return nil
+	}
+	return &speculativeExecutor{
+		chainData: chData,
+		orders:    orders,
+		results:   make(map[common.Hash]*speculativeTx),
+	}
+}
+
+// commitTx commits the tx at the head of orders to envDiff, reusing its speculative execution when possible
+func (s *speculativeExecutor) commitTx(envDiff *environmentDiff, orders *types.TransactionsByPriceAndNonce, tx *types.Transaction, chData chainData) (*types.Receipt, int, error) {
+	if s == nil {
+		return envDiff.commitTx(tx, chData)
+	}
+
+	result, found := s.results[tx.Hash()]
+	if !found {
+		s.speculate(envDiff, orders)
+		result = s.results[tx.Hash()]
+	}
+	delete(s.results, tx.Hash())
+
+	if result != nil && s.valid(envDiff, tx, result) {
+		s.hits++
+		if metrics.EnabledBuilder {
+			speculativeHitMeter.Mark(1)
+		}
+		return s.apply(envDiff, tx, result), shiftTx, nil
+	}
+	s.misses++
+	if metrics.EnabledBuilder {
+		speculativeMissMeter.Mark(1)
+	}
+	return envDiff.commitTx(tx, chData)
+}
+
+// speculate executes the next txs of orders not executed yet in parallel, one tx per sender
+func (s *speculativeExecutor) speculate(envDiff *environmentDiff, orders *types.TransactionsByPriceAndNonce) {
+	var (
+		next    = orders.DeepCopy()
+		txs     = make([]*types.Transaction, 0, s.orders)
+		senders = make(map[common.Address]struct{}, s.orders)
+	)
+	for len(txs) < s.orders {
+		order := next.Peek()
+		if order == nil {
+			break
+		}
+		tx := order.Tx()
+		if tx == nil {
+			next.Pop()
+			continue
+		}
+		next.Shift()
+
+		// the next txs of a sender fail on the current state
+		from, err := types.Sender(envDiff.baseEnvironment.signer, tx)
+		if err != nil {
+			continue
+		}
+		if _, found := senders[from]; found {
+			continue
+		}
+		senders[from] = struct{}{}
+		if _, found := s.results[tx.Hash()]; !found {
+			txs = append(txs, tx)
+		}
+	}
+
+	var (
+		wg      sync.WaitGroup
+		tracers = make([]*logger.StateAccessTracer, len(txs))
+		states  = make([]*state.StateDB, len(txs))
+		results = make([]*types.Receipt, len(txs))
+
+		availableGas = envDiff.gasPool.Gas()
+		usedGas      = envDiff.header.GasUsed
+	)
+	for i, tx := range txs {
+		wg.Add(1)
+		go func(i int, tx *types.Transaction, statedb *state.StateDB) {
+			defer wg.Done()
+
+			tracers[i] = logger.NewStateAccessTracer()
+			config := *s.chainData.chain.GetVMConfig()
+			config.Tracer = tracers[i]
+			config.Debug = true
+
+			var (
+				gasPool = new(core.GasPool).AddGas(availableGas)
+				gasUsed = usedGas
+			)
+			statedb.SetTxContext(tx.Hash(), 0)
+			receipt, err := core.ApplyTransaction(s.chainData.chainConfig, s.chainData.chain, &envDiff.baseEnvironment.coinbase,
+				gasPool, statedb, envDiff.header, tx, &gasUsed, config, nil)
+			if err == nil {
+				states[i], results[i] = statedb, receipt
+			}
+		}(i, tx, envDiff.state.Copy())
+	}
+	wg.Wait()
+
+	// the state the txs were executed on is read once they are done, envDiff is not modified in the meantime
+	for i, tx := range txs {
+		s.results[tx.Hash()] = nil
+		if results[i] != nil && tx.To() != nil {
+			s.results[tx.Hash()] = s.record(envDiff, states[i], tracers[i], results[i])
+		}
+	}
+}
+
+// record captures the state the tx read on envDiff and its changes on statedb, it returns nil if the tx created or
+// destroyed an account it can not be reused
+func (s *speculativeExecutor) record(envDiff *environmentDiff, statedb *state.StateDB, tracer *logger.StateAccessTracer, receipt *types.Receipt) *speculativeTx {
+	var (
+		rwSet    = tracer.RWSet()
+		coinbase = envDiff.baseEnvironment.coinbase
+		result   = &speculativeTx{
+			receipt:        receipt,
+			logs:           receipt.Logs,
+			accounts:       make(map[common.Address]*speculativeAccount),
+			storage:        make(map[types.StorageSlot]common.Hash),
+			balanceChanges: make(map[common.Address]*big.Int),
+			nonceChanges:   make(map[common.Address]uint64),
+			storageChanges: make(map[types.StorageSlot]common.Hash),
+		}
+	)
+	addAccount := func(address common.Address, strict bool) {
+		if account, found := result.accounts[address]; found {
+			account.strict = account.strict || strict
+			return
+		}
+		result.accounts[address] = &speculativeAccount{
+			strict:   strict,
+			balance:  envDiff.state.GetBalance(address),
+			nonce:    envDiff.state.GetNonce(address),
+			codeHash: envDiff.state.GetCodeHash(address),
+			empty:    envDiff.state.Empty(address),
+		}
+	}
+	for address := range rwSet.AccountReads {
+		addAccount(address, true)
+	}
+	for address := range rwSet.AccountWrites {
+		addAccount(address, false)
+	}
+	for _, address := range tracer.TouchedAddresses() {
+		addAccount(address, false)
+	}
+	// the coinbase is credited with the fees outside of the evm
+	addAccount(coinbase, false)
+
+	for address, account := range result.accounts {
+		if !sameCode(statedb.GetCodeHash(address), account.codeHash) || (envDiff.state.Exist(address) && !statedb.Exist(address)) {
+			return nil
+		}
+		if _, written := rwSet.AccountWrites[address]; !written && address != coinbase {
+			continue
+		}
+		if balance := statedb.GetBalance(address); balance.Cmp(account.balance) != 0 || address == coinbase {
+			result.balanceChanges[address] = new(big.Int).Sub(balance, account.balance)
+		}
+		if nonce := statedb.GetNonce(address); nonce != account.nonce {
+			result.nonceChanges[address] = nonce
+		}
+	}
+
+	for slot := range rwSet.StorageReads {
+		result.storage[slot] = envDiff.state.GetState(slot.Address, slot.Key)
+	}
+	for slot := range rwSet.StorageWrites {
+		before := envDiff.state.GetState(slot.Address, slot.Key)
+		result.storage[slot] = before
+		if after := statedb.GetState(slot.Address, slot.Key); after != before {
+			result.storageChanges[slot] = after
+		}
+	}
+	return result
+}
+
+// sameCode reports whether the code hashes are equal, the accounts that do not exist have no code
+func sameCode(a, b common.Hash) bool {
+	if a == (common.Hash{}) {
+		a = types.EmptyCodeHash
+	}
+	if b == (common.Hash{}) {
+		b = types.EmptyCodeHash
+	}
+	return a == b
+}
+
+// valid reports whether the state the speculatively executed tx read is unchanged on envDiff
+func (s *speculativeExecutor) valid(envDiff *environmentDiff, tx *types.Transaction, result *speculativeTx) bool {
+	if envDiff.gasPool.Gas() < tx.Gas() {
+		return false
+	}
+	for address, account := range result.accounts {
+		if !sameCode(envDiff.state.GetCodeHash(address), account.codeHash) || envDiff.state.Empty(address) != account.empty {
+			return false
+		}
+		if account.strict && (envDiff.state.GetBalance(address).Cmp(account.balance) != 0 || envDiff.state.GetNonce(address) != account.nonce) {
+			return false
+		}
+	}
+	for slot, value := range result.storage {
+		if envDiff.state.GetState(slot.Address, slot.Key) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// apply commits the changes of the speculatively executed tx to envDiff the way envDiff.commitTx would have
+func (s *speculativeExecutor) apply(envDiff *environmentDiff, tx *types.Transaction, result *speculativeTx) *types.Receipt {
+	var (
+		header    = envDiff.header
+		blockHash = header.Hash()
+		statedb   = envDiff.state
+	)
+	statedb.SetTxContext(tx.Hash(), envDiff.baseEnvironment.tcount+len(envDiff.newTxs))
+	for address, change := range result.balanceChanges {
+		if change.Sign() < 0 {
+			statedb.SubBalance(address, new(big.Int).Neg(change))
+		} else {
+			statedb.AddBalance(address, change)
+		}
+	}
+	for address, nonce := range result.nonceChanges {
+		statedb.SetNonce(address, nonce)
+	}
+	for slot, value := range result.storageChanges {
+		statedb.SetState(slot.Address, slot.Key, value)
+	}
+	for _, log := range result.logs {
+		cpy := *log
+		statedb.AddLog(&cpy)
+	}
+	statedb.Finalise(true)
+
+	gasUsed := result.receipt.GasUsed
+	_ = envDiff.gasPool.SubGas(gasUsed)
+	header.GasUsed += gasUsed
+
+	receipt := &types.Receipt{
+		Type:              result.receipt.Type,
+		Status:            result.receipt.Status,
+		CumulativeGasUsed: header.GasUsed,
+		TxHash:            tx.Hash(),
+		GasUsed:           gasUsed,
+		Logs:              statedb.GetLogs(tx.Hash(), header.Number.Uint64(), blockHash),
+		BlockHash:         blockHash,
+		BlockNumber:       header.Number,
+		TransactionIndex:  uint(statedb.TxIndex()),
+	}
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+	// the effective tip was checked when the tx was executed
+	gasPrice, _ := tx.EffectiveGasTip(header.BaseFee)
+	envDiff.newProfit = envDiff.newProfit.Add(envDiff.newProfit, gasPrice.Mul(gasPrice, new(big.Int).SetUint64(gasUsed)))
+	envDiff.newTxs = append(envDiff.newTxs, tx)
+	envDiff.newReceipts = append(envDiff.newReceipts, receipt)
+	return receipt
+}
@@ -0,0 +1,173 @@
+package miner
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// counter contract increments the value of its slot 0
+	counterAddress = common.HexToAddress("0x3300000000000000000000000000000000000000")
+	counterCode    = hexutil.MustDecode("0x60005460010160005500")
+)
+
+func TestSpeculativeExecution(t *testing.T) {
+	config := params.AllEthashProtocolChanges
+	signers := genSignerList(10, config)
+	alloc := genGenesisAlloc(signers, []common.Address{payProxyAddress, logContractAddress, counterAddress}, [][]byte{payProxyCode, logContractCode, counterCode})
+	statedb, chData := genTestSetupWithAlloc(config, alloc, GasLimit)
+	env := newEnvironment(chData, statedb, signers.addresses[0], GasLimit, big.NewInt(1))
+
+	var (
+		fresh    = common.Address{0x01}
+		calldata = append(make([]byte, 32-20), signers.addresses[9].Bytes()...)
+	)
+	txs := map[common.Address]types.Transactions{
+		signers.addresses[1]: {
+			signers.signTx(1, 21000, big.NewInt(9), big.NewInt(10), signers.addresses[0], big.NewInt(1), nil),
+			signers.signTx(1, 40000, big.NewInt(9), big.NewInt(10), logContractAddress, big.NewInt(5), nil),
+		},
+		signers.addresses[2]: {
+			signers.signTx(2, 60000, big.NewInt(8), big.NewInt(10), counterAddress, big.NewInt(0), nil),
+			signers.signTx(2, 60000, big.NewInt(8), big.NewInt(10), counterAddress, big.NewInt(0), nil),
+		},
+		signers.addresses[3]: {
+			signers.signTx(3, 60000, big.NewInt(7), big.NewInt(10), counterAddress, big.NewInt(0), nil),
+		},
+		signers.addresses[4]: {
+			signers.signTx(4, 40000, big.NewInt(6), big.NewInt(10), payProxyAddress, big.NewInt(7), calldata),
+		},
+		signers.addresses[5]: {
+			signers.signTx(5, 21000, big.NewInt(5), big.NewInt(10), fresh, big.NewInt(3), nil),
+		},
+		signers.addresses[6]: {
+			signers.signTx(6, 21000, big.NewInt(4), big.NewInt(10), fresh, big.NewInt(4), nil),
+		},
+		signers.addresses[7]: {
+			signers.signTx(7, 40000, big.NewInt(3), big.NewInt(10), logContractAddress, big.NewInt(2), nil),
+			// nonce too high
+			signers.signTx(7, 40000, big.NewInt(3), big.NewInt(10), logContractAddress, big.NewInt(2), nil),
+		},
+	}
+	txs[signers.addresses[7]] = txs[signers.addresses[7]][1:]
+	txs[signers.addresses[8]] = types.Transactions{
+		// reverts for lack of gas
+		signers.signTx(8, 21100, big.NewInt(2), big.NewInt(10), counterAddress, big.NewInt(0), nil),
+	}
+
+	expected, speculative := mergeTxs(env, chData, txs, 0)
+	require.Nil(t, speculative)
+	require.Len(t, expected.newTxs, 9)
+
+	for _, orders := range []int{1, 3, 16} {
+		envDiff, speculative := mergeTxs(env, chData, txs, orders)
+		require.Equal(t, expected.newTxs, envDiff.newTxs, orders)
+		require.Equal(t, expected.newReceipts, envDiff.newReceipts, orders)
+		require.Equal(t, expected.newProfit, envDiff.newProfit, orders)
+		require.Equal(t, expected.header.GasUsed, envDiff.header.GasUsed, orders)
+		require.Equal(t, expected.gasPool.Gas(), envDiff.gasPool.Gas(), orders)
+		require.Equal(t, expected.state.IntermediateRoot(true), envDiff.state.IntermediateRoot(true), orders)
+		require.Positive(t, speculative.hits, orders)
+		if orders > 1 {
+			// the counter calls after the first one read the slot it wrote
+			require.Positive(t, speculative.misses, orders)
+		}
+	}
+
+	// speculative execution is disabled with a blacklist
+	chData.blacklist = map[common.Address]struct{}{signers.addresses[9]: {}}
+	require.Nil(t, newSpeculativeExecutor(chData, newEnvironmentDiff(env.copy()), 16))
+}
+
+func TestGreedySpeculativeBuildBlock(t *testing.T) {
+	statedb, chData, signers := genTestSetup(GasLimit)
+	env := newEnvironment(chData, statedb, signers.addresses[0], GasLimit, big.NewInt(1))
+
+	txs := make(map[common.Address]types.Transactions)
+	for i := 1; i < len(signers.signers); i++ {
+		for nonce := 0; nonce < 3; nonce++ {
+			txs[signers.addresses[i]] = append(txs[signers.addresses[i]],
+				signers.signTx(i, 40000, big.NewInt(int64(i)), big.NewInt(20), logContractAddress, big.NewInt(int64(nonce+1)), nil))
+		}
+	}
+
+	algoConf := defaultAlgorithmConfig
+	expected, _, _ := newGreedyBuilder(chData.chain, chData.chainConfig, &algoConf, nil, env, nil, nil).buildBlock(nil, nil, copyTransactions(txs))
+	algoConf.SpeculativeOrders = 4
+	result, _, _ := newGreedyBuilder(chData.chain, chData.chainConfig, &algoConf, nil, env, nil, nil).buildBlock(nil, nil, copyTransactions(txs))
+
+	require.Len(t, result.txs, 27)
+	require.Equal(t, expected.txs, result.txs)
+	require.Equal(t, expected.receipts, result.receipts)
+	require.Equal(t, expected.profit, result.profit)
+	require.Equal(t, expected.state.IntermediateRoot(true), result.state.IntermediateRoot(true))
+}
+
+// BenchmarkSpeculativeMerge compares the sequential merge of txs with their speculative merge. Every speculated tx
+// executes on a copy of the state with a tracer, the txs of every tenth sender read the counter written by the others.
+func BenchmarkSpeculativeMerge(b *testing.B) {
+	config := params.AllEthashProtocolChanges
+	signers := genSignerList(100, config)
+	alloc := genGenesisAlloc(signers, []common.Address{counterAddress}, [][]byte{counterCode})
+	statedb, chData := genTestSetupWithAlloc(config, alloc, GasLimit)
+	env := newEnvironment(chData, statedb, signers.addresses[0], GasLimit, big.NewInt(1))
+
+	txs := make(map[common.Address]types.Transactions)
+	for i := 1; i < len(signers.signers); i++ {
+		for nonce := 0; nonce < 3; nonce++ {
+			var tx *types.Transaction
+			if i%10 == 0 {
+				tx = signers.signTx(i, 60000, big.NewInt(int64(i)), big.NewInt(200), counterAddress, big.NewInt(0), nil)
+			} else {
+				tx = signers.signTx(i, 21000, big.NewInt(int64(i)), big.NewInt(200), common.Address{0xff, byte(i)}, big.NewInt(1), nil)
+			}
+			txs[signers.addresses[i]] = append(txs[signers.addresses[i]], tx)
+		}
+	}
+
+	for _, orders := range []int{0, 4, 16} {
+		name := "sequential"
+		if orders > 0 {
+			name = fmt.Sprintf("speculative-%d", orders)
+		}
+		b.Run(name, func(b *testing.B) {
+			var hits, misses int
+			for i := 0; i < b.N; i++ {
+				envDiff, speculative := mergeTxs(env, chData, txs, orders)
+				if len(envDiff.newTxs) != 3*(len(signers.signers)-1) {
+					b.Fatalf("merged %d txs", len(envDiff.newTxs))
+				}
+				if speculative != nil {
+					hits, misses = hits+speculative.hits, misses+speculative.misses
+				}
+			}
+			if hits+misses > 0 {
+				b.ReportMetric(float64(hits)/float64(hits+misses), "hits/tx")
+			}
+		})
+	}
+}
+
+// mergeTxs merges txs on top of a copy of env the way the greedy algorithm does, executing orders txs ahead
+func mergeTxs(env *environment, chData chainData, txs map[common.Address]types.Transactions, orders int) (*environmentDiff, *speculativeExecutor) {
+	envDiff := newEnvironmentDiff(env.copy())
+	speculative := newSpeculativeExecutor(chData, envDiff, orders)
+	txOrders := types.NewTransactionsByPriceAndNonce(env.signer, copyTransactions(txs), nil, nil, env.header.BaseFee)
+	for order := txOrders.Peek(); order != nil; order = txOrders.Peek() {
+		_, skip, _ := speculative.commitTx(envDiff, txOrders, order.Tx(), chData)
+		switch skip {
+		case shiftTx:
+			txOrders.Shift()
+		case popTx:
+			txOrders.Pop()
+		}
+	}
+	return envDiff, speculative
+}
@@ -0,0 +1,249 @@
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// orderTrace records the orders committed and the bundles and sbundles failed by a greedy pass in the order they
+// were tried, a nil trace records nothing. Failed transactions are not recorded as the rest of the transactions of
+// their sender were skipped.
+type orderTrace struct {
+	committed []*types.TxWithMinerFee
+	failed    []*types.TxWithMinerFee
+}
+
+func (t *orderTrace) record(order *types.TxWithMinerFee, failed bool) {
+	switch {
+	case t == nil:
+	case !failed:
+		t.committed = append(t.committed, order)
+	case order.Tx() == nil:
+		t.failed = append(t.failed, order)
+	}
+}
+
+// localSearchCandidate is an ordering of orders tried by the local search
+type localSearchCandidate struct {
+	orders  []*types.TxWithMinerFee // orders committed
+	profits []*big.Int              // profit of every committed order
+	unused  []*types.TxWithMinerFee // orders not committed
+	profit  *big.Int
+}
+
+// localSearch improves the block of a greedy pass, which commits the orders once in price order, by re-inserting the
+// failed bundles and sbundles at other positions, by replacing lower value transactions with them and by swapping
+// neighbouring orders. A change is kept only if it increases the profit of the block.
+type localSearch struct {
+	env        *environment // environment the greedy pass started from, the search commits the block onto it
+	chainData  chainData
+	builderKey *ecdsa.PrivateKey
+	interrupt  *int32
+	algoConf   algorithmConfig
+	deadline   time.Time
+}
+
+// newLocalSearch creates a local search running for algoConf.LocalSearchBudget, or until the slot of the block starts
+// if it is sooner
+func newLocalSearch(env *environment, chData chainData, key *ecdsa.PrivateKey, interrupt *int32, algoConf algorithmConfig) *localSearch {
+	deadline := time.Now().Add(algoConf.LocalSearchBudget)
+	if slotStart := time.Unix(int64(env.header.Time), 0); slotStart.Before(deadline) {
+		deadline = slotStart
+	}
+	return &localSearch{
+		env:        env,
+		chainData:  chData,
+		builderKey: key,
+		interrupt:  interrupt,
+		algoConf:   algoConf,
+		deadline:   deadline,
+	}
+}
+
+func (s *localSearch) running() bool {
+	return !checkInterrupt(s.interrupt) && time.Now().Before(s.deadline)
+}
+
+// commitOrder commits the order in its own snapshot, ok is false if the order failed and was reverted
+func (s *localSearch) commitOrder(changes *envChanges, order *types.TxWithMinerFee) (ok bool, err error) {
+	if err := changes.env.state.NewMultiTxSnapshot(); err != nil {
+		return false, err
+	}
+
+	var orderErr error
+	if tx := order.Tx(); tx != nil {
+		_, _, orderErr = changes.commitTx(tx, s.chainData)
+	} else if bundle := order.Bundle(); bundle != nil {
+		orderErr = changes.commitBundle(bundle, s.chainData, s.algoConf)
+	} else if sbundle := order.SBundle(); sbundle != nil {
+		orderErr = changes.CommitSBundle(sbundle, s.chainData, s.builderKey, s.algoConf)
+	}
+
+	if orderErr != nil {
+		return false, changes.env.state.MultiTxSnapshotRevert()
+	}
+	return true, changes.env.state.MultiTxSnapshotCommit()
+}
+
+// evaluate commits the orders onto the environment, the changes are applied to the environment if apply is set and
+// discarded otherwise
+func (s *localSearch) evaluate(orders, unused []*types.TxWithMinerFee, apply bool) (*localSearchCandidate, error) {
+	changes, err := newEnvChanges(s.env)
+	if err != nil {
+		return nil, err
+	}
+
+	candidate := &localSearchCandidate{
+		orders:  make([]*types.TxWithMinerFee, 0, len(orders)),
+		profits: make([]*big.Int, 0, len(orders)),
+		unused:  append([]*types.TxWithMinerFee{}, unused...),
+	}
+	for _, order := range orders {
+		profitBefore := new(big.Int).Set(changes.profit)
+		ok, err := s.commitOrder(changes, order)
+		if err != nil {
+			_ = changes.discard()
+			return nil, err
+		}
+		if !ok {
+			candidate.unused = append(candidate.unused, order)
+			continue
+		}
+		candidate.orders = append(candidate.orders, order)
+		candidate.profits = append(candidate.profits, profitBefore.Sub(changes.profit, profitBefore))
+	}
+	candidate.profit = new(big.Int).Set(changes.profit)
+
+	if apply {
+		err = changes.apply()
+	} else {
+		err = changes.discard()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return candidate, nil
+}
+
+// neighbours calls fn with the orderings one change away from best until fn returns false
+func (s *localSearch) neighbours(best *localSearchCandidate, fn func(orders, unused []*types.TxWithMinerFee) bool) {
+	without := func(orders []*types.TxWithMinerFee, i int) []*types.TxWithMinerFee {
+		res := make([]*types.TxWithMinerFee, 0, len(orders))
+		return append(append(res, orders[:i]...), orders[i+1:]...)
+	}
+
+	// re-insert the unused bundles and sbundles at every position
+	for u, order := range best.unused {
+		if order.Tx() != nil {
+			continue
+		}
+		unused := without(best.unused, u)
+		for i := 0; i <= len(best.orders); i++ {
+			orders := make([]*types.TxWithMinerFee, 0, len(best.orders)+1)
+			orders = append(append(append(orders, best.orders[:i]...), order), best.orders[i:]...)
+			if !fn(orders, unused) {
+				return
+			}
+		}
+	}
+
+	// replace the transactions worth less than an unused bundle or sbundle with it
+	for u, order := range best.unused {
+		if order.Tx() != nil {
+			continue
+		}
+		value := order.Profit(s.env.header.BaseFee, 0)
+		for i, committed := range best.orders {
+			if committed.Tx() == nil || best.profits[i].Cmp(value) >= 0 {
+				continue
+			}
+			orders := append([]*types.TxWithMinerFee{}, best.orders...)
+			orders[i] = order
+			if !fn(orders, append(without(best.unused, u), committed)) {
+				return
+			}
+		}
+	}
+
+	// swap the neighbouring orders
+	for i := 0; i+1 < len(best.orders); i++ {
+		orders := append([]*types.TxWithMinerFee{}, best.orders...)
+		orders[i], orders[i+1] = orders[i+1], orders[i]
+		if !fn(orders, best.unused) {
+			return
+		}
+	}
+}
+
+// improve searches for a block more profitable than the one of the greedy pass recorded in trace, worth profit. It
+// returns the environment of the block found along with the bundles and sbundles it included, ok is false if no
+// better block was found before the deadline.
+func (s *localSearch) improve(trace *orderTrace, profit *big.Int) (env *environment, usedBundles []types.SimulatedBundle, usedSbundles []types.UsedSBundle, ok bool) {
+	var (
+		start      = time.Now()
+		candidates int
+	)
+	best, err := s.evaluate(trace.committed, trace.failed, false)
+	if err != nil {
+		log.Error("Failed to evaluate greedy ordering", "err", err)
+		return nil, nil, nil, false
+	}
+
+	for s.running() {
+		var improved *localSearchCandidate
+		s.neighbours(best, func(orders, unused []*types.TxWithMinerFee) bool {
+			if !s.running() {
+				return false
+			}
+			candidates++
+			candidate, evalErr := s.evaluate(orders, unused, false)
+			if evalErr != nil {
+				err = evalErr
+				return false
+			}
+			if candidate.profit.Cmp(best.profit) > 0 {
+				improved = candidate
+				return false
+			}
+			return true
+		})
+		if err != nil {
+			log.Error("Failed to evaluate ordering", "err", err)
+			return nil, nil, nil, false
+		}
+		if improved == nil {
+			break
+		}
+		best = improved
+	}
+
+	log.Debug("Local search finished", "candidates", candidates, "elapsed", time.Since(start),
+		"greedyProfit", profit, "profit", best.profit)
+	if best.profit.Cmp(profit) <= 0 {
+		return nil, nil, nil, false
+	}
+
+	if best, err = s.evaluate(best.orders, best.unused, true); err != nil {
+		log.Error("Failed to commit local search ordering", "err", err)
+		return nil, nil, nil, false
+	} else if best.profit.Cmp(profit) <= 0 {
+		return nil, nil, nil, false
+	}
+	for _, order := range best.orders {
+		if bundle := order.Bundle(); bundle != nil {
+			usedBundles = append(usedBundles, *bundle)
+		} else if sbundle := order.SBundle(); sbundle != nil {
+			usedSbundles = append(usedSbundles, types.UsedSBundle{Bundle: sbundle.Bundle, Success: true})
+		}
+	}
+	for _, order := range best.unused {
+		if sbundle := order.SBundle(); sbundle != nil {
+			usedSbundles = append(usedSbundles, types.UsedSBundle{Bundle: sbundle.Bundle, Success: false})
+		}
+	}
+	return s.env, usedBundles, usedSbundles, true
+}
@@ -0,0 +1,83 @@
+package miner
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalSearch(t *testing.T) {
+	for _, algo := range []AlgoType{ALGO_GREEDY, ALGO_GREEDY_MULTISNAP} {
+		for _, budget := range []time.Duration{0, time.Minute} {
+			statedb, chData, signers := genTestSetup(GasLimit)
+			env := newEnvironment(chData, statedb, signers.addresses[0], GasLimit, big.NewInt(1))
+			env.header.Time = uint64(time.Now().Add(time.Minute).Unix())
+
+			// the transaction is ordered before the bundle but the bundle, spending the same nonce, is worth more
+			tx := signers.signTx(1, 21000, big.NewInt(10), big.NewInt(11), signers.addresses[2], big.NewInt(0), []byte{})
+			signers.nonces[1] = 0
+			bundle := types.MevBundle{
+				Txs: types.Transactions{
+					signers.signTx(1, 21000, big.NewInt(6), big.NewInt(7), signers.addresses[2], big.NewInt(0), []byte{}),
+					signers.signTx(2, 21000, big.NewInt(6), big.NewInt(7), signers.addresses[2], big.NewInt(0), []byte{}),
+				},
+				BlockNumber: env.header.Number,
+			}
+			simBundle, err := simulateBundle(env.copy(), bundle, chData, nil)
+			require.NoError(t, err)
+
+			algoConf := defaultAlgorithmConfig
+			algoConf.LocalSearchBudget = budget
+			txs := map[common.Address]types.Transactions{signers.addresses[1]: {tx}}
+			var (
+				result      *environment
+				usedBundles []types.SimulatedBundle
+			)
+			switch algo {
+			case ALGO_GREEDY:
+				builder := newGreedyBuilder(chData.chain, chData.chainConfig, &algoConf, nil, env, nil, nil)
+				result, usedBundles, _ = builder.buildBlock([]types.SimulatedBundle{simBundle}, nil, txs)
+			case ALGO_GREEDY_MULTISNAP:
+				builder := newGreedyMultiSnapBuilder(chData.chain, chData.chainConfig, &algoConf, nil, env, nil, nil)
+				result, usedBundles, _ = builder.buildBlock([]types.SimulatedBundle{simBundle}, nil, txs)
+			}
+
+			if budget == 0 {
+				require.Equal(t, types.Transactions{tx}, types.Transactions(result.txs), algo.String())
+				require.Empty(t, usedBundles, algo.String())
+				require.Equal(t, big.NewInt(21000*10), result.profit, algo.String())
+				continue
+			}
+			require.Equal(t, bundle.Txs, types.Transactions(result.txs), algo.String())
+			require.Len(t, usedBundles, 1, algo.String())
+			require.Equal(t, bundle.Hash, usedBundles[0].OriginalBundle.Hash, algo.String())
+			require.Equal(t, big.NewInt(2*21000*6), result.profit, algo.String())
+			require.Equal(t, 2, result.tcount, algo.String())
+			require.Equal(t, uint64(2*21000), result.header.GasUsed, algo.String())
+		}
+	}
+}
+
+func TestLocalSearchDeadline(t *testing.T) {
+	statedb, chData, signers := genTestSetup(GasLimit)
+	env := newEnvironment(chData, statedb, signers.addresses[0], GasLimit, big.NewInt(1))
+	algoConf := defaultAlgorithmConfig
+	algoConf.LocalSearchBudget = time.Hour
+
+	// the search does not run past the start of the slot
+	env.header.Time = uint64(time.Now().Add(time.Minute).Unix())
+	search := newLocalSearch(env, chData, nil, nil, algoConf)
+	require.Equal(t, time.Unix(int64(env.header.Time), 0), search.deadline)
+	require.True(t, search.running())
+
+	env.header.Time = uint64(time.Now().Add(-time.Second).Unix())
+	require.False(t, newLocalSearch(env, chData, nil, nil, algoConf).running())
+
+	interrupt := int32(commitInterruptNewHead)
+	env.header.Time = uint64(time.Now().Add(time.Minute).Unix())
+	require.False(t, newLocalSearch(env, chData, nil, &interrupt, algoConf).running())
+}
@@ -0,0 +1,349 @@
+package miner
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// defaultRetryLimit is the number of times orders not committed due to low profit are retried by the bucket algorithms
+const defaultRetryLimit = 1
+
+var (
+	errAlgorithmRegistered = errors.New("algorithm already registered")
+	errInvalidAlgorithm    = errors.New("invalid algorithm name")
+)
+
+// BlockBuildingAlgorithm orders the simulated bundles and sbundles and the pending transactions into a block built on
+// top of env. It returns the environment of the block along with the bundles and sbundles it included, env must be
+// left untouched.
+type BlockBuildingAlgorithm interface {
+	BuildBlock(env Environment, simBundles []types.SimulatedBundle, simSBundles []*types.SimSBundle,
+		transactions map[common.Address]types.Transactions) (Environment, []types.SimulatedBundle, []types.UsedSBundle)
+}
+
+// AlgorithmFunc is a function implementing BlockBuildingAlgorithm
+type AlgorithmFunc func(env Environment, simBundles []types.SimulatedBundle, simSBundles []*types.SimSBundle,
+	transactions map[common.Address]types.Transactions) (Environment, []types.SimulatedBundle, []types.UsedSBundle)
+
+func (f AlgorithmFunc) BuildBlock(env Environment, simBundles []types.SimulatedBundle, simSBundles []*types.SimSBundle,
+	transactions map[common.Address]types.Transactions) (Environment, []types.SimulatedBundle, []types.UsedSBundle) {
+	return f(env, simBundles, simSBundles, transactions)
+}
+
+// AlgorithmContext is the chain and builder data available to the algorithm building a block. Algorithms should stop
+// committing orders once Interrupt is set.
+type AlgorithmContext struct {
+	Chain       *core.BlockChain
+	ChainConfig *params.ChainConfig
+	Blacklist   map[common.Address]struct{}
+	BuilderKey  *ecdsa.PrivateKey
+	Interrupt   *int32
+
+	simBundles    []types.SimulatedBundle
+	simSBundles   []*types.SimSBundle
+	conflictsOnce sync.Once
+	conflicts     *BundleConflictGraph
+}
+
+// Conflicts returns the conflict graph of the simulated bundles and sbundles of the block, built on the first call.
+// Bundles only have read/write sets when RecordConflicts is set in the options of the algorithm, otherwise every
+// bundle conflicts with every other one.
+func (ctx *AlgorithmContext) Conflicts() *BundleConflictGraph {
+	ctx.conflictsOnce.Do(func() {
+		ctx.conflicts = NewBundleConflictGraph(ctx.simBundles, ctx.simSBundles)
+	})
+	return ctx.conflicts
+}
+
+// AlgorithmOptions are the options of a block building algorithm, set per algorithm with --builder.algo_options
+type AlgorithmOptions struct {
+	// DropRevertibleTxOnErr discards the revertible transactions of bundles and sbundles failing on commit instead of
+	// the whole bundle
+	DropRevertibleTxOnErr bool
+	// EnforceProfit skips the orders whose profit falls below ProfitThresholdPercent of their simulated profit
+	EnforceProfit          bool
+	ProfitThresholdPercent int // 0-100
+	// PriceCutoffPercent is the minimum effective gas price of a bucket relative to its top order, used by the bucket
+	// algorithms
+	PriceCutoffPercent int // 0-100
+	// RetryLimit is the number of times an order not committed due to low profit is retried, used by the bucket
+	// algorithms
+	RetryLimit int
+	// LocalSearchBudget is the time the greedy and greedy-multi-snap algorithms spend improving their block by local
+	// search, bounded by the start of the slot, 0 disables the search
+	LocalSearchBudget time.Duration
+	// SpeculativeOrders is the number of txs the greedy algorithm executes ahead in parallel while merging orders, 0
+	// disables speculative execution. Each tx executed ahead runs on a copy of the state.
+	SpeculativeOrders int
+	// RecordConflicts records the read/write sets of the bundles in simulation for AlgorithmContext.Conflicts, the
+	// greedy algorithm then evaluates the conflicting bundles as alternatives to each other
+	RecordConflicts bool
+}
+
+func (o AlgorithmOptions) validate() error {
+	if o.ProfitThresholdPercent < 0 || o.ProfitThresholdPercent > 100 {
+		return errors.New("invalid profit threshold percent - must be between 0 and 100")
+	}
+	if o.PriceCutoffPercent < 0 || o.PriceCutoffPercent > 100 {
+		return errors.New("invalid price cutoff percent - must be between 0 and 100")
+	}
+	if o.RetryLimit < 0 {
+		return errors.New("invalid retry limit - must not be negative")
+	}
+	if o.LocalSearchBudget < 0 {
+		return errors.New("invalid local search budget - must not be negative")
+	}
+	if o.SpeculativeOrders < 0 {
+		return errors.New("invalid speculative orders - must not be negative")
+	}
+	return nil
+}
+
+func (o AlgorithmOptions) algorithmConfig() *algorithmConfig {
+	return &algorithmConfig{
+		DropRevertibleTxOnErr:  o.DropRevertibleTxOnErr,
+		EnforceProfit:          o.EnforceProfit,
+		ProfitThresholdPercent: o.ProfitThresholdPercent,
+		PriceCutoffPercent:     o.PriceCutoffPercent,
+		RetryLimit:             o.RetryLimit,
+		LocalSearchBudget:      o.LocalSearchBudget,
+		SpeculativeOrders:      o.SpeculativeOrders,
+	}
+}
+
+// AlgorithmFactory creates the algorithm building one block
+type AlgorithmFactory func(ctx *AlgorithmContext, opts AlgorithmOptions) BlockBuildingAlgorithm
+
+type registeredAlgorithm struct {
+	name     string
+	factory  AlgorithmFactory
+	defaults AlgorithmOptions
+}
+
+var (
+	algorithmsMu sync.RWMutex
+	algorithms   = make(map[AlgoType]*registeredAlgorithm)
+	// nextAlgoType is the algo type of the next algorithm registered with RegisterAlgorithm
+	nextAlgoType = ALGO_GREEDY_BUCKETS_MULTISNAP + 1
+)
+
+func init() {
+	greedyDefaults := AlgorithmOptions{
+		EnforceProfit:          defaultAlgorithmConfig.EnforceProfit,
+		ProfitThresholdPercent: defaultAlgorithmConfig.ProfitThresholdPercent,
+		PriceCutoffPercent:     defaultPriceCutoffPercent,
+		RetryLimit:             defaultRetryLimit,
+	}
+	bucketsDefaults := greedyDefaults
+	bucketsDefaults.EnforceProfit = true
+
+	registerAlgorithm(ALGO_GREEDY, "greedy", newBuiltinAlgorithm(newGreedyBuilder), greedyDefaults)
+	registerAlgorithm(ALGO_GREEDY_BUCKETS, "greedy-buckets", newBuiltinAlgorithm(newGreedyBucketsBuilder), bucketsDefaults)
+	registerAlgorithm(ALGO_GREEDY_MULTISNAP, "greedy-multi-snap", newBuiltinAlgorithm(newGreedyMultiSnapBuilder), greedyDefaults)
+	registerAlgorithm(ALGO_GREEDY_BUCKETS_MULTISNAP, "greedy-buckets-multi-snap", newBuiltinAlgorithm(newGreedyBucketsMultiSnapBuilder), bucketsDefaults)
+}
+
+// builtinBuilder is the block builder of a built-in algorithm
+type builtinBuilder interface {
+	buildBlock(simBundles []types.SimulatedBundle, simSBundles []*types.SimSBundle,
+		transactions map[common.Address]types.Transactions) (*environment, []types.SimulatedBundle, []types.UsedSBundle)
+}
+
+// conflictAwareBuilder is the block builder of a built-in algorithm using the bundle conflict graph
+type conflictAwareBuilder interface {
+	useConflicts(conflicts *BundleConflictGraph)
+}
+
+// builtinBuilderConstructor is the constructor shared by the block builders of the built-in algorithms
+type builtinBuilderConstructor[B builtinBuilder] func(chain *core.BlockChain, chainConfig *params.ChainConfig,
+	algoConf *algorithmConfig, blacklist map[common.Address]struct{}, env *environment, key *ecdsa.PrivateKey,
+	interrupt *int32) B
+
+// newBuiltinAlgorithm returns the factory of the built-in algorithm building blocks with the builders of newBuilder.
+// Environments not created by the miner are left untouched and returned as is.
+func newBuiltinAlgorithm[B builtinBuilder](newBuilder builtinBuilderConstructor[B]) AlgorithmFactory {
+	return func(ctx *AlgorithmContext, opts AlgorithmOptions) BlockBuildingAlgorithm {
+		return AlgorithmFunc(func(env Environment, simBundles []types.SimulatedBundle, simSBundles []*types.SimSBundle, transactions map[common.Address]types.Transactions) (Environment, []types.SimulatedBundle, []types.UsedSBundle) {
+			algoEnv, ok := env.(*algorithmEnvironment)
+			if !ok {
+				log.Error("Failed to build block", "err", fmt.Errorf("%w: %T", errInvalidEnvironment, env))
+				return env, nil, nil
+			}
+			builder := newBuilder(ctx.Chain, ctx.ChainConfig, opts.algorithmConfig(), ctx.Blacklist, algoEnv.env, ctx.BuilderKey, ctx.Interrupt)
+			if conflictsBuilder, ok := any(builder).(conflictAwareBuilder); ok && opts.RecordConflicts {
+				conflictsBuilder.useConflicts(ctx.Conflicts())
+			}
+			newEnv, blockBundles, usedSbundles := builder.buildBlock(simBundles, simSBundles, transactions)
+			return algoEnv.wrap(newEnv), blockBundles, usedSbundles
+		})
+	}
+}
+
+func registerAlgorithm(algoType AlgoType, name string, factory AlgorithmFactory, defaults AlgorithmOptions) {
+	algorithms[algoType] = &registeredAlgorithm{name: name, factory: factory, defaults: defaults}
+}
+
+// RegisterAlgorithm registers a block building algorithm under name with its default options and returns its algo
+// type. Algorithms must be registered before the command line flags selecting them are parsed, typically from the init
+// function of their package.
+func RegisterAlgorithm(name string, factory AlgorithmFactory, defaults AlgorithmOptions) (AlgoType, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || strings.ContainsAny(name, ",;:= ") {
+		return 0, fmt.Errorf("%w: %q", errInvalidAlgorithm, name)
+	}
+	if err := defaults.validate(); err != nil {
+		return 0, err
+	}
+
+	algorithmsMu.Lock()
+	defer algorithmsMu.Unlock()
+
+	if name == ALGO_MEV_GETH.String() {
+		return 0, fmt.Errorf("%w: %s", errAlgorithmRegistered, name)
+	}
+	for _, algo := range algorithms {
+		if algo.name == name {
+			return 0, fmt.Errorf("%w: %s", errAlgorithmRegistered, name)
+		}
+	}
+	algoType := nextAlgoType
+	nextAlgoType++
+	registerAlgorithm(algoType, name, factory, defaults)
+	return algoType, nil
+}
+
+// RegisteredAlgorithms returns the names of the registered block building algorithms in the order of their algo types
+func RegisteredAlgorithms() []string {
+	algorithmsMu.RLock()
+	defer algorithmsMu.RUnlock()
+
+	algoTypes := make([]AlgoType, 0, len(algorithms))
+	for algoType := range algorithms {
+		algoTypes = append(algoTypes, algoType)
+	}
+	sort.Slice(algoTypes, func(i, j int) bool { return algoTypes[i] < algoTypes[j] })
+
+	names := make([]string, len(algoTypes))
+	for i, algoType := range algoTypes {
+		names[i] = algorithms[algoType].name
+	}
+	return names
+}
+
+func lookupAlgorithm(algoType AlgoType) (*registeredAlgorithm, bool) {
+	algorithmsMu.RLock()
+	defer algorithmsMu.RUnlock()
+
+	algo, found := algorithms[algoType]
+	return algo, found
+}
+
+func lookupAlgorithmByName(name string) (AlgoType, bool) {
+	algorithmsMu.RLock()
+	defer algorithmsMu.RUnlock()
+
+	for algoType, algo := range algorithms {
+		if algo.name == name {
+			return algoType, true
+		}
+	}
+	return 0, false
+}
+
+// newBlockBuildingAlgorithm creates the registered algorithm of the algo type
+func newBlockBuildingAlgorithm(algoType AlgoType, ctx *AlgorithmContext, opts AlgorithmOptions) (BlockBuildingAlgorithm, error) {
+	algo, found := lookupAlgorithm(algoType)
+	if !found {
+		return nil, fmt.Errorf("%w: %s", errInvalidAlgorithm, algoType)
+	}
+	return algo.factory(ctx, opts), nil
+}
+
+// DefaultAlgorithmOptions returns the default options of the registered algorithm, with the revertible transactions and
+// price cutoff settings of the config which apply to every algorithm
+func DefaultAlgorithmOptions(algoType AlgoType, config *Config) (AlgorithmOptions, error) {
+	algo, found := lookupAlgorithm(algoType)
+	if !found {
+		return AlgorithmOptions{}, fmt.Errorf("%w: %s", errInvalidAlgorithm, algoType)
+	}
+	opts := algo.defaults
+	opts.DropRevertibleTxOnErr = config.DiscardRevertibleTxOnErr
+	opts.PriceCutoffPercent = config.PriceCutoffPercent
+	return opts, opts.validate()
+}
+
+// ParseAlgorithmOptions parses per algorithm options of the form `<algo>:<key>=<value>,...;<algo>:...` on top of the
+// default options of the algorithms. The keys are drop_revertible_tx_on_err, enforce_profit, profit_threshold_percent,
+// price_cutoff_percent, retry_limit, local_search_budget, speculative_orders and record_conflicts.
+func ParseAlgorithmOptions(options string, config *Config) (map[AlgoType]AlgorithmOptions, error) {
+	algoOptions := make(map[AlgoType]AlgorithmOptions)
+	if strings.TrimSpace(options) == "" {
+		return algoOptions, nil
+	}
+
+	for _, entry := range strings.Split(options, ";") {
+		name, values, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found {
+			return nil, fmt.Errorf("invalid algorithm options %q, expected <algo>:<key>=<value>,...", entry)
+		}
+		algoType, found := lookupAlgorithmByName(strings.ToLower(name))
+		if !found {
+			return nil, fmt.Errorf("%w: %q", errInvalidAlgorithm, name)
+		}
+		opts, ok := algoOptions[algoType]
+		if !ok {
+			var err error
+			if opts, err = DefaultAlgorithmOptions(algoType, config); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, option := range strings.Split(values, ",") {
+			key, value, found := strings.Cut(strings.TrimSpace(option), "=")
+			if !found {
+				return nil, fmt.Errorf("invalid option %q of algorithm %s, expected <key>=<value>", option, name)
+			}
+			var err error
+			switch key {
+			case "drop_revertible_tx_on_err":
+				opts.DropRevertibleTxOnErr, err = strconv.ParseBool(value)
+			case "enforce_profit":
+				opts.EnforceProfit, err = strconv.ParseBool(value)
+			case "profit_threshold_percent":
+				opts.ProfitThresholdPercent, err = strconv.Atoi(value)
+			case "price_cutoff_percent":
+				opts.PriceCutoffPercent, err = strconv.Atoi(value)
+			case "retry_limit":
+				opts.RetryLimit, err = strconv.Atoi(value)
+			case "local_search_budget":
+				opts.LocalSearchBudget, err = time.ParseDuration(value)
+			case "speculative_orders":
+				opts.SpeculativeOrders, err = strconv.Atoi(value)
+			case "record_conflicts":
+				opts.RecordConflicts, err = strconv.ParseBool(value)
+			default:
+				return nil, fmt.Errorf("unknown option %q of algorithm %s", key, name)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of option %s of algorithm %s: %w", key, name, err)
+			}
+		}
+		if err := opts.validate(); err != nil {
+			return nil, fmt.Errorf("algorithm %s: %w", name, err)
+		}
+		algoOptions[algoType] = opts
+	}
+	return algoOptions, nil
+}
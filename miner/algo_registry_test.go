@@ -0,0 +1,123 @@
+package miner
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// testAlgoCalls counts the blocks built by the test algorithm, testAlgoOptions are the options of the last one
+	testAlgoCalls   int32
+	testAlgoOptions atomic.Value
+
+	// testAlgoType is an algorithm registered the way other packages would, it orders blocks with the greedy algorithm
+	testAlgoType, testAlgoErr = RegisterAlgorithm("Test-Algo", func(ctx *AlgorithmContext, opts AlgorithmOptions) BlockBuildingAlgorithm {
+		greedy := newBuiltinAlgorithm(newGreedyBuilder)(ctx, opts)
+		return AlgorithmFunc(func(env Environment, simBundles []types.SimulatedBundle, simSBundles []*types.SimSBundle, transactions map[common.Address]types.Transactions) (Environment, []types.SimulatedBundle, []types.UsedSBundle) {
+			atomic.AddInt32(&testAlgoCalls, 1)
+			testAlgoOptions.Store(opts)
+			return greedy.BuildBlock(env, simBundles, simSBundles, transactions)
+		})
+	}, AlgorithmOptions{ProfitThresholdPercent: 50, RetryLimit: 3})
+)
+
+func TestRegisterAlgorithm(t *testing.T) {
+	require.NoError(t, testAlgoErr)
+	require.Greater(t, testAlgoType, ALGO_GREEDY_BUCKETS_MULTISNAP)
+	require.Equal(t, "test-algo", testAlgoType.String())
+	// the algorithms of the external tests are registered after test-algo
+	require.Equal(t, []string{"greedy", "greedy-buckets", "greedy-multi-snap", "greedy-buckets-multi-snap", "test-algo"}, RegisteredAlgorithms()[:5])
+
+	algoType, err := AlgoTypeFlagToEnum("TEST-ALGO")
+	require.NoError(t, err)
+	require.Equal(t, testAlgoType, algoType)
+	algoTypes, err := ParseAlgoTypes("greedy,test-algo")
+	require.NoError(t, err)
+	require.Equal(t, []AlgoType{ALGO_GREEDY, testAlgoType}, algoTypes)
+
+	for _, name := range []string{"test-algo", "greedy", "mev-geth", "", "a,b", "a:b"} {
+		_, err = RegisterAlgorithm(name, nil, AlgorithmOptions{})
+		require.Error(t, err, name)
+	}
+	_, err = RegisterAlgorithm("invalid-options", nil, AlgorithmOptions{PriceCutoffPercent: 101})
+	require.Error(t, err)
+}
+
+func TestParseAlgorithmOptions(t *testing.T) {
+	config := &Config{DiscardRevertibleTxOnErr: true, PriceCutoffPercent: 20}
+
+	opts, err := DefaultAlgorithmOptions(ALGO_GREEDY_BUCKETS, config)
+	require.NoError(t, err)
+	require.Equal(t, AlgorithmOptions{
+		DropRevertibleTxOnErr:  true,
+		EnforceProfit:          true,
+		ProfitThresholdPercent: defaultProfitThresholdPercent,
+		PriceCutoffPercent:     20,
+		RetryLimit:             defaultRetryLimit,
+	}, opts)
+	_, err = DefaultAlgorithmOptions(ALGO_MEV_GETH, config)
+	require.Error(t, err)
+
+	algoOptions, err := ParseAlgorithmOptions("", config)
+	require.NoError(t, err)
+	require.Empty(t, algoOptions)
+
+	algoOptions, err = ParseAlgorithmOptions("greedy-buckets:price_cutoff_percent=30,retry_limit=2; greedy:enforce_profit=true;greedy:drop_revertible_tx_on_err=false,local_search_budget=150ms,speculative_orders=8,record_conflicts=true", config)
+	require.NoError(t, err)
+	require.Equal(t, map[AlgoType]AlgorithmOptions{
+		ALGO_GREEDY_BUCKETS: {
+			DropRevertibleTxOnErr:  true,
+			EnforceProfit:          true,
+			ProfitThresholdPercent: defaultProfitThresholdPercent,
+			PriceCutoffPercent:     30,
+			RetryLimit:             2,
+		},
+		ALGO_GREEDY: {
+			EnforceProfit:          true,
+			ProfitThresholdPercent: defaultProfitThresholdPercent,
+			PriceCutoffPercent:     20,
+			RetryLimit:             defaultRetryLimit,
+			LocalSearchBudget:      150 * time.Millisecond,
+			SpeculativeOrders:      8,
+			RecordConflicts:        true,
+		},
+	}, algoOptions)
+
+	for _, options := range []string{
+		"greedy", "greedy:", "unknown:retry_limit=1", "greedy:unknown=1", "greedy:retry_limit=a",
+		"greedy:retry_limit=-1", "greedy:profit_threshold_percent=101", "mev-geth:retry_limit=1",
+		"greedy:local_search_budget=1", "greedy:local_search_budget=-1s", "greedy:speculative_orders=-1",
+		"greedy:record_conflicts=2",
+	} {
+		_, err = ParseAlgorithmOptions(options, config)
+		require.Error(t, err, options)
+	}
+}
+
+func TestRegisteredAlgorithmWorker(t *testing.T) {
+	require.NoError(t, testAlgoErr)
+	t.Cleanup(func() {
+		testConfig.AlgoType = ALGO_MEV_GETH
+		testConfig.AlgoOptions = nil
+	})
+	testConfig.AlgoType = testAlgoType
+	testConfig.AlgoOptions = map[AlgoType]AlgorithmOptions{testAlgoType: {ProfitThresholdPercent: 10, RetryLimit: 5}}
+
+	w, b := newTestWorker(t, params.TestChainConfig, ethash.NewFaker(), rawdb.NewMemoryDatabase(), defaultGenesisAlloc, 0)
+	defer w.close()
+
+	calls := atomic.LoadInt32(&testAlgoCalls)
+	block, _, err := w.getSealingBlock(b.chain.CurrentBlock().Hash(), b.chain.CurrentHeader().Time+12, testUserAddress, 0, common.Hash{}, nil, false, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, block.Transactions(), len(pendingTxs))
+	require.Equal(t, calls+1, atomic.LoadInt32(&testAlgoCalls))
+	require.Equal(t, AlgorithmOptions{ProfitThresholdPercent: 10, RetryLimit: 5}, testAlgoOptions.Load())
+}
@@ -0,0 +1,222 @@
+package miner
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// bundleChanSize is the size of channel listening to NewBundlesEvent.
+const bundleChanSize = 256
+
+// BuildingSession builds blocks for the same payload arguments. The environment on top of the parent is prepared once
+// and every block is filled from a copy of it. The session follows the transactions and bundles entering the pool so
+// that blocks are only rebuilt when the orders changed, bundles already simulated on the environment are not
+// simulated again.
+type BuildingSession struct {
+	workers []*workerSession
+
+	// changed is signalled when the orders changed since the last build
+	changed chan struct{}
+	buildMu sync.Mutex
+
+	txsCh      chan core.NewTxsEvent
+	txsSub     event.Subscription
+	bundlesCh  chan core.NewBundlesEvent
+	bundlesSub event.Subscription
+
+	exitCh    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// workerSession is the prepared environment of a worker of the session
+type workerSession struct {
+	w                 *worker
+	params            *generateParams
+	env               *environment
+	validatorCoinbase common.Address
+	paymentTxReserve  *proposerTxReservation
+}
+
+func (w *multiWorker) newBuildingSession(args *BuildPayloadArgs) (*BuildingSession, error) {
+	session := &BuildingSession{
+		changed:   make(chan struct{}, 1),
+		txsCh:     make(chan core.NewTxsEvent, txChanSize),
+		bundlesCh: make(chan core.NewBundlesEvent, bundleChanSize),
+		exitCh:    make(chan struct{}),
+	}
+
+	for _, worker := range w.workers {
+		params := &generateParams{
+			timestamp:   args.Timestamp,
+			forceTime:   true,
+			parentHash:  args.Parent,
+			coinbase:    args.FeeRecipient,
+			gasLimit:    args.GasLimit,
+			random:      args.Random,
+			withdrawals: args.Withdrawals,
+			noUncle:     true,
+			onBlock:     args.BlockHook,
+
+			proposerPayment: args.ProposerPayment,
+		}
+		env, validatorCoinbase, paymentTxReserve, err := worker.prepareSealingWork(params)
+		if err != nil {
+			log.Error("could not prepare building session", "isFlashbotsWorker", worker.flashbots.isFlashbots, "#bundles", worker.flashbots.maxMergedBundles, "err", err)
+			continue
+		}
+		session.workers = append(session.workers, &workerSession{
+			w:                 worker,
+			params:            params,
+			env:               env,
+			validatorCoinbase: validatorCoinbase,
+			paymentTxReserve:  paymentTxReserve,
+		})
+	}
+	if len(session.workers) == 0 {
+		return nil, errors.New("no worker could prepare the building session")
+	}
+
+	// Subscribe before the first build so that no order is missed
+	txPool := w.regularWorker.eth.TxPool()
+	session.txsSub = txPool.SubscribeNewTxsEvent(session.txsCh)
+	session.bundlesSub = txPool.SubscribeNewBundlesEvent(session.bundlesCh)
+
+	// The first build is due right away
+	session.changed <- struct{}{}
+
+	session.wg.Add(1)
+	go session.loop()
+
+	return session, nil
+}
+
+func (s *BuildingSession) loop() {
+	defer s.wg.Done()
+	defer s.txsSub.Unsubscribe()
+	defer s.bundlesSub.Unsubscribe()
+
+	for {
+		select {
+		case <-s.txsCh:
+		case <-s.bundlesCh:
+		case <-s.txsSub.Err():
+			return
+		case <-s.bundlesSub.Err():
+			return
+		case <-s.exitCh:
+			return
+		}
+
+		select {
+		case s.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Changed is signalled when transactions or bundles entered the pool since the last build
+func (s *BuildingSession) Changed() <-chan struct{} {
+	return s.changed
+}
+
+// Build fills every worker's copy of the prepared environment with the orders of the pool and returns the most
+// profitable block. Every block built is passed to the block hook of the payload arguments. Filling the blocks is
+// interrupted once the timeout passed, a zero timeout disables it.
+func (s *BuildingSession) Build(timeout time.Duration) (*types.Block, *big.Int, error) {
+	s.buildMu.Lock()
+	defer s.buildMu.Unlock()
+
+	select {
+	case <-s.exitCh:
+		return nil, nil, errors.New("building session closed")
+	default:
+	}
+
+	// Orders arriving from now on are picked up by the next build
+	select {
+	case <-s.changed:
+	default:
+	}
+
+	interrupt := new(int32)
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout, func() {
+			atomic.StoreInt32(interrupt, commitInterruptTimeout)
+		})
+		defer timer.Stop()
+	}
+
+	var (
+		wg     sync.WaitGroup
+		start  = time.Now()
+		blocks = make([]*types.Block, len(s.workers))
+		fees   = make([]*big.Int, len(s.workers))
+		errs   = make([]error, len(s.workers))
+	)
+	for i, ws := range s.workers {
+		wg.Add(1)
+		go func(i int, ws *workerSession) {
+			defer wg.Done()
+
+			work := ws.env.copy()
+			defer work.discard()
+
+			blocks[i], fees[i], errs[i] = ws.w.fillSealingWork(ws.params, work, ws.validatorCoinbase, ws.paymentTxReserve, interrupt, start)
+		}(i, ws)
+	}
+	wg.Wait()
+
+	var (
+		bestBlock *types.Block
+		bestFees  *big.Int
+		bestBy    string
+		builtBy   []string
+		firstErr  error
+	)
+	for i, ws := range s.workers {
+		switch {
+		case errs[i] == nil:
+			algo := ws.w.flashbots.algoType.String()
+			builtBy = append(builtBy, algo)
+			if bestBlock == nil || fees[i].Cmp(bestFees) > 0 {
+				bestBlock, bestFees, bestBy = blocks[i], fees[i], algo
+			}
+		case errors.Is(errs[i], ErrProposerPaymentWithheld):
+			log.Debug("Block discarded without proposer payment", "err", errs[i])
+		default:
+			log.Error("Error while sealing block", "err", errs[i])
+		}
+		if errs[i] != nil && firstErr == nil {
+			firstErr = errs[i]
+		}
+	}
+	if bestBlock == nil {
+		return nil, nil, firstErr
+	}
+	markAlgoResults(builtBy, bestBy)
+	return bestBlock, bestFees, nil
+}
+
+// Close stops following the pool and releases the prepared environments
+func (s *BuildingSession) Close() {
+	s.closeOnce.Do(func() {
+		close(s.exitCh)
+		s.wg.Wait()
+
+		s.buildMu.Lock()
+		defer s.buildMu.Unlock()
+		for _, ws := range s.workers {
+			ws.env.discard()
+		}
+	})
+}
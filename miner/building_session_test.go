@@ -0,0 +1,68 @@
+package miner
+
+import (
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildingSession(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	w, b := newTestWorker(t, params.TestChainConfig, ethash.NewFaker(), db, defaultGenesisAlloc, 0)
+	defer w.close()
+	mw := &multiWorker{regularWorker: w, workers: []*worker{w}}
+
+	var hookCalls int32
+	session, err := mw.newBuildingSession(&BuildPayloadArgs{
+		Parent:       b.chain.CurrentBlock().Hash(),
+		Timestamp:    uint64(time.Now().Unix()),
+		FeeRecipient: common.HexToAddress("0xdeadbeef"),
+		BlockHook: func(*types.Block, *big.Int, time.Time, []types.SimulatedBundle, []types.SimulatedBundle, []types.UsedSBundle) {
+			atomic.AddInt32(&hookCalls, 1)
+		},
+	})
+	require.NoError(t, err)
+	defer session.Close()
+
+	requireChanged := func(changed bool) {
+		select {
+		case <-session.Changed():
+			require.True(t, changed, "unexpected change of the orders")
+		case <-time.After(200 * time.Millisecond):
+			require.False(t, changed, "change of the orders not signalled")
+		}
+	}
+
+	// the first build is due right away
+	requireChanged(true)
+	block, _, err := session.Build(0)
+	require.NoError(t, err)
+	require.Len(t, block.Transactions(), len(pendingTxs))
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&hookCalls) == 1 }, time.Second, 10*time.Millisecond)
+
+	// nothing to rebuild until orders enter the pool
+	requireChanged(false)
+
+	tx := b.newRandomTx(false, testUserAddress, 1000, testBankKey, 0, big.NewInt(10*params.InitialBaseFee))
+	require.NoError(t, b.txPool.AddLocal(tx))
+	requireChanged(true)
+
+	block, _, err = session.Build(0)
+	require.NoError(t, err)
+	require.Len(t, block.Transactions(), len(pendingTxs)+1)
+
+	require.NoError(t, b.txPool.AddMevBundles([]types.MevBundle{{BlockNumber: big.NewInt(1)}}))
+	requireChanged(true)
+
+	session.Close()
+	_, _, err = session.Build(0)
+	require.Error(t, err)
+}
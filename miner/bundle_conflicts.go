@@ -0,0 +1,162 @@
+package miner
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// BundleConflictGraph records which of the simulated bundles and sbundles conflict, that is which of them access the
+// state written by another one. Bundles are simulated independently on top of the parent state, the result of the
+// simulation of a bundle holds in a block as long as none of the bundles committed before it conflict with it.
+// Bundles simulated without a read/write set conflict with every other bundle.
+type BundleConflictGraph struct {
+	hashes    []common.Hash
+	conflicts map[common.Hash]map[common.Hash]struct{}
+	unknown   map[common.Hash]struct{}
+}
+
+// NewBundleConflictGraph builds the conflict graph of the bundles and sbundles from their simulated read/write sets
+func NewBundleConflictGraph(bundles []types.SimulatedBundle, sbundles []*types.SimSBundle) *BundleConflictGraph {
+	g := &BundleConflictGraph{
+		hashes:    make([]common.Hash, 0, len(bundles)+len(sbundles)),
+		conflicts: make(map[common.Hash]map[common.Hash]struct{}),
+		unknown:   make(map[common.Hash]struct{}),
+	}
+
+	// index the bundles by the accounts and storage slots they access, conflicts are found per account and slot
+	var (
+		accountReads  = make(map[common.Address][]common.Hash)
+		accountWrites = make(map[common.Address][]common.Hash)
+		storageReads  = make(map[types.StorageSlot][]common.Hash)
+		storageWrites = make(map[types.StorageSlot][]common.Hash)
+	)
+	add := func(hash common.Hash, rwSet *types.RWSet) {
+		if _, ok := g.conflicts[hash]; ok {
+			return
+		}
+		g.hashes = append(g.hashes, hash)
+		g.conflicts[hash] = make(map[common.Hash]struct{})
+		if rwSet == nil {
+			g.unknown[hash] = struct{}{}
+			return
+		}
+		for address := range rwSet.AccountReads {
+			accountReads[address] = append(accountReads[address], hash)
+		}
+		for address := range rwSet.AccountWrites {
+			accountWrites[address] = append(accountWrites[address], hash)
+		}
+		for slot := range rwSet.StorageReads {
+			storageReads[slot] = append(storageReads[slot], hash)
+		}
+		for slot := range rwSet.StorageWrites {
+			storageWrites[slot] = append(storageWrites[slot], hash)
+		}
+	}
+	for _, bundle := range bundles {
+		add(bundle.OriginalBundle.Hash, bundle.RWSet)
+	}
+	for _, sbundle := range sbundles {
+		add(sbundle.Bundle.Hash(), sbundle.RWSet)
+	}
+
+	for address, writers := range accountWrites {
+		g.connect(writers, accountReads[address])
+	}
+	for slot, writers := range storageWrites {
+		g.connect(writers, storageReads[slot])
+		g.connect(writers, writers)
+	}
+	return g
+}
+
+func (g *BundleConflictGraph) connect(a, b []common.Hash) {
+	for _, x := range a {
+		for _, y := range b {
+			if x != y {
+				g.conflicts[x][y] = struct{}{}
+				g.conflicts[y][x] = struct{}{}
+			}
+		}
+	}
+}
+
+// Conflicts reports whether the bundles or sbundles a and b conflict
+func (g *BundleConflictGraph) Conflicts(a, b common.Hash) bool {
+	if a == b {
+		return false
+	}
+	if _, ok := g.unknown[a]; ok {
+		return true
+	}
+	if _, ok := g.unknown[b]; ok {
+		return true
+	}
+	_, ok := g.conflicts[a][b]
+	return ok
+}
+
+// Conflicting returns the bundles and sbundles conflicting with the bundle or sbundle hash
+func (g *BundleConflictGraph) Conflicting(hash common.Hash) []common.Hash {
+	var result []common.Hash
+	for _, other := range g.hashes {
+		if g.Conflicts(hash, other) {
+			result = append(result, other)
+		}
+	}
+	return result
+}
+
+// Independent reports whether the bundle or sbundle hash conflicts with no other bundle, it can be committed in any
+// position of the block without being simulated again
+func (g *BundleConflictGraph) Independent(hash common.Hash) bool {
+	if _, ok := g.unknown[hash]; ok {
+		return len(g.hashes) <= 1
+	}
+	return len(g.conflicts[hash]) == 0 && len(g.unknown) == 0
+}
+
+// Groups returns the groups of bundles and sbundles connected by conflicts, the bundles of a group are alternatives
+// to each other whose value depends on which of them are committed first. Bundles conflicting with no other bundle
+// are left out, groups and their bundles are in the order the bundles were given.
+func (g *BundleConflictGraph) Groups() [][]common.Hash {
+	var (
+		groups [][]common.Hash
+		seen   = make(map[common.Hash]struct{}, len(g.hashes))
+	)
+	for _, hash := range g.hashes {
+		if _, ok := seen[hash]; ok {
+			continue
+		}
+		seen[hash] = struct{}{}
+
+		group := []common.Hash{hash}
+		for i := 0; i < len(group); i++ {
+			for _, other := range g.Conflicting(group[i]) {
+				if _, ok := seen[other]; !ok {
+					seen[other] = struct{}{}
+					group = append(group, other)
+				}
+			}
+		}
+		if len(group) > 1 {
+			groups = append(groups, g.sorted(group))
+		}
+	}
+	return groups
+}
+
+// sorted orders the hashes of a group the way the bundles were given
+func (g *BundleConflictGraph) sorted(group []common.Hash) []common.Hash {
+	members := make(map[common.Hash]struct{}, len(group))
+	for _, hash := range group {
+		members[hash] = struct{}{}
+	}
+	result := make([]common.Hash, 0, len(group))
+	for _, hash := range g.hashes {
+		if _, ok := members[hash]; ok {
+			result = append(result, hash)
+		}
+	}
+	return result
+}
@@ -0,0 +1,148 @@
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundleConflictGraph(t *testing.T) {
+	var (
+		contract = common.Address{0x01}
+		slot     = common.Hash{0x02}
+	)
+	rwSet := func(read, write bool) *types.RWSet {
+		s := types.NewRWSet()
+		if read {
+			s.ReadStorage(contract, slot)
+		}
+		if write {
+			s.WriteStorage(contract, slot)
+		}
+		return s
+	}
+	bundle := func(hash byte, rwSet *types.RWSet) types.SimulatedBundle {
+		return types.SimulatedBundle{OriginalBundle: types.MevBundle{Hash: common.Hash{hash}}, RWSet: rwSet}
+	}
+	sbundle := &types.SimSBundle{Bundle: &types.SBundle{}, RWSet: rwSet(false, true)}
+
+	g := NewBundleConflictGraph([]types.SimulatedBundle{
+		bundle(1, rwSet(true, false)),
+		bundle(2, rwSet(true, false)),
+		bundle(3, types.NewRWSet()),
+	}, []*types.SimSBundle{sbundle})
+
+	require.False(t, g.Conflicts(common.Hash{1}, common.Hash{2}))
+	require.True(t, g.Conflicts(common.Hash{1}, sbundle.Bundle.Hash()))
+	require.True(t, g.Conflicts(sbundle.Bundle.Hash(), common.Hash{2}))
+	require.False(t, g.Conflicts(common.Hash{3}, sbundle.Bundle.Hash()))
+	require.Equal(t, []common.Hash{sbundle.Bundle.Hash()}, g.Conflicting(common.Hash{1}))
+	require.True(t, g.Independent(common.Hash{3}))
+	require.False(t, g.Independent(common.Hash{1}))
+	require.Equal(t, [][]common.Hash{{{1}, {2}, sbundle.Bundle.Hash()}}, g.Groups())
+
+	// bundles simulated without a read/write set conflict with every bundle
+	g = NewBundleConflictGraph([]types.SimulatedBundle{bundle(1, nil), bundle(2, types.NewRWSet()), bundle(3, types.NewRWSet())}, nil)
+	require.True(t, g.Conflicts(common.Hash{2}, common.Hash{1}))
+	require.False(t, g.Conflicts(common.Hash{2}, common.Hash{3}))
+	require.False(t, g.Independent(common.Hash{2}))
+	require.Equal(t, [][]common.Hash{{{1}, {2}, {3}}}, g.Groups())
+}
+
+func TestSimulatedBundleConflicts(t *testing.T) {
+	var (
+		receiver      = common.Address{0x01}
+		otherReceiver = common.Address{0x02}
+		// the contracts set the slot of their calldata and pay their call value to the coinbase
+		contract      = common.Address{0xc1}
+		otherContract = common.Address{0xc2}
+		payCoinbase   = hexutil.MustDecode("0x600160003555600060006000600034415af100")
+	)
+	alloc := core.GenesisAlloc{
+		testBankAddress: {Balance: testBankFunds},
+		testUserAddress: {Balance: testBankFunds},
+		testAddress1:    {Balance: testBankFunds},
+		testAddress2:    {Balance: testBankFunds},
+		contract:        {Balance: new(big.Int), Code: payCoinbase},
+		otherContract:   {Balance: new(big.Int), Code: payCoinbase},
+	}
+	t.Cleanup(func() {
+		testConfig.AlgoType = ALGO_MEV_GETH
+		testConfig.AlgoOptions = nil
+	})
+	// the read/write sets are recorded for the algorithms reading the conflict graph
+	testConfig.AlgoType = ALGO_GREEDY
+	testConfig.AlgoOptions = map[AlgoType]AlgorithmOptions{ALGO_GREEDY: {RecordConflicts: true}}
+	w, _ := newTestWorker(t, ethashChainConfig, ethash.NewFaker(), rawdb.NewMemoryDatabase(), alloc, 0)
+	defer w.close()
+
+	env, err := w.prepareWork(&generateParams{gasLimit: 30000000})
+	require.NoError(t, err)
+
+	bundle := func(hash byte, to common.Address, key *ecdsa.PrivateKey, data []byte) types.MevBundle {
+		tx, err := types.SignTx(types.NewTransaction(0, to, big.NewInt(1000), 100000, env.header.BaseFee, data), types.HomesteadSigner{}, key)
+		require.NoError(t, err)
+		return types.MevBundle{Txs: types.Transactions{tx}, Hash: common.Hash{hash}}
+	}
+
+	simBundles, _, err := w.simulateBundles(env, []types.MevBundle{
+		bundle(1, receiver, testBankKey, nil),
+		// spends the nonce of the first bundle
+		bundle(2, otherReceiver, testBankKey, nil),
+		// pay the coinbase and write unrelated storage
+		bundle(3, contract, testUserKey, common.Hash{1}.Bytes()),
+		bundle(4, otherContract, testAddress1Key, common.Hash{2}.Bytes()),
+		// pays the receiver of the first bundle, balance increments do not conflict
+		bundle(5, receiver, testAddress2Key, nil),
+	}, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, simBundles, 5)
+	for _, simBundle := range simBundles {
+		require.NotNil(t, simBundle.RWSet)
+	}
+	require.Contains(t, simBundles[0].RWSet.AccountReads, testBankAddress)
+	require.Contains(t, simBundles[0].RWSet.AccountWrites, receiver)
+	require.NotContains(t, simBundles[0].RWSet.AccountReads, receiver)
+	for _, simBundle := range simBundles[2:4] {
+		require.Contains(t, simBundle.RWSet.AccountWrites, env.coinbase)
+		require.NotContains(t, simBundle.RWSet.AccountReads, env.coinbase)
+	}
+	require.Contains(t, simBundles[2].RWSet.AccountReads, contract)
+	require.Contains(t, simBundles[2].RWSet.StorageWrites, types.StorageSlot{Address: contract, Key: common.Hash{1}})
+
+	ctx := &AlgorithmContext{simBundles: simBundles}
+	g := ctx.Conflicts()
+	require.Same(t, g, ctx.Conflicts())
+	require.True(t, g.Conflicts(common.Hash{1}, common.Hash{2}))
+	require.False(t, g.Conflicts(common.Hash{3}, common.Hash{4}))
+	require.False(t, g.Conflicts(common.Hash{1}, common.Hash{5}))
+	for _, hash := range []common.Hash{{3}, {4}, {5}} {
+		require.True(t, g.Independent(hash))
+	}
+	require.Equal(t, [][]common.Hash{{{1}, {2}}}, g.Groups())
+}
+
+func TestSimulatedBundleConflictsNotRecorded(t *testing.T) {
+	alloc := core.GenesisAlloc{testBankAddress: {Balance: testBankFunds}}
+	w, _ := newTestWorker(t, ethashChainConfig, ethash.NewFaker(), rawdb.NewMemoryDatabase(), alloc, 0)
+	defer w.close()
+
+	env, err := w.prepareWork(&generateParams{gasLimit: 30000000})
+	require.NoError(t, err)
+
+	tx, err := types.SignTx(types.NewTransaction(0, common.Address{0x01}, big.NewInt(1000), params.TxGas, env.header.BaseFee, nil), types.HomesteadSigner{}, testBankKey)
+	require.NoError(t, err)
+	simBundles, _, err := w.simulateBundles(env, []types.MevBundle{{Txs: types.Transactions{tx}, Hash: common.Hash{1}}}, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, simBundles, 1)
+	require.Nil(t, simBundles[0].RWSet)
+}
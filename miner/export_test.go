@@ -0,0 +1,64 @@
+package miner
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// NewBlockBuildingAlgorithm exports newBlockBuildingAlgorithm to the algorithms tested from other packages
+var NewBlockBuildingAlgorithm = newBlockBuildingAlgorithm
+
+// TestAlgorithmSetup is an empty block of the test chain along with the orders of its funded signers
+type TestAlgorithmSetup struct {
+	Context *AlgorithmContext
+	Env     Environment
+	// Signers are the signers of the txs, in the order of their tips
+	Signers []common.Address
+	// Transactions are txs paying the log contract, with tips decreasing with the index of their signer
+	Transactions map[common.Address]types.Transactions
+	// Bundles are simulated bundles of two txs paying the log contract, their signers have no other txs
+	Bundles []types.SimulatedBundle
+}
+
+// NewTestAlgorithmSetup returns the test block of the algorithm options with txsPerSigner txs for each of the first
+// signers and one bundle for each of the last ones
+func NewTestAlgorithmSetup(opts AlgorithmOptions, txSigners, txsPerSigner, bundleSigners int) (*TestAlgorithmSetup, error) {
+	statedb, chData, signers := genTestSetup(GasLimit)
+	env := newEnvironment(chData, statedb, signers.addresses[0], GasLimit, big.NewInt(1))
+	setup := &TestAlgorithmSetup{
+		Context:      &AlgorithmContext{Chain: chData.chain, ChainConfig: chData.chainConfig},
+		Transactions: make(map[common.Address]types.Transactions),
+	}
+
+	for i := 1; i <= txSigners; i++ {
+		setup.Signers = append(setup.Signers, signers.addresses[i])
+		for nonce := 0; nonce < txsPerSigner; nonce++ {
+			setup.Transactions[signers.addresses[i]] = append(setup.Transactions[signers.addresses[i]],
+				signers.signTx(i, 40000, big.NewInt(int64(2*(len(signers.signers)-i))), big.NewInt(50), logContractAddress, big.NewInt(1), nil))
+		}
+	}
+	for i := txSigners + 1; i <= txSigners+bundleSigners; i++ {
+		bundle := types.MevBundle{
+			Txs: types.Transactions{
+				signers.signTx(i, 40000, big.NewInt(int64(len(signers.signers)-i)), big.NewInt(50), logContractAddress, big.NewInt(1), nil),
+				signers.signTx(i, 40000, big.NewInt(int64(len(signers.signers)-i)), big.NewInt(50), logContractAddress, big.NewInt(1), nil),
+			},
+			BlockNumber: env.header.Number,
+		}
+		simBundle, err := simulateBundle(env.copy(), bundle, chData, nil)
+		if err != nil {
+			return nil, err
+		}
+		setup.Bundles = append(setup.Bundles, simBundle)
+	}
+
+	setup.Env = newAlgorithmEnvironment(env, setup.Context, opts)
+	return setup, nil
+}
+
+// EnvironmentTxs returns the txs committed to the block of env
+func EnvironmentTxs(env Environment) types.Transactions {
+	return env.(*algorithmEnvironment).env.txs
+}
@@ -20,6 +20,27 @@ var (
 	simulationCommittedMeter = metrics.NewRegisteredMeter("miner/block/simulation/committed", nil)
 	simulationRevertedMeter  = metrics.NewRegisteredMeter("miner/block/simulation/reverted", nil)
 
+	speculativeHitMeter  = metrics.NewRegisteredMeter("miner/speculative/hits", nil)
+	speculativeMissMeter = metrics.NewRegisteredMeter("miner/speculative/misses", nil)
+
 	gasUsedGauge        = metrics.NewRegisteredGauge("miner/block/gasused", nil)
 	transactionNumGauge = metrics.NewRegisteredGauge("miner/block/txnum", nil)
 )
+
+// markAlgoResults meters a build for every algorithm that built a block for the payload and a win for the algorithm of
+// the most valuable one, the win rate of an algorithm is the rate of its wins over the rate of its builds. Workers of
+// the same algorithm, such as the mev-geth workers, count once.
+func markAlgoResults(builtBy []string, bestBy string) {
+	if !metrics.EnabledBuilder {
+		return
+	}
+	seen := make(map[string]struct{}, len(builtBy))
+	for _, algo := range builtBy {
+		if _, found := seen[algo]; found {
+			continue
+		}
+		seen[algo] = struct{}{}
+		metrics.GetOrRegisterMeter("miner/algo/"+algo+"/builds", nil).Mark(1)
+	}
+	metrics.GetOrRegisterMeter("miner/algo/"+bestBy+"/wins", nil).Mark(1)
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
+	"golang.org/x/exp/slices"
 )
 
 // Backend wraps all methods required for mining. Only full node is capable
@@ -58,53 +59,59 @@ const (
 	ALGO_GREEDY_BUCKETS_MULTISNAP
 )
 
+// String returns the name of mev-geth or of the registered algorithm
 func (a AlgoType) String() string {
-	switch a {
-	case ALGO_GREEDY:
-		return "greedy"
-	case ALGO_GREEDY_MULTISNAP:
-		return "greedy-multi-snap"
-	case ALGO_MEV_GETH:
+	if a == ALGO_MEV_GETH {
 		return "mev-geth"
-	case ALGO_GREEDY_BUCKETS:
-		return "greedy-buckets"
-	case ALGO_GREEDY_BUCKETS_MULTISNAP:
-		return "greedy-buckets-multi-snap"
-	default:
-		return "unsupported"
 	}
+	if algo, found := lookupAlgorithm(a); found {
+		return algo.name
+	}
+	return "unsupported"
 }
 
+// AlgoTypeFlagToEnum returns the algo type of mev-geth or of a registered algorithm
 func AlgoTypeFlagToEnum(algoString string) (AlgoType, error) {
-	switch strings.ToLower(algoString) {
-	case ALGO_MEV_GETH.String():
+	algoString = strings.ToLower(algoString)
+	if algoString == ALGO_MEV_GETH.String() {
 		return ALGO_MEV_GETH, nil
-	case ALGO_GREEDY_BUCKETS.String():
-		return ALGO_GREEDY_BUCKETS, nil
-	case ALGO_GREEDY.String():
-		return ALGO_GREEDY, nil
-	case ALGO_GREEDY_MULTISNAP.String():
-		return ALGO_GREEDY_MULTISNAP, nil
-	case ALGO_GREEDY_BUCKETS_MULTISNAP.String():
-		return ALGO_GREEDY_BUCKETS_MULTISNAP, nil
-	default:
-		return ALGO_MEV_GETH, errors.New("algo not recognized")
 	}
+	if algoType, found := lookupAlgorithmByName(algoString); found {
+		return algoType, nil
+	}
+	return ALGO_MEV_GETH, errors.New("algo not recognized")
+}
+
+// ParseAlgoTypes parses a comma separated list of algorithms, algorithms listed more than once are only run once
+func ParseAlgoTypes(algoString string) ([]AlgoType, error) {
+	var algoTypes []AlgoType
+	for _, algo := range strings.Split(algoString, ",") {
+		algoType, err := AlgoTypeFlagToEnum(strings.TrimSpace(algo))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, algo)
+		}
+		if !slices.Contains(algoTypes, algoType) {
+			algoTypes = append(algoTypes, algoType)
+		}
+	}
+	return algoTypes, nil
 }
 
 // Config is the configuration parameters of mining.
 type Config struct {
-	Etherbase                common.Address    `toml:",omitempty"` // Public address for block mining rewards (default = first account)
-	Notify                   []string          `toml:",omitempty"` // HTTP URL list to be notified of new work packages (only useful in ethash).
-	NotifyFull               bool              `toml:",omitempty"` // Notify with pending block headers instead of work packages
-	ExtraData                hexutil.Bytes     `toml:",omitempty"` // Block extra data set by the miner
-	GasFloor                 uint64            // Target gas floor for mined blocks.
-	GasCeil                  uint64            // Target gas ceiling for mined blocks.
-	GasPrice                 *big.Int          // Minimum gas price for mining a transaction
-	AlgoType                 AlgoType          // Algorithm to use for block building
-	Recommit                 time.Duration     // The time interval for miner to re-create mining work.
-	Noverify                 bool              // Disable remote mining solution verification(only useful in ethash).
-	BuilderTxSigningKey      *ecdsa.PrivateKey `toml:",omitempty"` // Signing key of builder coinbase to make transaction to validator
+	Etherbase                common.Address                `toml:",omitempty"` // Public address for block mining rewards (default = first account)
+	Notify                   []string                      `toml:",omitempty"` // HTTP URL list to be notified of new work packages (only useful in ethash).
+	NotifyFull               bool                          `toml:",omitempty"` // Notify with pending block headers instead of work packages
+	ExtraData                hexutil.Bytes                 `toml:",omitempty"` // Block extra data set by the miner
+	GasFloor                 uint64                        // Target gas floor for mined blocks.
+	GasCeil                  uint64                        // Target gas ceiling for mined blocks.
+	GasPrice                 *big.Int                      // Minimum gas price for mining a transaction
+	AlgoType                 AlgoType                      // Algorithm to use for block building
+	AlgoTypes                []AlgoType                    // Algorithms run in parallel for every payload, overrides AlgoType when more than one is set
+	AlgoOptions              map[AlgoType]AlgorithmOptions `toml:",omitempty"` // Options of the algorithms, DefaultAlgorithmOptions when not set
+	Recommit                 time.Duration                 // The time interval for miner to re-create mining work.
+	Noverify                 bool                          // Disable remote mining solution verification(only useful in ethash).
+	BuilderTxSigningKey      *ecdsa.PrivateKey             `toml:",omitempty"` // Signing key of builder coinbase to make transaction to validator
 	MaxMergedBundles         int
 	Blocklist                []common.Address `toml:",omitempty"`
 	NewPayloadTimeout        time.Duration    // The maximum time allowance for creating a new payload
@@ -318,7 +325,19 @@ func (miner *Miner) SubscribePendingLogs(ch chan<- []*types.Log) event.Subscript
 // Accepts the block, time at which orders were taken, bundles which were used to build the block and all bundles that were considered for the block
 type BlockHookFn = func(*types.Block, *big.Int, time.Time, []types.SimulatedBundle, []types.SimulatedBundle, []types.UsedSBundle)
 
+// ErrProposerPaymentWithheld is returned by a ProposerPaymentFn to discard the block instead of paying the proposer
+var ErrProposerPaymentWithheld = errors.New("proposer payment withheld")
+
+// Accepts the funds available to pay the proposer and returns the amount used for the proposer payment transaction,
+// the payment transaction fee is paid from the returned amount
+type ProposerPaymentFn = func(availableFunds *big.Int) (*big.Int, error)
+
 // BuildPayload builds the payload according to the provided parameters.
 func (miner *Miner) BuildPayload(args *BuildPayloadArgs) (*Payload, error) {
 	return miner.worker.buildPayload(args)
 }
+
+// NewBuildingSession prepares a session building blocks for the payload arguments until it is closed.
+func (miner *Miner) NewBuildingSession(args *BuildPayloadArgs) (*BuildingSession, error) {
+	return miner.worker.newBuildingSession(args)
+}
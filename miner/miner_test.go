@@ -20,6 +20,7 @@ package miner
 import (
 	"errors"
 	"math/big"
+	"reflect"
 	"testing"
 	"time"
 
@@ -283,3 +284,28 @@ func createMiner(t *testing.T) (*Miner, *event.TypeMux, func(skipMiner bool)) {
 	}
 	return miner, mux, cleanup
 }
+
+func TestParseAlgoTypes(t *testing.T) {
+	tests := []struct {
+		algoString string
+		want       []AlgoType
+	}{
+		{"greedy", []AlgoType{ALGO_GREEDY}},
+		{"greedy-buckets, mev-geth,greedy-buckets,GREEDY-MULTI-SNAP", []AlgoType{ALGO_GREEDY_BUCKETS, ALGO_MEV_GETH, ALGO_GREEDY_MULTISNAP}},
+	}
+	for _, test := range tests {
+		algoTypes, err := ParseAlgoTypes(test.algoString)
+		if err != nil {
+			t.Fatalf("%q: unexpected error %v", test.algoString, err)
+		}
+		if !reflect.DeepEqual(algoTypes, test.want) {
+			t.Fatalf("%q: got %v, want %v", test.algoString, algoTypes, test.want)
+		}
+	}
+
+	for _, algoString := range []string{"", "greedy,", "greedy,unknown"} {
+		if _, err := ParseAlgoTypes(algoString); err == nil {
+			t.Fatalf("%q: expected error", algoString)
+		}
+	}
+}
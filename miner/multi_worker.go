@@ -93,7 +93,7 @@ func (w *multiWorker) buildPayload(args *BuildPayloadArgs) (*Payload, error) {
 	var empty *types.Block
 	for _, worker := range w.workers {
 		var err error
-		empty, _, err = worker.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.GasLimit, args.Random, args.Withdrawals, true, nil)
+		empty, _, err = worker.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.GasLimit, args.Random, args.Withdrawals, true, nil, nil)
 		if err != nil {
 			log.Error("could not start async block construction", "isFlashbotsWorker", worker.flashbots.isFlashbots, "#bundles", worker.flashbots.maxMergedBundles)
 			continue
@@ -117,14 +117,18 @@ func (w *multiWorker) buildPayload(args *BuildPayloadArgs) (*Payload, error) {
 
 	for _, w := range w.workers {
 		workerPayload := newPayload(empty, args.Id())
+		workerPayload.algo = w.flashbots.algoType.String()
 		workerPayloads = append(workerPayloads, workerPayload)
 
 		go func(w *worker) {
 			// Update routine done elsewhere!
 			start := time.Now()
-			block, fees, err := w.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.GasLimit, args.Random, args.Withdrawals, false, args.BlockHook)
+			block, fees, err := w.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.GasLimit, args.Random, args.Withdrawals, false, args.BlockHook, args.ProposerPayment)
 			if err == nil {
 				workerPayload.update(block, fees, time.Since(start))
+			} else if errors.Is(err, ErrProposerPaymentWithheld) {
+				log.Debug("Block discarded without proposer payment", "err", err)
+				workerPayload.Cancel()
 			} else {
 				log.Error("Error while sealing block", "err", err)
 				workerPayload.Cancel()
@@ -138,14 +142,16 @@ func (w *multiWorker) buildPayload(args *BuildPayloadArgs) (*Payload, error) {
 }
 
 func newMultiWorker(config *Config, chainConfig *params.ChainConfig, engine consensus.Engine, eth Backend, mux *event.TypeMux, isLocalBlock func(header *types.Header) bool, init bool) *multiWorker {
-	switch config.AlgoType {
-	case ALGO_MEV_GETH:
+	if len(config.AlgoTypes) > 1 {
+		return newMultiWorkerParallel(config, chainConfig, engine, eth, mux, isLocalBlock, init)
+	}
+	if config.AlgoType == ALGO_MEV_GETH {
 		return newMultiWorkerMevGeth(config, chainConfig, engine, eth, mux, isLocalBlock, init)
-	case ALGO_GREEDY, ALGO_GREEDY_BUCKETS, ALGO_GREEDY_MULTISNAP, ALGO_GREEDY_BUCKETS_MULTISNAP:
-		return newMultiWorkerGreedy(config, chainConfig, engine, eth, mux, isLocalBlock, init)
-	default:
+	}
+	if _, registered := lookupAlgorithm(config.AlgoType); !registered {
 		panic("unsupported builder algorithm found")
 	}
+	return newMultiWorkerGreedy(config, chainConfig, engine, eth, mux, isLocalBlock, init)
 }
 
 func newMultiWorkerGreedy(config *Config, chainConfig *params.ChainConfig, engine consensus.Engine, eth Backend, mux *event.TypeMux, isLocalBlock func(header *types.Header) bool, init bool) *multiWorker {
@@ -167,6 +173,17 @@ func newMultiWorkerGreedy(config *Config, chainConfig *params.ChainConfig, engin
 }
 
 func newMultiWorkerMevGeth(config *Config, chainConfig *params.ChainConfig, engine consensus.Engine, eth Backend, mux *event.TypeMux, isLocalBlock func(header *types.Header) bool, init bool) *multiWorker {
+	workers := newMevGethWorkers(config, chainConfig, engine, eth, mux, isLocalBlock, init)
+
+	log.Info("creating multi worker", "config.MaxMergedBundles", config.MaxMergedBundles, "workers", len(workers))
+	return &multiWorker{
+		regularWorker: workers[0],
+		workers:       workers,
+	}
+}
+
+// newMevGethWorkers creates the regular worker followed by a flashbots worker for every number of merged bundles
+func newMevGethWorkers(config *Config, chainConfig *params.ChainConfig, engine consensus.Engine, eth Backend, mux *event.TypeMux, isLocalBlock func(header *types.Header) bool, init bool) []*worker {
 	queue := make(chan *task)
 
 	bundleCache := NewBundleCache()
@@ -180,20 +197,48 @@ func newMultiWorkerMevGeth(config *Config, chainConfig *params.ChainConfig, engi
 	})
 
 	workers := []*worker{regularWorker}
-	if config.AlgoType == ALGO_MEV_GETH {
-		for i := 1; i <= config.MaxMergedBundles; i++ {
-			workers = append(workers,
-				newWorker(config, chainConfig, engine, eth, mux, isLocalBlock, init, &flashbotsData{
-					isFlashbots:      true,
-					queue:            queue,
-					algoType:         ALGO_MEV_GETH,
-					maxMergedBundles: i,
-					bundleCache:      bundleCache,
-				}))
+	for i := 1; i <= config.MaxMergedBundles; i++ {
+		workers = append(workers,
+			newWorker(config, chainConfig, engine, eth, mux, isLocalBlock, init, &flashbotsData{
+				isFlashbots:      true,
+				queue:            queue,
+				algoType:         ALGO_MEV_GETH,
+				maxMergedBundles: i,
+				bundleCache:      bundleCache,
+			}))
+	}
+	return workers
+}
+
+// newMultiWorkerParallel creates the workers of every algorithm of the config, payloads are resolved to the most
+// valuable block of all algorithms. The greedy workers share their bundle cache so that they order the same simulated
+// bundles, mev-geth workers keep their own as their simulation accounts for the gas of pending transactions.
+func newMultiWorkerParallel(config *Config, chainConfig *params.ChainConfig, engine consensus.Engine, eth Backend, mux *event.TypeMux, isLocalBlock func(header *types.Header) bool, init bool) *multiWorker {
+	var (
+		regularWorker *worker
+		workers       []*worker
+		bundleCache   = NewBundleCache()
+	)
+	for _, algoType := range config.AlgoTypes {
+		if algoType == ALGO_MEV_GETH {
+			mevGethWorkers := newMevGethWorkers(config, chainConfig, engine, eth, mux, isLocalBlock, init)
+			regularWorker = mevGethWorkers[0]
+			workers = append(workers, mevGethWorkers...)
+			continue
 		}
+		workers = append(workers, newWorker(config, chainConfig, engine, eth, mux, isLocalBlock, init, &flashbotsData{
+			isFlashbots:      true,
+			queue:            make(chan *task),
+			algoType:         algoType,
+			maxMergedBundles: config.MaxMergedBundles,
+			bundleCache:      bundleCache,
+		}))
+	}
+	if regularWorker == nil {
+		regularWorker = workers[0]
 	}
 
-	log.Info("creating multi worker", "config.MaxMergedBundles", config.MaxMergedBundles, "workers", len(workers))
+	log.Info("creating parallel multi worker", "algos", config.AlgoTypes, "workers", len(workers))
 	return &multiWorker{
 		regularWorker: regularWorker,
 		workers:       workers,
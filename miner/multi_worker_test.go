@@ -0,0 +1,62 @@
+package miner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiWorkerParallel(t *testing.T) {
+	enabled, enabledBuilder := metrics.Enabled, metrics.EnabledBuilder
+	metrics.Enabled, metrics.EnabledBuilder = true, true
+	defer func() { metrics.Enabled, metrics.EnabledBuilder = enabled, enabledBuilder }()
+
+	backend := newTestWorkerBackend(t, params.TestChainConfig, ethash.NewFaker(), rawdb.NewMemoryDatabase(), core.Genesis{Config: params.TestChainConfig, Alloc: defaultGenesisAlloc}, 0)
+	backend.txPool.AddLocals(pendingTxs)
+
+	config := *testConfig
+	config.AlgoTypes = []AlgoType{ALGO_GREEDY, ALGO_MEV_GETH, ALGO_GREEDY_BUCKETS}
+	config.MaxMergedBundles = 1
+	config.PriceCutoffPercent = defaultPriceCutoffPercent
+	w := newMultiWorker(&config, params.TestChainConfig, ethash.NewFaker(), backend, new(event.TypeMux), nil, false)
+	defer w.close()
+	w.setEtherbase(testBankAddress)
+
+	// the greedy workers share their bundle cache, the mev-geth workers have their own and provide the regular worker
+	require.Len(t, w.workers, 4)
+	require.Equal(t, ALGO_GREEDY, w.workers[0].flashbots.algoType)
+	require.Equal(t, ALGO_GREEDY_BUCKETS, w.workers[3].flashbots.algoType)
+	require.Same(t, w.workers[0].flashbots.bundleCache, w.workers[3].flashbots.bundleCache)
+	require.NotSame(t, w.workers[0].flashbots.bundleCache, w.workers[1].flashbots.bundleCache)
+	require.Same(t, w.workers[1], w.regularWorker)
+	require.False(t, w.regularWorker.flashbots.isFlashbots)
+
+	buildsBefore := metrics.GetOrRegisterMeter("miner/algo/greedy/builds", nil).Count()
+	payload, err := w.buildPayload(&BuildPayloadArgs{
+		Parent:       backend.chain.CurrentBlock().Hash(),
+		Timestamp:    uint64(time.Now().Unix()),
+		FeeRecipient: common.HexToAddress("0xdeadbeef"),
+	})
+	require.NoError(t, err)
+	full := payload.ResolveFull()
+	require.NotNil(t, full)
+	require.Len(t, full.ExecutionPayload.Transactions, len(pendingTxs))
+
+	// the payload is resolved once every worker is done
+	require.Eventually(t, func() bool {
+		return metrics.GetOrRegisterMeter("miner/algo/greedy/builds", nil).Count() == buildsBefore+1
+	}, time.Second, 10*time.Millisecond)
+	var wins int64
+	for _, algo := range []AlgoType{ALGO_GREEDY, ALGO_MEV_GETH, ALGO_GREEDY_BUCKETS} {
+		wins += metrics.GetOrRegisterMeter("miner/algo/"+algo.String()+"/wins", nil).Count()
+	}
+	require.Equal(t, int64(1), wins)
+}
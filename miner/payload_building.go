@@ -42,6 +42,8 @@ type BuildPayloadArgs struct {
 	Random       common.Hash       // The provided randomness value
 	Withdrawals  types.Withdrawals // The provided withdrawals
 	BlockHook    BlockHookFn
+	// ProposerPayment decides the proposer payment, nil pays all available funds to the proposer
+	ProposerPayment ProposerPaymentFn
 }
 
 // Id computes an 8-byte identifier by hashing the components of the payload arguments.
@@ -72,6 +74,9 @@ type Payload struct {
 	stop     chan struct{}
 	lock     sync.Mutex
 	cond     *sync.Cond
+
+	// algo is the algorithm building the payload, only set for the payloads of the workers of a multi worker
+	algo string
 }
 
 // newPayload initializes the payload object.
@@ -116,6 +121,10 @@ func (payload *Payload) resolveBestFullPayload(payloads []*Payload) {
 	defer payload.lock.Unlock()
 
 	log.Trace("resolving best payload")
+	var (
+		builtBy []string
+		bestBy  string
+	)
 	for _, p := range payloads {
 		p.lock.Lock()
 
@@ -133,13 +142,18 @@ func (payload *Payload) resolveBestFullPayload(payloads []*Payload) {
 				continue
 			}
 		}
+		builtBy = append(builtBy, p.algo)
 		if payload.full == nil || payload.fullFees.Cmp(p.fullFees) < 0 {
-			log.Trace("best payload updated", "id", p.id, "blockHash", p.full.Hash())
+			log.Trace("best payload updated", "id", p.id, "blockHash", p.full.Hash(), "algo", p.algo)
 			payload.full = p.full
 			payload.fullFees = p.fullFees
+			bestBy = p.algo
 		}
 		p.lock.Unlock()
 	}
+	if payload.full != nil {
+		markAlgoResults(builtBy, bestBy)
+	}
 
 	// Since we are not expecting any updates, close the payload already
 	select {
@@ -151,7 +165,7 @@ func (payload *Payload) resolveBestFullPayload(payloads []*Payload) {
 	payload.cond.Broadcast() // fire signal for notifying full block
 
 	if payload.full != nil {
-		log.Trace("best payload resolved", "id", payload.id, "blockHash", payload.full.Hash())
+		log.Trace("best payload resolved", "id", payload.id, "blockHash", payload.full.Hash(), "algo", bestBy)
 	} else {
 		log.Trace("no payload resolved", "id", payload.id)
 	}
@@ -221,7 +235,7 @@ func (w *worker) buildPayload(args *BuildPayloadArgs) (*Payload, error) {
 	// Build the initial version with no transaction included. It should be fast
 	// enough to run. The empty payload can at least make sure there is something
 	// to deliver for not missing slot.
-	empty, _, err := w.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.GasLimit, args.Random, args.Withdrawals, true, args.BlockHook)
+	empty, _, err := w.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.GasLimit, args.Random, args.Withdrawals, true, args.BlockHook, args.ProposerPayment)
 	if err != nil {
 		return nil, err
 	}
@@ -245,7 +259,7 @@ func (w *worker) buildPayload(args *BuildPayloadArgs) (*Payload, error) {
 			select {
 			case <-timer.C:
 				start := time.Now()
-				block, fees, err := w.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.GasLimit, args.Random, args.Withdrawals, false, args.BlockHook)
+				block, fees, err := w.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.GasLimit, args.Random, args.Withdrawals, false, args.BlockHook, args.ProposerPayment)
 				if err == nil {
 					payload.update(block, fees, time.Since(start))
 				}
@@ -33,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/tracers/logger"
 	"github.com/ethereum/go-ethereum/event"
@@ -1208,6 +1209,8 @@ type generateParams struct {
 	noUncle     bool              // Flag whether the uncle block inclusion is allowed
 	noTxs       bool              // Flag whether an empty block without any transaction is expected
 	onBlock     BlockHookFn       // Callback to call for each produced block
+
+	proposerPayment ProposerPaymentFn // Decides the proposer payment, nil pays all available funds
 }
 
 func doPrepareHeader(genParams *generateParams, chain *core.BlockChain, config *Config, chainConfig *params.ChainConfig, extra []byte, engine consensus.Engine) (*types.Header, *types.Header, error) {
@@ -1318,11 +1321,12 @@ func (w *worker) fillTransactionsSelectAlgo(interrupt *int32, env *environment)
 		mempoolTxHashes map[common.Hash]struct{}
 		err             error
 	)
-	switch w.flashbots.algoType {
-	case ALGO_GREEDY, ALGO_GREEDY_BUCKETS, ALGO_GREEDY_MULTISNAP, ALGO_GREEDY_BUCKETS_MULTISNAP:
+	_, registered := lookupAlgorithm(w.flashbots.algoType)
+	switch {
+	case registered:
 		println("===== select greedy")
 		blockBundles, allBundles, usedSbundles, mempoolTxHashes, err = w.fillTransactionsAlgoWorker(interrupt, env)
-	case ALGO_MEV_GETH:
+	case w.flashbots.algoType == ALGO_MEV_GETH:
 		println("======= select mev geth")
 		blockBundles, allBundles, mempoolTxHashes, err = w.fillTransactions(interrupt, env)
 	default:
@@ -1424,83 +1428,28 @@ func (w *worker) fillTransactionsAlgoWorker(interrupt *int32, env *environment)
 	}
 	log.Info("bundles simu is: ", "simu", bundlesToConsider)
 	log.Info("bundles simu is: ", "simu", sbundlesToConsider)
-	var (
-		newEnv       *environment
-		blockBundles []types.SimulatedBundle
-		usedSbundle  []types.UsedSBundle
-		start        = time.Now()
-	)
-	switch w.flashbots.algoType {
-	case ALGO_GREEDY_BUCKETS:
-		println("algo 1========")
-		priceCutoffPercent := w.config.PriceCutoffPercent
-		if !(priceCutoffPercent >= 0 && priceCutoffPercent <= 100) {
-			return nil, nil, nil, nil, errors.New("invalid price cutoff percent - must be between 0 and 100")
-		}
-
-		algoConf := &algorithmConfig{
-			DropRevertibleTxOnErr:  w.config.DiscardRevertibleTxOnErr,
-			EnforceProfit:          true,
-			ProfitThresholdPercent: defaultProfitThresholdPercent,
-			PriceCutoffPercent:     priceCutoffPercent,
-		}
-		builder := newGreedyBucketsBuilder(
-			w.chain, w.chainConfig, algoConf, w.blockList, env,
-			w.config.BuilderTxSigningKey, interrupt,
-		)
-
-		newEnv, blockBundles, usedSbundle = builder.buildBlock(bundlesToConsider, sbundlesToConsider, pending)
-	case ALGO_GREEDY_BUCKETS_MULTISNAP:
-		println("algo 2 ======")
-		priceCutoffPercent := w.config.PriceCutoffPercent
-		if !(priceCutoffPercent >= 0 && priceCutoffPercent <= 100) {
-			return nil, nil, nil, nil, errors.New("invalid price cutoff percent - must be between 0 and 100")
-		}
-
-		algoConf := &algorithmConfig{
-			DropRevertibleTxOnErr:  w.config.DiscardRevertibleTxOnErr,
-			EnforceProfit:          true,
-			ProfitThresholdPercent: defaultProfitThresholdPercent,
-			PriceCutoffPercent:     priceCutoffPercent,
-		}
-		builder := newGreedyBucketsMultiSnapBuilder(
-			w.chain, w.chainConfig, algoConf, w.blockList, env,
-			w.config.BuilderTxSigningKey, interrupt,
-		)
-		newEnv, blockBundles, usedSbundle = builder.buildBlock(bundlesToConsider, sbundlesToConsider, pending)
-	case ALGO_GREEDY_MULTISNAP:
-		println("algo 3 ========")
-		// For greedy multi-snap builder, set algorithm configuration to default values,
-		// except DropRevertibleTxOnErr which is passed in from worker config
-		algoConf := &algorithmConfig{
-			DropRevertibleTxOnErr:  w.config.DiscardRevertibleTxOnErr,
-			EnforceProfit:          defaultAlgorithmConfig.EnforceProfit,
-			ProfitThresholdPercent: defaultAlgorithmConfig.ProfitThresholdPercent,
-		}
-
-		builder := newGreedyMultiSnapBuilder(
-			w.chain, w.chainConfig, algoConf, w.blockList, env,
-			w.config.BuilderTxSigningKey, interrupt,
-		)
-		newEnv, blockBundles, usedSbundle = builder.buildBlock(bundlesToConsider, sbundlesToConsider, pending)
-	case ALGO_GREEDY:
-		println("algo 4 ===========")
-		fallthrough
-	default:
-		println("algo 5========")
-		// For default greedy builder, set algorithm configuration to default values,
-		// except DropRevertibleTxOnErr which is passed in from worker config
-		algoConf := &algorithmConfig{
-			DropRevertibleTxOnErr:  w.config.DiscardRevertibleTxOnErr,
-			EnforceProfit:          defaultAlgorithmConfig.EnforceProfit,
-			ProfitThresholdPercent: defaultAlgorithmConfig.ProfitThresholdPercent,
-		}
-
-		builder := newGreedyBuilder(
-			w.chain, w.chainConfig, algoConf, w.blockList,
-			env, w.config.BuilderTxSigningKey, interrupt,
-		)
-		newEnv, blockBundles, usedSbundle = builder.buildBlock(bundlesToConsider, sbundlesToConsider, pending)
+	start := time.Now()
+	opts, err := w.algorithmOptions()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	algoCtx := &AlgorithmContext{
+		Chain:       w.chain,
+		ChainConfig: w.chainConfig,
+		Blacklist:   w.blockList,
+		BuilderKey:  w.config.BuilderTxSigningKey,
+		Interrupt:   interrupt,
+		simBundles:  bundlesToConsider,
+		simSBundles: sbundlesToConsider,
+	}
+	algo, err := newBlockBuildingAlgorithm(w.flashbots.algoType, algoCtx, opts)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	algoEnv, blockBundles, usedSbundle := algo.BuildBlock(newAlgorithmEnvironment(env, algoCtx, opts), bundlesToConsider, sbundlesToConsider, pending)
+	newEnv, err := unwrapEnvironment(algoEnv)
+	if err != nil {
+		return nil, nil, nil, nil, err
 	}
 
 	if metrics.EnabledBuilder {
@@ -1512,6 +1461,33 @@ func (w *worker) fillTransactionsAlgoWorker(interrupt *int32, env *environment)
 	return blockBundles, bundlesToConsider, usedSbundle, mempoolTxHashes, err
 }
 
+// algorithmOptions returns the options of the block building algorithm of the worker
+func (w *worker) algorithmOptions() (AlgorithmOptions, error) {
+	if opts, ok := w.config.AlgoOptions[w.flashbots.algoType]; ok {
+		return opts, nil
+	}
+	return DefaultAlgorithmOptions(w.flashbots.algoType, w.config)
+}
+
+// recordsConflicts reports whether the algorithm of the worker reads the bundle conflict graph, the read/write sets
+// of the bundles are only recorded in simulation for such algorithms
+func (w *worker) recordsConflicts() bool {
+	opts, err := w.algorithmOptions()
+	return err == nil && opts.RecordConflicts
+}
+
+// newStateAccessTracer sets a state access tracer to config when bundles are checked against the blocklist or their
+// read/write sets are recorded, it returns nil otherwise
+func (w *worker) newStateAccessTracer(config *vm.Config) *logger.StateAccessTracer {
+	if len(w.blockList) == 0 && !w.recordsConflicts() {
+		return nil
+	}
+	tracer := logger.NewStateAccessTracer()
+	config.Tracer = tracer
+	config.Debug = true
+	return tracer
+}
+
 func (w *worker) getSimulatedBundles(env *environment) ([]types.SimulatedBundle, []*types.SimSBundle, error) {
 	if !w.flashbots.isFlashbots {
 		return nil, nil, nil
@@ -1546,16 +1522,40 @@ func (w *worker) getSimulatedBundles(env *environment) ([]types.SimulatedBundle,
 func (w *worker) generateWork(params *generateParams) (*types.Block, *big.Int, error) {
 	println("====== enter generate work")
 	start := time.Now()
+	work, validatorCoinbase, paymentTxReserve, err := w.prepareSealingWork(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer work.discard()
+
+	return w.fillSealingWork(params, work, validatorCoinbase, paymentTxReserve, nil, start)
+}
+
+// prepareSealingWork prepares the environment of the sealing block and reserves the gas of the proposer payment.
+// The environment only depends on the parameters, blocks can be filled from copies of it.
+func (w *worker) prepareSealingWork(params *generateParams) (*environment, common.Address, *proposerTxReservation, error) {
 	validatorCoinbase := params.coinbase
 	// Set builder coinbase to be passed to beacon header
 	params.coinbase = w.coinbase
 
 	work, err := w.prepareWork(params)
 	if err != nil {
-		return nil, nil, err
+		return nil, common.Address{}, nil, err
 	}
-	defer work.discard()
+	if params.noTxs {
+		return work, validatorCoinbase, nil, nil
+	}
+
+	paymentTxReserve, err := w.proposerTxPrepare(work, &validatorCoinbase)
+	if err != nil {
+		work.discard()
+		return nil, common.Address{}, nil, err
+	}
+	return work, validatorCoinbase, paymentTxReserve, nil
+}
 
+// fillSealingWork fills the prepared environment with the orders of the pool, pays the proposer and finalizes the block
+func (w *worker) fillSealingWork(params *generateParams, work *environment, validatorCoinbase common.Address, paymentTxReserve *proposerTxReservation, interrupt *int32, start time.Time) (*types.Block, *big.Int, error) {
 	finalizeFn := func(env *environment, orderCloseTime time.Time,
 		blockBundles []types.SimulatedBundle, allBundles []types.SimulatedBundle, usedSbundles []types.UsedSBundle, noTxs bool) (*types.Block, *big.Int, error) {
 		block, profit, err := w.finalizeBlock(env, params.withdrawals, validatorCoinbase, noTxs)
@@ -1596,14 +1596,9 @@ func (w *worker) generateWork(params *generateParams) (*types.Block, *big.Int, e
 		return finalizeFn(work, time.Now(), nil, nil, nil, true)
 	}
 
-	paymentTxReserve, err := w.proposerTxPrepare(work, &validatorCoinbase)
-	if err != nil {
-		return nil, nil, err
-	}
-
 	orderCloseTime := time.Now()
 
-	blockBundles, allBundles, usedSbundles, mempoolTxHashes, err := w.fillTransactionsSelectAlgo(nil, work)
+	blockBundles, allBundles, usedSbundles, mempoolTxHashes, err := w.fillTransactionsSelectAlgo(interrupt, work)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -1631,7 +1626,7 @@ func (w *worker) generateWork(params *generateParams) (*types.Block, *big.Int, e
 		return finalizeFn(work, orderCloseTime, blockBundles, allBundles, usedSbundles, true)
 	}
 
-	err = w.proposerTxCommit(work, &validatorCoinbase, paymentTxReserve)
+	err = w.proposerTxCommit(work, &validatorCoinbase, paymentTxReserve, params.proposerPayment)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -1788,7 +1783,7 @@ func (w *worker) commit(env *environment, interval func(), update bool, start ti
 // getSealingBlock generates the sealing block based on the given parameters.
 // The generation result will be passed back via the given channel no matter
 // the generation itself succeeds or not.
-func (w *worker) getSealingBlock(parent common.Hash, timestamp uint64, coinbase common.Address, gasLimit uint64, random common.Hash, withdrawals types.Withdrawals, noTxs bool, blockHook BlockHookFn) (*types.Block, *big.Int, error) {
+func (w *worker) getSealingBlock(parent common.Hash, timestamp uint64, coinbase common.Address, gasLimit uint64, random common.Hash, withdrawals types.Withdrawals, noTxs bool, blockHook BlockHookFn, proposerPayment ProposerPaymentFn) (*types.Block, *big.Int, error) {
 	req := &getWorkReq{
 		params: &generateParams{
 			timestamp:   timestamp,
@@ -1801,6 +1796,8 @@ func (w *worker) getSealingBlock(parent common.Hash, timestamp uint64, coinbase
 			noUncle:     true,
 			noTxs:       noTxs,
 			onBlock:     blockHook,
+
+			proposerPayment: proposerPayment,
 		},
 		result: make(chan *newPayloadResult, 1),
 	}
@@ -1903,9 +1900,12 @@ func (w *worker) simulateBundles(env *environment, bundles []types.MevBundle, sb
 	simResult := make([]*simulatedBundle, len(bundles))
 	sbSimResult := make([]*types.SimSBundle, len(sbundles))
 
+	// bundles cached by the workers not recording read/write sets are simulated again
+	recordConflicts := w.recordsConflicts()
+
 	var wg sync.WaitGroup
 	for i, bundle := range bundles {
-		if simmed, ok := simCache.GetSimulatedBundle(bundle.Hash); ok {
+		if simmed, ok := simCache.GetSimulatedBundle(bundle.Hash); ok && (!recordConflicts || simmed == nil || simmed.RWSet != nil) {
 			simResult[i] = simmed
 			continue
 		}
@@ -1948,7 +1948,7 @@ func (w *worker) simulateBundles(env *environment, bundles []types.MevBundle, sb
 	}
 
 	for i, sbundle := range sbundles {
-		if simmed, ok := simCache.GetSimSBundle(sbundle.Hash()); ok {
+		if simmed, ok := simCache.GetSimSBundle(sbundle.Hash()); ok && (!recordConflicts || simmed == nil || simmed.RWSet != nil) {
 			sbSimResult[i] = simmed
 			continue
 		}
@@ -1966,12 +1966,7 @@ func (w *worker) simulateBundles(env *environment, bundles []types.MevBundle, sb
 
 			tmpGasUsed := uint64(0)
 			config := *w.chain.GetVMConfig()
-			var tracer *logger.AccountTouchTracer
-			if len(w.blockList) != 0 {
-				tracer = logger.NewAccountTouchTracer()
-				config.Tracer = tracer
-				config.Debug = true
-			}
+			tracer := w.newStateAccessTracer(&config)
 			simRes, err := core.SimBundle(w.chainConfig, w.chain, &env.coinbase, gp, state, env.header, sbundle, 0, &tmpGasUsed, config, false)
 			if metrics.EnabledBuilder {
 				simulationMeter.Mark(1)
@@ -1996,6 +1991,9 @@ func (w *worker) simulateBundles(env *environment, bundles []types.MevBundle, sb
 				MevGasPrice: simRes.MevGasPrice,
 				Profit:      simRes.TotalProfit,
 			}
+			if tracer != nil {
+				result.RWSet = tracer.RWSet()
+			}
 			sbSimResult[idx] = result
 
 			if metrics.EnabledBuilder {
@@ -2052,6 +2050,10 @@ func (w *worker) computeBundleGas(
 
 	ethSentToCoinbase := new(big.Int)
 
+	// the tracer records the state accessed by the whole bundle, it also provides the accounts checked against the blocklist
+	config := *w.chain.GetVMConfig()
+	tracer := w.newStateAccessTracer(&config)
+
 	for i, tx := range bundle.Txs {
 		if env.header.BaseFee != nil && tx.Type() == 2 {
 			// Sanity check for extremely large numbers
@@ -2070,13 +2072,6 @@ func (w *worker) computeBundleGas(
 		state.SetTxContext(tx.Hash(), i+currentTxCount)
 		coinbaseBalanceBefore := state.GetBalance(env.coinbase)
 
-		config := *w.chain.GetVMConfig()
-		var tracer *logger.AccountTouchTracer
-		if len(w.blockList) != 0 {
-			tracer = logger.NewAccountTouchTracer()
-			config.Tracer = tracer
-			config.Debug = true
-		}
 		receipt, err := core.ApplyTransaction(w.chainConfig, w.chain, &env.coinbase, gasPool, state, env.header, tx, &tempGasUsed, config, nil)
 		if err != nil {
 			return simulatedBundle{}, err
@@ -2131,13 +2126,17 @@ func (w *worker) computeBundleGas(
 
 	totalEth := new(big.Int).Add(ethSentToCoinbase, gasFees)
 
-	return simulatedBundle{
+	simmed := simulatedBundle{
 		MevGasPrice:       new(big.Int).Div(totalEth, new(big.Int).SetUint64(totalGasUsed)),
 		TotalEth:          totalEth,
 		EthSentToCoinbase: ethSentToCoinbase,
 		TotalGasUsed:      totalGasUsed,
 		OriginalBundle:    bundle,
-	}, nil
+	}
+	if tracer != nil {
+		simmed.RWSet = tracer.RWSet()
+	}
+	return simmed, nil
 }
 
 // copyReceipts makes a deep copy of the given receipts.
@@ -2210,7 +2209,7 @@ func (w *worker) proposerTxPrepare(env *environment, validatorCoinbase *common.A
 	}, nil
 }
 
-func (w *worker) proposerTxCommit(env *environment, validatorCoinbase *common.Address, reserve *proposerTxReservation) error {
+func (w *worker) proposerTxCommit(env *environment, validatorCoinbase *common.Address, reserve *proposerTxReservation, proposerPayment ProposerPaymentFn) error {
 	if reserve == nil || validatorCoinbase == nil {
 		return nil
 	}
@@ -2232,6 +2231,17 @@ func (w *worker) proposerTxCommit(env *environment, validatorCoinbase *common.Ad
 		return errors.New("builder balance decreased")
 	}
 
+	if proposerPayment != nil {
+		payment, err := proposerPayment(new(big.Int).Set(availableFunds))
+		if err != nil {
+			return err
+		}
+		if payment == nil || payment.Sign() <= 0 || payment.Cmp(availableFunds) > 0 {
+			return fmt.Errorf("invalid proposer payment %v, available funds %v", payment, availableFunds)
+		}
+		availableFunds = payment
+	}
+
 	env.gasPool.AddGas(reserve.reservedGas)
 	chainData := chainData{w.chainConfig, w.chain, w.blockList}
 	_, err := insertPayoutTx(env, sender, *validatorCoinbase, reserve.reservedGas, reserve.isEOA, availableFunds, w.config.BuilderTxSigningKey, chainData)